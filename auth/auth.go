@@ -0,0 +1,124 @@
+// auth.go
+package auth
+
+import (
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// Число итераций PBKDF2-HMAC-SHA256 по рекомендации OWASP (2023)
+	iterations = 600000
+	saltSize   = 16
+	keySize    = 32
+	scheme     = "pbkdf2-sha256"
+
+	defaultLockMinutes = 5
+)
+
+// HashSecret хеширует пароль или PIN со случайной солью.
+// Результат вида "pbkdf2-sha256$600000$<соль>$<хеш>" хранится в базе.
+func HashSecret(secret string) (string, error) {
+	salt := make([]byte, saltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("ошибка генерации соли: %v", err)
+	}
+	key, err := pbkdf2.Key(sha256.New, secret, salt, iterations, keySize)
+	if err != nil {
+		return "", err
+	}
+	return strings.Join([]string{
+		scheme,
+		strconv.Itoa(iterations),
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	}, "$"), nil
+}
+
+// VerifySecret сравнивает пароль с сохранённым хешем за постоянное время
+func VerifySecret(secret, encoded string) bool {
+	key, want, ok := deriveFor(secret, encoded)
+	return ok && subtle.ConstantTimeCompare(key, want) == 1
+}
+
+// deriveFor разбирает строку вида "pbkdf2-sha256$<итерации>$<соль>$<данные>" и выводит из secret
+// ключ с теми же параметрами. Возвращает ключ и декодированные данные.
+func deriveFor(secret, encoded string) (key, payload []byte, ok bool) {
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 4 || parts[0] != scheme {
+		return nil, nil, false
+	}
+	iter, err := strconv.Atoi(parts[1])
+	if err != nil || iter <= 0 {
+		return nil, nil, false
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, nil, false
+	}
+	payload, err = base64.RawStdEncoding.DecodeString(parts[3])
+	if err != nil || len(payload) == 0 {
+		return nil, nil, false
+	}
+	keyLen := len(payload)
+	if keyLen > keySize {
+		keyLen = keySize
+	}
+	key, err = pbkdf2.Key(sha256.New, secret, salt, iter, keyLen)
+	if err != nil {
+		return nil, nil, false
+	}
+	return key, payload, true
+}
+
+// CheckSecret проверяет требования к новому паролю: PIN из 4+ цифр или пароль от 6 символов
+func CheckSecret(secret string) error {
+	if strings.TrimSpace(secret) != secret {
+		return fmt.Errorf("пароль не должен начинаться или заканчиваться пробелом")
+	}
+	n := len([]rune(secret))
+	if isDigits(secret) {
+		if n < 4 {
+			return fmt.Errorf("PIN должен состоять минимум из 4 цифр")
+		}
+		return nil
+	}
+	if n < 6 {
+		return fmt.Errorf("пароль должен быть не короче 6 символов")
+	}
+	return nil
+}
+
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// LockTimeout - время бездействия до автоблокировки (переменная окружения TODO_LOCK_MINUTES);
+// 0 отключает блокировку
+func LockTimeout() time.Duration {
+	value := os.Getenv("TODO_LOCK_MINUTES")
+	if value == "" {
+		return defaultLockMinutes * time.Minute
+	}
+	minutes, err := strconv.Atoi(value)
+	if err != nil || minutes < 0 {
+		return defaultLockMinutes * time.Minute
+	}
+	return time.Duration(minutes) * time.Minute
+}
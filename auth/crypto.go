@@ -0,0 +1,143 @@
+// crypto.go
+package auth
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ErrWrongSecret возвращается, когда ключ не удаётся открыть паролем
+var ErrWrongSecret = errors.New("неверный пароль")
+
+// Ключ данных открывается паролем офлайн, без ограничения числа попыток, поэтому
+// пароль для шифрования должен быть заметно сильнее PIN для входа
+const (
+	minPassphraseLen      = 10
+	minPassphraseDistinct = 5
+)
+
+// CheckPassphrase проверяет, годится ли пароль для шифрования ключа данных (см. WrapKey)
+func CheckPassphrase(passphrase string) error {
+	if isDigits(passphrase) {
+		return fmt.Errorf("для шифрования нужен пароль, а не PIN: PIN подбирается перебором за минуты")
+	}
+	runes := []rune(passphrase)
+	if len(runes) < minPassphraseLen {
+		return fmt.Errorf("для шифрования нужен пароль не короче %d символов", minPassphraseLen)
+	}
+	distinct := make(map[rune]bool)
+	for _, r := range runes {
+		distinct[r] = true
+	}
+	if len(distinct) < minPassphraseDistinct {
+		return fmt.Errorf("пароль для шифрования слишком однообразен: нужно хотя бы %d разных символов", minPassphraseDistinct)
+	}
+	return nil
+}
+
+// NewDataKey создаёт случайный ключ AES-256 для шифрования данных пользователя
+func NewDataKey() ([]byte, error) {
+	key := make([]byte, keySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("ошибка генерации ключа: %v", err)
+	}
+	return key, nil
+}
+
+// WrapKey шифрует ключ данных ключом, выведенным из пароля.
+// Формат тот же, что у HashSecret, только вместо хеша - зашифрованный ключ.
+func WrapKey(key []byte, passphrase string) (string, error) {
+	salt := make([]byte, saltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("ошибка генерации соли: %v", err)
+	}
+	kek, err := pbkdf2.Key(sha256.New, passphrase, salt, iterations, keySize)
+	if err != nil {
+		return "", err
+	}
+	sealed, err := seal(kek, key)
+	if err != nil {
+		return "", err
+	}
+	return strings.Join([]string{
+		scheme,
+		strconv.Itoa(iterations),
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(sealed),
+	}, "$"), nil
+}
+
+// UnwrapKey открывает ключ данных паролем
+func UnwrapKey(wrapped, passphrase string) ([]byte, error) {
+	kek, sealed, ok := deriveFor(passphrase, wrapped)
+	if !ok {
+		return nil, fmt.Errorf("повреждённый ключ шифрования")
+	}
+	key, err := open(kek, sealed)
+	if err != nil {
+		return nil, ErrWrongSecret
+	}
+	return key, nil
+}
+
+// Seal шифрует текст ключом данных (AES-256-GCM) и возвращает его в base64
+func Seal(key []byte, plaintext string) (string, error) {
+	sealed, err := seal(key, []byte(plaintext))
+	if err != nil {
+		return "", err
+	}
+	return base64.RawStdEncoding.EncodeToString(sealed), nil
+}
+
+// Open расшифровывает результат Seal
+func Open(key []byte, text string) (string, error) {
+	sealed, err := base64.RawStdEncoding.DecodeString(text)
+	if err != nil {
+		return "", err
+	}
+	plaintext, err := open(key, sealed)
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
+
+// seal возвращает nonce вместе с шифротекстом
+func seal(key, plaintext []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+func open(key, sealed []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return nil, fmt.Errorf("слишком короткий шифротекст")
+	}
+	return gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], nil)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,132 @@
+package auth
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func mustDataKey(t *testing.T) []byte {
+	t.Helper()
+	key, err := NewDataKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+// flipLastByte портит последний байт base64-строки, сохраняя её корректной
+func flipLastByte(t *testing.T, text string) string {
+	t.Helper()
+	data, err := base64.RawStdEncoding.DecodeString(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data[len(data)-1] ^= 1
+	return base64.RawStdEncoding.EncodeToString(data)
+}
+
+func TestWrapKeyRoundTrip(t *testing.T) {
+	key := mustDataKey(t)
+	wrapped, err := WrapKey(key, "длинный пароль")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(wrapped, scheme+"$") {
+		t.Errorf("WrapKey = %q, want prefix %q", wrapped, scheme+"$")
+	}
+	got, err := UnwrapKey(wrapped, "длинный пароль")
+	if err != nil {
+		t.Fatalf("UnwrapKey: %v", err)
+	}
+	if !bytes.Equal(got, key) {
+		t.Error("UnwrapKey вернул не тот ключ")
+	}
+}
+
+func TestUnwrapKeyErrors(t *testing.T) {
+	wrapped, err := WrapKey(mustDataKey(t), "длинный пароль")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := UnwrapKey(wrapped, "другой пароль"); !errors.Is(err, ErrWrongSecret) {
+		t.Errorf("UnwrapKey с чужим паролем: %v, want ErrWrongSecret", err)
+	}
+
+	parts := strings.Split(wrapped, "$")
+	parts[len(parts)-1] = flipLastByte(t, parts[len(parts)-1])
+	if _, err := UnwrapKey(strings.Join(parts, "$"), "длинный пароль"); err == nil {
+		t.Error("UnwrapKey открыл испорченный ключ")
+	}
+	if _, err := UnwrapKey("pbkdf2-sha256$1$соль", "длинный пароль"); err == nil {
+		t.Error("UnwrapKey открыл ключ неверного формата")
+	}
+}
+
+func TestSealOpen(t *testing.T) {
+	key := mustDataKey(t)
+	for _, text := range []string{"", "Купить молоко", strings.Repeat("длинное описание ", 100)} {
+		sealed, err := Seal(key, text)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if text != "" && strings.Contains(sealed, text) {
+			t.Errorf("Seal(%q) содержит открытый текст", text)
+		}
+		got, err := Open(key, sealed)
+		if err != nil {
+			t.Fatalf("Open(Seal(%q)): %v", text, err)
+		}
+		if got != text {
+			t.Errorf("Open(Seal(%q)) = %q", text, got)
+		}
+	}
+
+	// Одинаковый текст шифруется каждый раз по-новому
+	a, _ := Seal(key, "Купить молоко")
+	b, _ := Seal(key, "Купить молоко")
+	if a == b {
+		t.Error("Seal вернул одинаковый шифротекст для двух вызовов")
+	}
+}
+
+func TestOpenErrors(t *testing.T) {
+	key := mustDataKey(t)
+	sealed, err := Seal(key, "Купить молоко")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Open(mustDataKey(t), sealed); err == nil {
+		t.Error("Open расшифровал чужим ключом")
+	}
+	if _, err := Open(key, flipLastByte(t, sealed)); err == nil {
+		t.Error("Open расшифровал испорченный шифротекст")
+	}
+	if _, err := Open(key, "AAAA"); err == nil {
+		t.Error("Open расшифровал слишком короткий шифротекст")
+	}
+	if _, err := Open(key, "не base64!"); err == nil {
+		t.Error("Open принял не base64")
+	}
+}
+
+func TestCheckPassphrase(t *testing.T) {
+	tests := []struct {
+		passphrase string
+		ok         bool
+	}{
+		{"1234567890123", false}, // PIN любой длины
+		{"короткий", false},
+		{"aaaaaaaaaaaa", false},
+		{"ababababab12", false},
+		{"длинный пароль", true},
+		{"correct horse", true},
+	}
+	for _, tt := range tests {
+		if err := CheckPassphrase(tt.passphrase); (err == nil) != tt.ok {
+			t.Errorf("CheckPassphrase(%q) = %v, want ok=%v", tt.passphrase, err, tt.ok)
+		}
+	}
+}
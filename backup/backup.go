@@ -0,0 +1,273 @@
+// backup.go
+package backup
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+	"todolist/db"
+)
+
+const (
+	// Version - версия формата файла снимка
+	Version = 1
+
+	defaultDir      = "backups"
+	defaultKeep     = 14
+	defaultInterval = 24 * time.Hour
+	timeFormat      = "20060102-150405"
+)
+
+// Имена файлов: todo-all-20261018-030000.json.gz или todo-user3-20261018-030000.json.gz
+var fileNameRe = regexp.MustCompile(`^todo-(all|user(\d+))-(\d{8}-\d{6})\.json\.gz$`)
+
+// Dir - каталог снимков (переменная окружения TODO_BACKUP_DIR)
+func Dir() string {
+	if dir := os.Getenv("TODO_BACKUP_DIR"); dir != "" {
+		return dir
+	}
+	return defaultDir
+}
+
+// Keep - сколько последних снимков хранить для всех данных и для каждого пользователя
+// (переменная окружения TODO_BACKUP_KEEP)
+func Keep() int {
+	if n, err := strconv.Atoi(os.Getenv("TODO_BACKUP_KEEP")); err == nil && n > 0 {
+		return n
+	}
+	return defaultKeep
+}
+
+// Interval - период автоматических снимков (переменная окружения TODO_BACKUP_INTERVAL, например "6h");
+// "0" отключает расписание
+func Interval() time.Duration {
+	value := os.Getenv("TODO_BACKUP_INTERVAL")
+	if value == "" {
+		return defaultInterval
+	}
+	if value == "0" {
+		return 0
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d < time.Minute {
+		log.Printf("Некорректный период резервного копирования %q, используется %v", value, defaultInterval)
+		return defaultInterval
+	}
+	return d
+}
+
+// Snapshot - содержимое файла резервной копии
+type Snapshot struct {
+	Version   int       `json:"version"`
+	CreatedAt time.Time `json:"created_at"`
+	// UserID = 0 - снимок всех пользователей
+	UserID int `json:"user_id"`
+	// Names - отображаемые имена пользователей на момент снимка
+	Names  map[int]string `json:"names,omitempty"`
+	Tables db.Dump        `json:"tables"`
+}
+
+// Info описывает файл снимка без его чтения
+type Info struct {
+	Path      string
+	UserID    int
+	CreatedAt time.Time
+	Size      int64
+}
+
+// Create сохраняет снимок всех данных (userID = 0) или данных одного пользователя
+// и удаляет устаревшие снимки того же вида
+func Create(userID int, names map[int]string) (*Info, error) {
+	tables, err := db.DumpData(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot := Snapshot{
+		Version:   Version,
+		CreatedAt: time.Now(),
+		UserID:    userID,
+		Tables:    tables,
+	}
+	if userID == 0 {
+		snapshot.Names = names
+	} else if name, ok := names[userID]; ok {
+		snapshot.Names = map[int]string{userID: name}
+	}
+
+	dir := Dir()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("ошибка создания каталога резервных копий: %v", err)
+	}
+	path := filepath.Join(dir, fileName(userID, snapshot.CreatedAt))
+	if err := write(path, &snapshot); err != nil {
+		return nil, err
+	}
+
+	if err := Rotate(userID, Keep()); err != nil {
+		log.Printf("Ошибка удаления старых резервных копий: %v", err)
+	}
+
+	stat, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	return &Info{Path: path, UserID: userID, CreatedAt: snapshot.CreatedAt, Size: stat.Size()}, nil
+}
+
+func fileName(userID int, t time.Time) string {
+	scope := "all"
+	if userID != 0 {
+		scope = fmt.Sprintf("user%d", userID)
+	}
+	return fmt.Sprintf("todo-%s-%s.json.gz", scope, t.Format(timeFormat))
+}
+
+// write пишет снимок во временный файл и переименовывает его, чтобы прерванная запись
+// не оставила повреждённую копию
+func write(path string, snapshot *Snapshot) error {
+	tmp := path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return fmt.Errorf("ошибка создания файла резервной копии: %v", err)
+	}
+
+	zw := gzip.NewWriter(f)
+	zw.Name = filepath.Base(path)
+	zw.ModTime = snapshot.CreatedAt
+	err = json.NewEncoder(zw).Encode(snapshot)
+	if closeErr := zw.Close(); err == nil {
+		err = closeErr
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("ошибка записи резервной копии: %v", err)
+	}
+	return os.Rename(tmp, path)
+}
+
+// List возвращает снимки из каталога, новые первыми
+func List() ([]Info, error) {
+	entries, err := os.ReadDir(Dir())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var infos []Info
+	for _, e := range entries {
+		m := fileNameRe.FindStringSubmatch(e.Name())
+		if m == nil || e.IsDir() {
+			continue
+		}
+		created, err := time.ParseInLocation(timeFormat, m[3], time.Local)
+		if err != nil {
+			continue
+		}
+		info := Info{Path: filepath.Join(Dir(), e.Name()), CreatedAt: created}
+		if m[2] != "" {
+			info.UserID, _ = strconv.Atoi(m[2])
+		}
+		if stat, err := e.Info(); err == nil {
+			info.Size = stat.Size()
+		}
+		infos = append(infos, info)
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].CreatedAt.After(infos[j].CreatedAt) })
+	return infos, nil
+}
+
+// Load читает снимок из файла
+func Load(path string) (*Snapshot, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, fmt.Errorf("файл не является резервной копией: %v", err)
+	}
+	defer zr.Close()
+
+	var snapshot Snapshot
+	if err := json.NewDecoder(zr).Decode(&snapshot); err != nil {
+		return nil, fmt.Errorf("ошибка чтения резервной копии: %v", err)
+	}
+	if snapshot.Version > Version {
+		return nil, fmt.Errorf("резервная копия создана более новой версией программы (формат %d)", snapshot.Version)
+	}
+	return &snapshot, nil
+}
+
+// Rotate оставляет keep последних снимков вида userID (0 - снимки всех данных)
+func Rotate(userID, keep int) error {
+	infos, err := List()
+	if err != nil {
+		return err
+	}
+	kept := 0
+	for _, info := range infos {
+		if info.UserID != userID {
+			continue
+		}
+		kept++
+		if kept > keep {
+			if err := os.Remove(info.Path); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Start запускает периодическое создание снимков всех данных.
+// names вызывается перед каждым снимком, чтобы сохранить актуальные имена пользователей.
+func Start(names func() map[int]string) {
+	interval := Interval()
+	if interval == 0 {
+		return
+	}
+
+	go func() {
+		// Первый снимок - если последний старше периода, чтобы частые перезапуски не плодили копии
+		var wait time.Duration
+		if last := lastFull(); !last.IsZero() && time.Since(last) < interval {
+			wait = interval - time.Since(last)
+		}
+
+		for {
+			time.Sleep(wait)
+			if _, err := Create(0, names()); err != nil {
+				log.Printf("Ошибка автоматического резервного копирования: %v", err)
+			}
+			wait = interval
+		}
+	}()
+}
+
+func lastFull() time.Time {
+	infos, err := List()
+	if err != nil {
+		return time.Time{}
+	}
+	for _, info := range infos {
+		if info.UserID == 0 {
+			return info.CreatedAt
+		}
+	}
+	return time.Time{}
+}
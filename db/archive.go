@@ -0,0 +1,62 @@
+// archive.go
+package db
+
+import (
+	"database/sql"
+	"time"
+	"todolist/models"
+
+	"github.com/lib/pq"
+)
+
+// Завершённый список уходит в архив: он остаётся доступен в разделе "Архив",
+// но его задачи не попадают в поиск, умные списки, повестку и календарь.
+
+// activeTasks - условие на задачу t: её список не в архиве
+const activeTasks = "t.list_id NOT IN (SELECT id FROM todo_lists WHERE archived_at IS NOT NULL)"
+
+// SetListArchived убирает список в архив или возвращает его; нужна роль редактора
+func SetListArchived(userID, listID int, archived bool) error {
+	return withTx(func(tx *sql.Tx) error {
+		if err := requireListRole(tx, userID, listID, models.RoleEditor); err != nil {
+			return err
+		}
+		_, err := tx.Exec(
+			"UPDATE todo_lists SET archived_at = CASE WHEN $1 THEN COALESCE(archived_at, $2) END WHERE id = $3",
+			archived, time.Now(), listID,
+		)
+		return err
+	})
+}
+
+// SetListAutoArchive включает архивирование списка, как только все его задачи выполнены.
+// Если они уже выполнены, список уходит в архив сразу. Пока архивирование включено,
+// появление открытой задачи возвращает список из архива.
+func SetListAutoArchive(userID, listID int, on bool) error {
+	return withTx(func(tx *sql.Tx) error {
+		if err := requireListRole(tx, userID, listID, models.RoleEditor); err != nil {
+			return err
+		}
+		if _, err := tx.Exec("UPDATE todo_lists SET auto_archive = $1 WHERE id = $2", on, listID); err != nil {
+			return err
+		}
+		return autoArchive(tx, listID)
+	})
+}
+
+// autoArchive приводит архив в соответствие с задачами для тех из списков, где включено
+// автоархивирование: список с задачами, которые все выполнены, уходит в архив, а архивный
+// список с открытой задачей (созданной, возвращённой в работу или перенесённой) возвращается
+func autoArchive(q querier, listIDs ...int) error {
+	_, err := q.Exec(
+		`UPDATE todo_lists l SET archived_at = CASE WHEN l.archived_at IS NULL THEN $2::timestamp END
+		WHERE l.id = ANY($1) AND l.auto_archive AND (
+			l.archived_at IS NULL
+				AND EXISTS (SELECT 1 FROM tasks WHERE list_id = l.id)
+				AND NOT EXISTS (SELECT 1 FROM tasks WHERE list_id = l.id AND NOT is_done)
+			OR l.archived_at IS NOT NULL
+				AND EXISTS (SELECT 1 FROM tasks WHERE list_id = l.id AND NOT is_done))`,
+		pq.Array(listIDs), time.Now(),
+	)
+	return err
+}
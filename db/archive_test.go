@@ -0,0 +1,62 @@
+package db
+
+import (
+	"testing"
+	"todolist/models"
+)
+
+func listArchived(t *testing.T, userID, listID int) bool {
+	t.Helper()
+	lists, err := GetTodoLists(userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, l := range lists {
+		if l.ID == listID {
+			return !l.ArchivedAt.IsZero()
+		}
+	}
+	t.Fatalf("список %d не найден", listID)
+	return false
+}
+
+// При автоархивировании список уходит в архив, когда все задачи выполнены,
+// и возвращается, когда появляется открытая задача; отмена восстанавливает отметку
+func TestAutoArchiveFollowsOpenTasks(t *testing.T) {
+	openTestDB(t)
+	t.Cleanup(func() { DiscardUndo(0) })
+	userID := mustCreateUser(t)
+	list := mustCreateList(t, userID, "Переезд")
+	done := mustCreateTask(t, userID, models.Task{ListID: list.ID, Title: "Упаковать вещи", IsDone: true})
+	if err := SetListAutoArchive(userID, list.ID, true); err != nil {
+		t.Fatal(err)
+	}
+	if !listArchived(t, userID, list.ID) {
+		t.Fatal("список со всеми выполненными задачами не ушёл в архив")
+	}
+
+	open := mustCreateTask(t, userID, models.Task{ListID: list.ID, Title: "Вызвать грузчиков"})
+	if listArchived(t, userID, list.ID) {
+		t.Error("новая открытая задача не вернула список из архива")
+	}
+
+	if err := CompleteTasks(userID, []int{open.ID}, true); err != nil {
+		t.Fatal(err)
+	}
+	if !listArchived(t, userID, list.ID) {
+		t.Fatal("список не ушёл в архив после выполнения задач")
+	}
+	if err := CompleteTasks(userID, []int{done.ID}, false); err != nil {
+		t.Fatal(err)
+	}
+	if listArchived(t, userID, list.ID) {
+		t.Error("возврат задачи в работу не вернул список из архива")
+	}
+
+	if _, err := UndoLast(userID); err != nil {
+		t.Fatal(err)
+	}
+	if !listArchived(t, userID, list.ID) {
+		t.Error("отмена возврата в работу не вернула список в архив")
+	}
+}
@@ -0,0 +1,126 @@
+// assign.go
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"todolist/models"
+)
+
+// checkAssignee проверяет, что исполнитель задачи видит её список
+func checkAssignee(q querier, task *models.Task) error {
+	if task.AssigneeID == 0 {
+		return nil
+	}
+	listID := task.ListID
+	if listID == 0 {
+		if err := q.QueryRow("SELECT list_id FROM tasks WHERE id = $1", task.ID).Scan(&listID); err != nil {
+			return err
+		}
+	}
+	role, err := listRole(q, task.AssigneeID, listID)
+	if err != nil {
+		return err
+	}
+	if role == "" {
+		return fmt.Errorf("исполнитель не имеет доступа к этому списку")
+	}
+	return nil
+}
+
+// notifyAssigned уведомляет нового исполнителя, если задачу ему назначил кто-то другой
+func notifyAssigned(q querier, actorID int, task *models.Task, previous int) error {
+	if task.AssigneeID == 0 || task.AssigneeID == previous || task.AssigneeID == actorID {
+		return nil
+	}
+	_, err := q.Exec(
+		"INSERT INTO notifications (user_id, actor_id, task_id, kind, created_at) VALUES ($1, $2, $3, $4, NOW())",
+		task.AssigneeID, nullInt(actorID), task.ID, models.NotifyAssigned,
+	)
+	return err
+}
+
+// GetAssignedTasks возвращает задачи, назначенные пользователю, во всех доступных ему списках вне архива
+func GetAssignedTasks(userID int) ([]models.Task, error) {
+	rows, err := DB.Query(
+		`SELECT `+prefixColumns("t", taskColumns)+` FROM tasks t
+		JOIN todo_lists l ON l.id = t.list_id
+		WHERE t.assignee_id = $1 AND l.archived_at IS NULL
+			AND (l.user_id = $1 OR EXISTS (SELECT 1 FROM list_members m WHERE m.list_id = l.id AND m.user_id = $1))
+		ORDER BY t.is_done, (`+taskHasDue+`) IS NOT TRUE, t.due_date, t.created_at DESC`,
+		userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return scanTasks(DB, rows)
+}
+
+// GetListUsers возвращает ID пользователей, которым виден список: владельца и участников
+func GetListUsers(listID int) ([]int, error) {
+	rows, err := DB.Query(
+		`SELECT user_id FROM todo_lists WHERE id = $1
+		UNION ALL
+		(SELECT user_id FROM list_members WHERE list_id = $1 ORDER BY created_at)`,
+		listID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// GetNotifications возвращает уведомления пользователя, новые первыми
+func GetNotifications(userID int, unreadOnly bool) ([]models.Notification, error) {
+	query := `SELECT n.id, n.user_id, COALESCE(n.actor_id, 0), n.task_id, n.kind, t.title, t.list_id, n.created_at, n.read_at
+		FROM notifications n JOIN tasks t ON t.id = n.task_id
+		WHERE n.user_id = $1`
+	if unreadOnly {
+		query += " AND n.read_at IS NULL"
+	}
+	query += " ORDER BY n.created_at DESC LIMIT 100"
+
+	rows, err := DB.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var notifications []models.Notification
+	for rows.Next() {
+		var (
+			n      models.Notification
+			readAt sql.NullTime
+		)
+		if err := rows.Scan(&n.ID, &n.UserID, &n.ActorID, &n.TaskID, &n.Kind, &n.TaskTitle, &n.ListID, &n.CreatedAt, &readAt); err != nil {
+			return nil, err
+		}
+		n.ReadAt = readAt.Time
+		n.TaskTitle = openField(n.TaskTitle)
+		notifications = append(notifications, n)
+	}
+	return notifications, rows.Err()
+}
+
+// CountUnreadNotifications - количество непрочитанных уведомлений
+func CountUnreadNotifications(userID int) (int, error) {
+	var count int
+	err := DB.QueryRow("SELECT COUNT(*) FROM notifications WHERE user_id = $1 AND read_at IS NULL", userID).Scan(&count)
+	return count, err
+}
+
+// MarkNotificationsRead отмечает все уведомления пользователя прочитанными
+func MarkNotificationsRead(userID int) error {
+	_, err := DB.Exec("UPDATE notifications SET read_at = NOW() WHERE user_id = $1 AND read_at IS NULL", userID)
+	return err
+}
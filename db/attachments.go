@@ -0,0 +1,258 @@
+// attachments.go
+package db
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+	"todolist/models"
+
+	"github.com/lib/pq"
+)
+
+const defaultAttachmentMaxMB = 20
+
+// AttachmentsDir - каталог для содержимого вложений (переменная окружения TODO_ATTACHMENTS_DIR).
+// Пустая строка - содержимое хранится в базе, в таблице attachment_blobs.
+func AttachmentsDir() string {
+	return os.Getenv("TODO_ATTACHMENTS_DIR")
+}
+
+// AttachmentMaxSize - наибольший размер вложения в байтах (переменная окружения TODO_ATTACHMENT_MAX_MB)
+func AttachmentMaxSize() int64 {
+	mb, err := strconv.Atoi(os.Getenv("TODO_ATTACHMENT_MAX_MB"))
+	if err != nil || mb <= 0 {
+		mb = defaultAttachmentMaxMB
+	}
+	return int64(mb) << 20
+}
+
+// blobPath - путь к содержимому в каталоге: файлы раскладываются по первым двум символам хеша
+func blobPath(dir, hash string) string {
+	return filepath.Join(dir, hash[:2], hash)
+}
+
+// AddAttachment прикрепляет файл к задаче. Одинаковое содержимое хранится один раз.
+func AddAttachment(userID, taskID int, name string, data []byte) (*models.Attachment, error) {
+	if int64(len(data)) > AttachmentMaxSize() {
+		return nil, fmt.Errorf("файл %s больше допустимых %d МБ", name, AttachmentMaxSize()>>20)
+	}
+
+	sum := sha256.Sum256(data)
+	a := &models.Attachment{
+		TaskID:    taskID,
+		UserID:    userID,
+		Name:      filepath.Base(name),
+		MimeType:  detectMimeType(name, data),
+		Size:      int64(len(data)),
+		Hash:      hex.EncodeToString(sum[:]),
+		CreatedAt: time.Now(),
+	}
+
+	dir := AttachmentsDir()
+	err := withTx(func(tx *sql.Tx) error {
+		if err := requireTaskRole(tx, userID, taskID, models.RoleEditor); err != nil {
+			return err
+		}
+		if dir != "" {
+			if err := writeBlobFile(dir, a.Hash, data); err != nil {
+				return err
+			}
+		} else if _, err := tx.Exec(
+			"INSERT INTO attachment_blobs (hash, data) VALUES ($1, $2) ON CONFLICT (hash) DO NOTHING",
+			a.Hash, data,
+		); err != nil {
+			return err
+		}
+		return tx.QueryRow(
+			"INSERT INTO attachments (task_id, user_id, name, mime_type, size, hash, created_at) VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id",
+			a.TaskID, a.UserID, a.Name, a.MimeType, a.Size, a.Hash, a.CreatedAt,
+		).Scan(&a.ID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+func detectMimeType(name string, data []byte) string {
+	if t := mime.TypeByExtension(filepath.Ext(name)); t != "" {
+		return t
+	}
+	return http.DetectContentType(data)
+}
+
+// writeBlobFile сохраняет содержимое под именем хеша, если такого файла ещё нет
+func writeBlobFile(dir, hash string, data []byte) error {
+	path := blobPath(dir, hash)
+	if _, err := os.Stat(path); err == nil {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("ошибка создания каталога вложений: %v", err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("ошибка записи вложения: %v", err)
+	}
+	return os.Rename(tmp, path)
+}
+
+// GetAttachments возвращает вложения задачи без содержимого
+func GetAttachments(taskID int) ([]models.Attachment, error) {
+	rows, err := DB.Query(
+		"SELECT id, task_id, COALESCE(user_id, 0), name, mime_type, size, hash, created_at FROM attachments WHERE task_id = $1 ORDER BY created_at, id",
+		taskID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var attachments []models.Attachment
+	for rows.Next() {
+		var a models.Attachment
+		if err := rows.Scan(&a.ID, &a.TaskID, &a.UserID, &a.Name, &a.MimeType, &a.Size, &a.Hash, &a.CreatedAt); err != nil {
+			return nil, err
+		}
+		attachments = append(attachments, a)
+	}
+	return attachments, rows.Err()
+}
+
+// ReadAttachment возвращает содержимое вложения. Сначала ищет файл в каталоге,
+// затем в базе, поэтому смена способа хранения не теряет старые вложения.
+func ReadAttachment(a models.Attachment) ([]byte, error) {
+	if dir := AttachmentsDir(); dir != "" {
+		data, err := os.ReadFile(blobPath(dir, a.Hash))
+		if err == nil {
+			return data, nil
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+	}
+
+	var data []byte
+	err := DB.QueryRow("SELECT data FROM attachment_blobs WHERE hash = $1", a.Hash).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("содержимое вложения %s не найдено", a.Name)
+	}
+	return data, err
+}
+
+// DeleteAttachment удаляет вложение; нужна роль редактора
+func DeleteAttachment(userID, attachmentID int) error {
+	var hashes []string
+	err := withTx(func(tx *sql.Tx) error {
+		var (
+			taskID int
+			hash   string
+		)
+		err := tx.QueryRow("SELECT task_id, hash FROM attachments WHERE id = $1", attachmentID).Scan(&taskID, &hash)
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("вложение не найдено")
+		}
+		if err != nil {
+			return err
+		}
+		if err := requireTaskRole(tx, userID, taskID, models.RoleEditor); err != nil {
+			return err
+		}
+		if _, err := tx.Exec("DELETE FROM attachments WHERE id = $1", attachmentID); err != nil {
+			return err
+		}
+		hashes, err = removeOrphanBlobs(tx, []string{hash})
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	removeBlobFiles(hashes)
+	return nil
+}
+
+// attachmentHashes собирает хеши вложений задач, отобранных условием where по колонке task_id
+func attachmentHashes(q querier, where string, args ...any) ([]string, error) {
+	rows, err := q.Query("SELECT DISTINCT hash FROM attachments WHERE "+where, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var hashes []string
+	for rows.Next() {
+		var hash string
+		if err := rows.Scan(&hash); err != nil {
+			return nil, err
+		}
+		hashes = append(hashes, hash)
+	}
+	return hashes, rows.Err()
+}
+
+// removeOrphanBlobs удаляет из базы содержимое, на которое больше не ссылается ни одно вложение
+// и которое не понадобится для отмены удаления (см. UndoLast), и возвращает хеши таких блобов,
+// чтобы после коммита удалить и файлы
+func removeOrphanBlobs(q querier, candidates []string) ([]string, error) {
+	held := undoHeld()
+	var free []string
+	for _, hash := range candidates {
+		if !held[hash] {
+			free = append(free, hash)
+		}
+	}
+	candidates = free
+	if len(candidates) == 0 {
+		return nil, nil
+	}
+	rows, err := q.Query(
+		"SELECT h FROM unnest($1::text[]) h WHERE NOT EXISTS (SELECT 1 FROM attachments a WHERE a.hash = h)",
+		pq.Array(candidates),
+	)
+	if err != nil {
+		return nil, err
+	}
+	var orphans []string
+	for rows.Next() {
+		var hash string
+		if err := rows.Scan(&hash); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		orphans = append(orphans, hash)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(orphans) > 0 {
+		if _, err := q.Exec("DELETE FROM attachment_blobs WHERE hash = ANY($1)", pq.Array(orphans)); err != nil {
+			return nil, err
+		}
+	}
+	return orphans, nil
+}
+
+// removeBlobFiles удаляет файлы содержимого из каталога вложений; ошибки только журналируются
+func removeBlobFiles(hashes []string) {
+	dir := AttachmentsDir()
+	if dir == "" {
+		return
+	}
+	for _, hash := range hashes {
+		if err := os.Remove(blobPath(dir, hash)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			log.Printf("Не удалось удалить файл вложения %s: %v", hash, err)
+		}
+	}
+}
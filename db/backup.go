@@ -0,0 +1,338 @@
+// backup.go
+package db
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// backupTable описывает, как выгрузить и восстановить таблицу по частям.
+// В условиях {todo_lists}, {tasks} и т.п. подменяются на живые таблицы при выгрузке
+// и на временные копии из снимка при восстановлении; $1 - ID пользователя или списка.
+type backupTable struct {
+	name      string
+	userScope string
+	listScope string // пусто - таблица не относится к отдельным спискам
+	serial    bool   // есть последовательность для колонки id
+	// prepare выполняется перед вставкой: убирает из restore_<name> ссылки на удалённых
+	// после снимка пользователей и на строки, которые не восстанавливаются вместе с ней
+	prepare string
+	// shared - строки могут принадлежать нескольким пользователям (содержимое вложений):
+	// при восстановлении их не удаляем, а добавляем недостающие
+	shared bool
+	// upsert - строка не удаляется, а обновляется из снимка: на неё ссылаются данные
+	// других пользователей, которые удалились бы или обнулились каскадом
+	upsert bool
+}
+
+// Таблицы в порядке зависимостей: удаляем с конца, вставляем с начала
+var backupTables = []backupTable{
+	{name: "users", userScope: "id = $1", serial: true, upsert: true},
+	{name: "list_folders", userScope: "user_id = $1", serial: true},
+	// Список, восстановленный отдельно, попадает в корень, если его папки уже нет
+	{name: "todo_lists", userScope: "user_id = $1", listScope: "id = $1", serial: true,
+		prepare: "UPDATE restore_todo_lists SET folder_id = NULL WHERE folder_id NOT IN (SELECT id FROM list_folders)"},
+	{name: "list_statuses", userScope: "list_id IN (SELECT id FROM {todo_lists} WHERE user_id = $1)", listScope: "list_id = $1", serial: true},
+	{name: "tasks", userScope: "list_id IN (SELECT id FROM {todo_lists} WHERE user_id = $1)", listScope: "list_id = $1", serial: true,
+		prepare: "UPDATE restore_tasks SET assignee_id = NULL WHERE assignee_id NOT IN (SELECT id FROM users)"},
+	{name: "task_tags", userScope: "task_id IN (SELECT t.id FROM {tasks} t JOIN {todo_lists} l ON l.id = t.list_id WHERE l.user_id = $1)", listScope: "task_id IN (SELECT id FROM {tasks} WHERE list_id = $1)"},
+	// Ссылки на чужие списки, удалённые после снимка, не восстанавливаются
+	{name: "feed_tokens", userScope: "user_id = $1 AND (list_id IS NULL OR list_id IN (SELECT id FROM todo_lists))", listScope: "list_id = $1"},
+	// Участники, удалённые из базы после снимка, не восстанавливаются
+	{name: "list_members", userScope: "list_id IN (SELECT id FROM {todo_lists} WHERE user_id = $1) AND user_id IN (SELECT id FROM users)", listScope: "list_id = $1 AND user_id IN (SELECT id FROM users)",
+		prepare: "UPDATE restore_list_members SET folder_id = NULL WHERE folder_id NOT IN (SELECT id FROM list_folders)"},
+	{name: "comments", userScope: "task_id IN (SELECT t.id FROM {tasks} t JOIN {todo_lists} l ON l.id = t.list_id WHERE l.user_id = $1)", listScope: "task_id IN (SELECT id FROM {tasks} WHERE list_id = $1)", serial: true,
+		prepare: "UPDATE restore_comments SET user_id = NULL WHERE user_id NOT IN (SELECT id FROM users)"},
+	{name: "attachments", userScope: "task_id IN (SELECT t.id FROM {tasks} t JOIN {todo_lists} l ON l.id = t.list_id WHERE l.user_id = $1)", listScope: "task_id IN (SELECT id FROM {tasks} WHERE list_id = $1)", serial: true,
+		prepare: "UPDATE restore_attachments SET user_id = NULL WHERE user_id NOT IN (SELECT id FROM users)"},
+	// При хранении вложений в каталоге (TODO_ATTACHMENTS_DIR) содержимое в снимок не попадает
+	{name: "attachment_blobs", userScope: "hash IN (SELECT a.hash FROM {attachments} a JOIN {tasks} t ON t.id = a.task_id JOIN {todo_lists} l ON l.id = t.list_id WHERE l.user_id = $1)", listScope: "hash IN (SELECT a.hash FROM {attachments} a JOIN {tasks} t ON t.id = a.task_id WHERE t.list_id = $1)", shared: true},
+	// Уведомления о задачах пользователя получают и другие участники его списков
+	{name: "notifications", userScope: "task_id IN (SELECT t.id FROM {tasks} t JOIN {todo_lists} l ON l.id = t.list_id WHERE l.user_id = $1) AND user_id IN (SELECT id FROM users)", listScope: "task_id IN (SELECT id FROM {tasks} WHERE list_id = $1) AND user_id IN (SELECT id FROM users)", serial: true,
+		prepare: "UPDATE restore_notifications SET actor_id = NULL WHERE actor_id NOT IN (SELECT id FROM users)"},
+	{name: "smart_lists", userScope: "user_id = $1", serial: true},
+	{name: "list_templates", userScope: "user_id = $1", serial: true},
+	{name: "template_tasks", userScope: "template_id IN (SELECT id FROM {list_templates} WHERE user_id = $1)", serial: true},
+}
+
+var (
+	liveTables    = strings.NewReplacer("{todo_lists}", "todo_lists", "{tasks}", "tasks", "{attachments}", "attachments", "{list_templates}", "list_templates")
+	restoreTables = strings.NewReplacer("{todo_lists}", "restore_todo_lists", "{tasks}", "restore_tasks", "{attachments}", "restore_attachments", "{list_templates}", "restore_list_templates")
+)
+
+// Dump - строки таблиц в JSON, по имени таблицы
+type Dump map[string][]json.RawMessage
+
+// DumpData выгружает все данные (userID = 0) или данные одного пользователя
+// в одной транзакции, чтобы снимок был согласованным
+func DumpData(userID int) (Dump, error) {
+	tx, err := DB.BeginTx(nil, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return nil, fmt.Errorf("ошибка начала транзакции: %v", err)
+	}
+	defer tx.Rollback()
+
+	dump := make(Dump)
+	for _, t := range backupTables {
+		query := "SELECT row_to_json(t) FROM " + t.name + " t"
+		var args []any
+		if userID != 0 {
+			query += " WHERE " + liveTables.Replace(t.userScope)
+			args = append(args, userID)
+		}
+
+		rows, err := tx.Query(query, args...)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка выгрузки таблицы %s: %v", t.name, err)
+		}
+		records := []json.RawMessage{}
+		for rows.Next() {
+			var record []byte
+			if err := rows.Scan(&record); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			records = append(records, json.RawMessage(record))
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+		dump[t.name] = records
+	}
+	return dump, nil
+}
+
+// RestoreUser заменяет все данные пользователя данными из снимка.
+// Строки в чужих списках (участие пользователя, назначенные ему задачи, его комментарии)
+// не затрагиваются. Вместе с данными восстанавливается и ключ шифрования,
+// поэтому открытый ключ пользователя забывается до следующего входа.
+func RestoreUser(dump Dump, userID int) error {
+	defer setUserKey(userID, nil)
+	return withTx(func(tx *sql.Tx) error {
+		if err := loadRestoreTables(tx, dump); err != nil {
+			return err
+		}
+
+		var found bool
+		if err := tx.QueryRow("SELECT EXISTS (SELECT 1 FROM restore_users WHERE id = $1)", userID).Scan(&found); err != nil {
+			return err
+		}
+		if !found {
+			return fmt.Errorf("в резервной копии нет пользователя %d", userID)
+		}
+
+		// Удаление папок обнулило бы раскладку открытых пользователю списков:
+		// запоминаем её и возвращаем, если папка есть и в снимке
+		if _, err := tx.Exec("CREATE TEMP TABLE restore_placement (list_id INTEGER, folder_id INTEGER) ON COMMIT DROP"); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(
+			"INSERT INTO restore_placement SELECT list_id, folder_id FROM list_members WHERE user_id = $1 AND folder_id IS NOT NULL",
+			userID,
+		); err != nil {
+			return err
+		}
+
+		if err := replaceRows(tx, func(t backupTable) string { return t.userScope }, userID); err != nil {
+			return err
+		}
+		_, err := tx.Exec(
+			`UPDATE list_members m SET folder_id = p.folder_id FROM restore_placement p
+			WHERE m.list_id = p.list_id AND m.user_id = $1 AND p.folder_id IN (SELECT id FROM list_folders WHERE user_id = $1)`,
+			userID,
+		)
+		return err
+	})
+}
+
+// RestoreList заменяет один список и его задачи данными из снимка.
+// Владелец списка должен существовать в базе.
+func RestoreList(dump Dump, listID int) error {
+	return withTx(func(tx *sql.Tx) error {
+		if err := loadRestoreTables(tx, dump); err != nil {
+			return err
+		}
+
+		var ownerID int
+		err := tx.QueryRow("SELECT user_id FROM restore_todo_lists WHERE id = $1", listID).Scan(&ownerID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("в резервной копии нет списка %d", listID)
+		}
+		if err != nil {
+			return err
+		}
+
+		var ownerExists bool
+		if err := tx.QueryRow("SELECT EXISTS (SELECT 1 FROM users WHERE id = $1)", ownerID).Scan(&ownerExists); err != nil {
+			return err
+		}
+		if !ownerExists {
+			return fmt.Errorf("владелец списка удалён: сначала восстановите пользователя целиком")
+		}
+
+		return replaceRows(tx, func(t backupTable) string { return t.listScope }, listID)
+	})
+}
+
+// replaceRows удаляет живые строки, попадающие под условие scope, и вставляет строки из снимка.
+// Таблицы с пустым условием пропускаются.
+func replaceRows(tx *sql.Tx, scope func(backupTable) string, id int) error {
+	for i := len(backupTables) - 1; i >= 0; i-- {
+		t := backupTables[i]
+		if scope(t) == "" || t.shared || t.upsert {
+			continue
+		}
+		if _, err := tx.Exec("DELETE FROM "+t.name+" WHERE "+liveTables.Replace(scope(t)), id); err != nil {
+			return fmt.Errorf("ошибка очистки таблицы %s: %v", t.name, err)
+		}
+	}
+	for _, t := range backupTables {
+		if scope(t) == "" {
+			continue
+		}
+		if err := prepareRestore(tx, t); err != nil {
+			return err
+		}
+		query := "INSERT INTO " + t.name + " SELECT * FROM restore_" + t.name + " WHERE " + restoreTables.Replace(scope(t))
+		if t.shared {
+			query += " ON CONFLICT DO NOTHING"
+		}
+		if t.upsert {
+			set, err := excludedColumns(tx, t.name)
+			if err != nil {
+				return err
+			}
+			query += " ON CONFLICT (id) DO UPDATE SET " + set
+		}
+		if _, err := tx.Exec(query, id); err != nil {
+			return fmt.Errorf("ошибка восстановления таблицы %s: %v", t.name, err)
+		}
+	}
+
+	// Содержимое вложений, на которое после замены никто не ссылается, больше не нужно
+	if _, err := tx.Exec("DELETE FROM attachment_blobs b WHERE NOT EXISTS (SELECT 1 FROM attachments a WHERE a.hash = b.hash)"); err != nil {
+		return fmt.Errorf("ошибка удаления ненужных вложений: %v", err)
+	}
+	return resetSequences(tx)
+}
+
+// loadRestoreTables копирует снимок во временные таблицы restore_*, удаляемые по окончании транзакции.
+// Колонки, добавленные после создания снимка, получают значения по умолчанию.
+func loadRestoreTables(tx *sql.Tx, dump Dump) error {
+	for _, t := range backupTables {
+		restore := "restore_" + t.name
+		if _, err := tx.Exec("CREATE TEMP TABLE " + restore + " (LIKE " + t.name + " INCLUDING DEFAULTS) ON COMMIT DROP"); err != nil {
+			return fmt.Errorf("ошибка подготовки восстановления: %v", err)
+		}
+
+		// В старом снимке новых колонок нет, и json_populate_recordset заполнит их NULL:
+		// снимаем NOT NULL на время загрузки, а затем подставляем значения по умолчанию
+		rows, err := tx.Query(
+			"SELECT column_name, column_default IS NOT NULL FROM information_schema.columns WHERE table_schema = current_schema() AND table_name = $1 AND is_nullable = 'NO'",
+			t.name,
+		)
+		if err != nil {
+			return err
+		}
+		var defaults, notNull []string
+		for rows.Next() {
+			var (
+				column     string
+				hasDefault bool
+			)
+			if err := rows.Scan(&column, &hasDefault); err != nil {
+				rows.Close()
+				return err
+			}
+			notNull = append(notNull, column)
+			if hasDefault {
+				defaults = append(defaults, column)
+			}
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		for _, column := range notNull {
+			if _, err := tx.Exec("ALTER TABLE " + restore + " ALTER COLUMN " + column + " DROP NOT NULL"); err != nil {
+				return err
+			}
+		}
+
+		records := dump[t.name]
+		if len(records) == 0 {
+			continue
+		}
+		data, err := json.Marshal(records)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(
+			"INSERT INTO "+restore+" SELECT * FROM json_populate_recordset(NULL::"+restore+", $1::json)",
+			string(data),
+		); err != nil {
+			return fmt.Errorf("ошибка чтения таблицы %s из резервной копии: %v", t.name, err)
+		}
+		for _, column := range defaults {
+			if _, err := tx.Exec("UPDATE " + restore + " SET " + column + " = DEFAULT WHERE " + column + " IS NULL"); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// excludedColumns возвращает "a = EXCLUDED.a, ..." для всех колонок таблицы, кроме id
+func excludedColumns(tx *sql.Tx, table string) (string, error) {
+	rows, err := tx.Query(
+		"SELECT column_name FROM information_schema.columns WHERE table_schema = current_schema() AND table_name = $1 AND column_name <> 'id' ORDER BY ordinal_position",
+		table,
+	)
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+
+	var set []string
+	for rows.Next() {
+		var column string
+		if err := rows.Scan(&column); err != nil {
+			return "", err
+		}
+		set = append(set, column+" = EXCLUDED."+column)
+	}
+	if err := rows.Err(); err != nil {
+		return "", err
+	}
+	return strings.Join(set, ", "), nil
+}
+
+func prepareRestore(tx *sql.Tx, t backupTable) error {
+	if t.prepare == "" {
+		return nil
+	}
+	if _, err := tx.Exec(t.prepare); err != nil {
+		return fmt.Errorf("ошибка подготовки таблицы %s: %v", t.name, err)
+	}
+	return nil
+}
+
+// resetSequences сдвигает последовательности за восстановленные ID, чтобы новые записи их не заняли
+func resetSequences(tx *sql.Tx) error {
+	for _, t := range backupTables {
+		if !t.serial {
+			continue
+		}
+		if _, err := tx.Exec(
+			"SELECT setval(pg_get_serial_sequence($1, 'id'), GREATEST((SELECT MAX(id) FROM "+t.name+"), 1))",
+			t.name,
+		); err != nil {
+			return fmt.Errorf("ошибка обновления последовательности %s: %v", t.name, err)
+		}
+	}
+	return nil
+}
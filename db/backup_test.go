@@ -0,0 +1,250 @@
+package db
+
+import (
+	"testing"
+	"time"
+	"todolist/models"
+)
+
+// Восстановление возвращает данные пользователя к снимку и не трогает других пользователей
+func TestRestoreUser(t *testing.T) {
+	openTestDB(t)
+	userID := mustCreateUser(t)
+	otherID := mustCreateUser(t)
+
+	list := mustCreateList(t, userID, "Дом")
+	task := mustCreateTask(t, userID, models.Task{ListID: list.ID, Title: "Полить цветы"})
+	other := mustCreateList(t, otherID, "Чужой")
+	mustCreateTask(t, otherID, models.Task{ListID: other.ID, Title: "Чужая задача"})
+
+	dump, err := DumpData(userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	task.Title = "Переименована"
+	if err := UpdateTask(userID, &task); err != nil {
+		t.Fatal(err)
+	}
+	mustCreateTask(t, userID, models.Task{ListID: list.ID, Title: "Добавлена после снимка"})
+	mustCreateList(t, userID, "Новый")
+	mustCreateTask(t, otherID, models.Task{ListID: other.ID, Title: "Ещё одна чужая"})
+
+	if err := RestoreUser(dump, userID); err != nil {
+		t.Fatalf("RestoreUser: %v", err)
+	}
+
+	lists, err := GetTodoLists(userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lists) != 1 || lists[0].ID != list.ID {
+		t.Errorf("списки после восстановления: %+v, want только %q", lists, list.Title)
+	}
+	tasks, err := GetTasksByList(list.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tasks) != 1 || tasks[0].Title != "Полить цветы" {
+		t.Errorf("задачи после восстановления: %+v, want только \"Полить цветы\"", tasks)
+	}
+	otherTasks, err := GetTasksByList(other.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(otherTasks) != 2 {
+		t.Errorf("у другого пользователя %d задач, want 2", len(otherTasks))
+	}
+
+	// Новые записи не должны занять восстановленные ID
+	mustCreateTask(t, userID, models.Task{ListID: list.ID, Title: "После восстановления", CreatedAt: time.Now()})
+}
+
+// Пользователя, удалённого после снимка, можно восстановить целиком
+func TestRestoreDeletedUser(t *testing.T) {
+	openTestDB(t)
+	userID := mustCreateUser(t)
+	list := mustCreateList(t, userID, "Работа")
+	mustCreateTask(t, userID, models.Task{ListID: list.ID, Title: "Отчёт"})
+
+	dump, err := DumpData(userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := DeleteUser(userID); err != nil {
+		t.Fatal(err)
+	}
+	if err := RestoreUser(dump, userID); err != nil {
+		t.Fatalf("RestoreUser: %v", err)
+	}
+
+	tasks, err := GetTasksByList(list.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tasks) != 1 || tasks[0].Title != "Отчёт" {
+		t.Errorf("задачи восстановленного пользователя: %+v", tasks)
+	}
+}
+
+// Восстановление пользователя не отнимает у него участие в чужих списках
+func TestRestoreUserKeepsMembership(t *testing.T) {
+	openTestDB(t)
+	userID := mustCreateUser(t)
+	ownerID := mustCreateUser(t)
+	shared := mustCreateList(t, ownerID, "Общий")
+	if err := ShareList(ownerID, shared.ID, userID, models.RoleEditor); err != nil {
+		t.Fatal(err)
+	}
+
+	dump, err := DumpData(userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := RestoreUser(dump, userID); err != nil {
+		t.Fatalf("RestoreUser: %v", err)
+	}
+
+	lists, err := GetTodoLists(userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lists) != 1 || lists[0].ID != shared.ID || lists[0].Role != models.RoleEditor {
+		t.Errorf("списки участника после восстановления: %+v, want %q с ролью редактора", lists, shared.Title)
+	}
+}
+
+// Восстановление пользователя сохраняет назначения в чужих списках и уведомления
+// других участников о задачах из его списков
+func TestRestoreUserKeepsAssignments(t *testing.T) {
+	openTestDB(t)
+	userID := mustCreateUser(t)
+	memberID := mustCreateUser(t)
+	ownerID := mustCreateUser(t)
+
+	own := mustCreateList(t, userID, "Свой")
+	if err := ShareList(userID, own.ID, memberID, models.RoleEditor); err != nil {
+		t.Fatal(err)
+	}
+	mustCreateTask(t, userID, models.Task{ListID: own.ID, Title: "Для участника", AssigneeID: memberID})
+
+	shared := mustCreateList(t, ownerID, "Чужой")
+	if err := ShareList(ownerID, shared.ID, userID, models.RoleEditor); err != nil {
+		t.Fatal(err)
+	}
+	assigned := mustCreateTask(t, ownerID, models.Task{ListID: shared.ID, Title: "Для пользователя", AssigneeID: userID})
+	// Задача с уведомлением пользователю удаляется уже после снимка
+	removed := mustCreateTask(t, ownerID, models.Task{ListID: shared.ID, Title: "Удалённая", AssigneeID: userID})
+
+	dump, err := DumpData(userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := DeleteTask(ownerID, removed.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := RestoreUser(dump, userID); err != nil {
+		t.Fatalf("RestoreUser: %v", err)
+	}
+
+	tasks, err := GetAssignedTasks(userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tasks) != 1 || tasks[0].ID != assigned.ID {
+		t.Errorf("задачи, назначенные пользователю: %+v, want только %q", tasks, assigned.Title)
+	}
+	for _, recipient := range []int{userID, memberID} {
+		notifications, err := GetNotifications(recipient, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(notifications) != 1 {
+			t.Errorf("у пользователя %d уведомлений: %d, want 1", recipient, len(notifications))
+		}
+	}
+}
+
+// Комментарии пользователя в чужих списках и чужие комментарии в его списках переживают восстановление
+func TestRestoreUserKeepsComments(t *testing.T) {
+	openTestDB(t)
+	userID := mustCreateUser(t)
+	ownerID := mustCreateUser(t)
+
+	own := mustCreateList(t, userID, "Свой")
+	if err := ShareList(userID, own.ID, ownerID, models.RoleViewer); err != nil {
+		t.Fatal(err)
+	}
+	ownTask := mustCreateTask(t, userID, models.Task{ListID: own.ID, Title: "Обсудить"})
+	shared := mustCreateList(t, ownerID, "Чужой")
+	if err := ShareList(ownerID, shared.ID, userID, models.RoleViewer); err != nil {
+		t.Fatal(err)
+	}
+	sharedTask := mustCreateTask(t, ownerID, models.Task{ListID: shared.ID, Title: "Тоже обсудить"})
+
+	for _, c := range []models.Comment{
+		{TaskID: ownTask.ID, UserID: ownerID, Body: "Чужой комментарий"},
+		{TaskID: sharedTask.ID, UserID: userID, Body: "Свой комментарий"},
+	} {
+		if err := AddComment(&c); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	dump, err := DumpData(userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := RestoreUser(dump, userID); err != nil {
+		t.Fatalf("RestoreUser: %v", err)
+	}
+
+	for _, tt := range []struct {
+		taskID, authorID int
+	}{
+		{ownTask.ID, ownerID},
+		{sharedTask.ID, userID},
+	} {
+		comments, err := GetComments(tt.taskID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(comments) != 1 || comments[0].UserID != tt.authorID {
+			t.Errorf("комментарии к задаче %d: %+v, want один от пользователя %d", tt.taskID, comments, tt.authorID)
+		}
+	}
+}
+
+// Папка, в которую пользователь положил открытый ему список, сохраняется при восстановлении
+func TestRestoreUserKeepsSharedListFolder(t *testing.T) {
+	openTestDB(t)
+	userID := mustCreateUser(t)
+	ownerID := mustCreateUser(t)
+	shared := mustCreateList(t, ownerID, "Общий")
+	if err := ShareList(ownerID, shared.ID, userID, models.RoleViewer); err != nil {
+		t.Fatal(err)
+	}
+	folder := models.Folder{UserID: userID, Title: "Работа"}
+	if err := CreateFolder(&folder); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetListFolder(userID, shared.ID, folder.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	dump, err := DumpData(userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := RestoreUser(dump, userID); err != nil {
+		t.Fatalf("RestoreUser: %v", err)
+	}
+
+	lists, err := GetTodoLists(userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lists) != 1 || lists[0].FolderID != folder.ID {
+		t.Errorf("открытый список после восстановления: %+v, want в папке %d", lists, folder.ID)
+	}
+}
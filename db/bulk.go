@@ -0,0 +1,457 @@
+// bulk.go
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+	"todolist/models"
+
+	"github.com/lib/pq"
+)
+
+// Групповые действия над задачами выполняются одной транзакцией и запоминают
+// прежнее состояние затронутых задач с подзадачами, чтобы действие можно было
+// отменить целиком (см. UndoLast). История отмены хранится в памяти для каждого
+// пользователя и пропадает при выходе из программы.
+
+const undoDepth = 20
+
+// Таблицы, строки которых сохраняются для отмены; $1 - ID задач с подзадачами
+var undoTables = []struct{ name, where string }{
+	{"tasks", "id = ANY($1)"},
+	{"task_tags", "task_id = ANY($1)"},
+	{"comments", "task_id = ANY($1)"},
+	{"attachments", "task_id = ANY($1)"},
+	{"notifications", "task_id = ANY($1)"},
+}
+
+// undoEntry - одно групповое действие: строки задач до него в JSON, по имени таблицы
+type undoEntry struct {
+	label string
+	rows  map[string]string
+	// orphans - содержимое вложений удалённых задач; удаляется, только когда отмена становится невозможной
+	orphans []string
+	// archived - отметки об архиве затронутых списков: действие могло убрать список в архив или вернуть
+	archived map[int]sql.NullTime
+}
+
+// saveArchived запоминает отметки об архиве списков listIDs, если они ещё не запомнены
+func (e *undoEntry) saveArchived(q querier, listIDs []int64) error {
+	rows, err := q.Query("SELECT id, archived_at FROM todo_lists WHERE id = ANY($1)", pq.Array(listIDs))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			id int
+			at sql.NullTime
+		)
+		if err := rows.Scan(&id, &at); err != nil {
+			return err
+		}
+		if _, ok := e.archived[id]; !ok {
+			e.archived[id] = at
+		}
+	}
+	return rows.Err()
+}
+
+var (
+	undoMu     sync.Mutex
+	undoStacks = make(map[int][]*undoEntry)
+)
+
+func pushUndo(userID int, e *undoEntry) {
+	undoMu.Lock()
+	stack := append(undoStacks[userID], e)
+	var dropped []*undoEntry
+	if len(stack) > undoDepth {
+		dropped, stack = stack[:len(stack)-undoDepth], stack[len(stack)-undoDepth:]
+	}
+	undoStacks[userID] = stack
+	undoMu.Unlock()
+	releaseUndo(dropped)
+}
+
+func lastUndo(userID int) *undoEntry {
+	undoMu.Lock()
+	defer undoMu.Unlock()
+	stack := undoStacks[userID]
+	if len(stack) == 0 {
+		return nil
+	}
+	return stack[len(stack)-1]
+}
+
+// dropUndo снимает запись e со стека пользователя
+func dropUndo(userID int, e *undoEntry) {
+	undoMu.Lock()
+	defer undoMu.Unlock()
+	stack := undoStacks[userID]
+	for i, item := range stack {
+		if item == e {
+			undoStacks[userID] = append(stack[:i:i], stack[i+1:]...)
+			return
+		}
+	}
+}
+
+// undoHeld возвращает хеши содержимого вложений, которое держат записи отмены
+func undoHeld() map[string]bool {
+	undoMu.Lock()
+	defer undoMu.Unlock()
+	held := make(map[string]bool)
+	for _, stack := range undoStacks {
+		for _, e := range stack {
+			for _, hash := range e.orphans {
+				held[hash] = true
+			}
+		}
+	}
+	return held
+}
+
+// releaseUndo удаляет содержимое вложений, которое держали отброшенные записи отмены
+func releaseUndo(entries []*undoEntry) {
+	var candidates []string
+	for _, e := range entries {
+		candidates = append(candidates, e.orphans...)
+	}
+	if len(candidates) == 0 {
+		return
+	}
+	var orphans []string
+	err := withTx(func(tx *sql.Tx) error {
+		var err error
+		orphans, err = removeOrphanBlobs(tx, candidates)
+		return err
+	})
+	if err != nil {
+		log.Printf("Ошибка удаления содержимого вложений: %v", err)
+		return
+	}
+	removeBlobFiles(orphans)
+}
+
+// DiscardUndo забывает историю отмены пользователя (userID = 0 - всех пользователей)
+func DiscardUndo(userID int) {
+	undoMu.Lock()
+	var dropped []*undoEntry
+	for id, stack := range undoStacks {
+		if userID == 0 || id == userID {
+			dropped = append(dropped, stack...)
+			delete(undoStacks, id)
+		}
+	}
+	undoMu.Unlock()
+	releaseUndo(dropped)
+}
+
+// UndoLabel возвращает название действия, которое отменит UndoLast
+func UndoLabel(userID int) (string, bool) {
+	undoMu.Lock()
+	defer undoMu.Unlock()
+	stack := undoStacks[userID]
+	if len(stack) == 0 {
+		return "", false
+	}
+	return stack[len(stack)-1].label, true
+}
+
+// withUndo выполняет групповое действие fn над задачами taskIDs в одной транзакции,
+// предварительно проверив роль редактора, и запоминает их прежнее состояние
+func withUndo(userID int, label string, taskIDs []int, fn func(tx *sql.Tx, e *undoEntry) error) error {
+	if len(taskIDs) == 0 {
+		return nil
+	}
+	e := &undoEntry{label: label, rows: make(map[string]string), archived: make(map[int]sql.NullTime)}
+	err := withTx(func(tx *sql.Tx) error {
+		if err := requireTasksRole(tx, userID, taskIDs, models.RoleEditor); err != nil {
+			return err
+		}
+		var ids []int64
+		if err := tx.QueryRow(
+			`WITH RECURSIVE sub AS (
+				SELECT id FROM tasks WHERE id = ANY($1)
+				UNION SELECT t.id FROM tasks t JOIN sub ON t.parent_id = sub.id
+			) SELECT array_agg(id) FROM sub`,
+			pq.Array(taskIDs),
+		).Scan(pq.Array(&ids)); err != nil {
+			return err
+		}
+		for _, t := range undoTables {
+			var rows string
+			if err := tx.QueryRow(
+				"SELECT COALESCE(json_agg(x), '[]') FROM (SELECT * FROM "+t.name+" WHERE "+t.where+") x",
+				pq.Array(ids),
+			).Scan(&rows); err != nil {
+				return err
+			}
+			e.rows[t.name] = rows
+		}
+		var listIDs []int64
+		if err := tx.QueryRow(
+			"SELECT COALESCE(array_agg(DISTINCT list_id), '{}') FROM tasks WHERE id = ANY($1)", pq.Array(ids),
+		).Scan(pq.Array(&listIDs)); err != nil {
+			return err
+		}
+		if err := e.saveArchived(tx, listIDs); err != nil {
+			return err
+		}
+		return fn(tx, e)
+	})
+	if err != nil {
+		return err
+	}
+	pushUndo(userID, e)
+	return nil
+}
+
+// requireTasksRole проверяет роль пользователя во всех списках, где лежат задачи
+func requireTasksRole(q querier, userID int, taskIDs []int, need models.Role) error {
+	rows, err := q.Query("SELECT DISTINCT list_id FROM tasks WHERE id = ANY($1)", pq.Array(taskIDs))
+	if err != nil {
+		return err
+	}
+	var listIDs []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return err
+		}
+		listIDs = append(listIDs, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for _, id := range listIDs {
+		if err := requireListRole(q, userID, id, need); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// UndoLast отменяет последнее групповое действие пользователя и возвращает его название.
+// Задачи возвращаются в прежнее состояние вместе с подзадачами, тегами, комментариями и
+// вложениями, а их списки - в архив или из архива, как было; изменения, сделанные с ними
+// после действия, теряются.
+func UndoLast(userID int) (string, error) {
+	// Запись снимается со стека только после отмены, чтобы до коммита её вложения не сочли ненужными
+	e := lastUndo(userID)
+	if e == nil {
+		return "", fmt.Errorf("отменять нечего")
+	}
+	err := withTx(func(tx *sql.Tx) error {
+		// Прав могли лишить после действия: проверяем списки, куда вернутся задачи
+		var listIDs []int64
+		if err := tx.QueryRow(
+			"SELECT COALESCE(array_agg(DISTINCT list_id), '{}') FROM json_populate_recordset(NULL::tasks, $1::json)",
+			e.rows["tasks"],
+		).Scan(pq.Array(&listIDs)); err != nil {
+			return err
+		}
+		for _, id := range listIDs {
+			if err := requireListRole(tx, userID, int(id), models.RoleEditor); err != nil {
+				return err
+			}
+		}
+
+		columns, err := tableColumns(tx, "tasks")
+		if err != nil {
+			return err
+		}
+		set := make([]string, len(columns))
+		for i, c := range columns {
+			set[i] = c + " = EXCLUDED." + c
+		}
+		if _, err := tx.Exec(
+			"INSERT INTO tasks SELECT * FROM json_populate_recordset(NULL::tasks, $1::json) ON CONFLICT (id) DO UPDATE SET "+strings.Join(set, ", "),
+			e.rows["tasks"],
+		); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(
+			"DELETE FROM task_tags WHERE task_id IN (SELECT id FROM json_populate_recordset(NULL::tasks, $1::json))",
+			e.rows["tasks"],
+		); err != nil {
+			return err
+		}
+		for _, t := range undoTables[1:] {
+			conflict := " ON CONFLICT (id) DO NOTHING"
+			if t.name == "task_tags" {
+				conflict = ""
+			}
+			if _, err := tx.Exec(
+				"INSERT INTO "+t.name+" SELECT * FROM json_populate_recordset(NULL::"+t.name+", $1::json)"+conflict,
+				e.rows[t.name],
+			); err != nil {
+				return err
+			}
+		}
+		for id, at := range e.archived {
+			if _, err := tx.Exec("UPDATE todo_lists SET archived_at = $1 WHERE id = $2", at, id); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	dropUndo(userID, e)
+	if err != nil {
+		// Повторная попытка, скорее всего, упадёт так же: запись отбрасывается
+		releaseUndo([]*undoEntry{e})
+		return "", err
+	}
+	return e.label, nil
+}
+
+// tableColumns возвращает колонки таблицы в порядке их объявления
+func tableColumns(q querier, table string) ([]string, error) {
+	rows, err := q.Query(
+		"SELECT column_name FROM information_schema.columns WHERE table_schema = current_schema() AND table_name = $1 ORDER BY ordinal_position",
+		table,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var columns []string
+	for rows.Next() {
+		var c string
+		if err := rows.Scan(&c); err != nil {
+			return nil, err
+		}
+		columns = append(columns, c)
+	}
+	return columns, rows.Err()
+}
+
+// completionSQL - отметка о выполнении: $1 - выполнено ли, $2 - текущее время.
+// Колонку после неё нужно выбрать заново (см. freeStatus).
+const completionSQL = "is_done = $1, completed_at = CASE WHEN $1 THEN COALESCE(completed_at, $2) END"
+
+// CompleteTasks отмечает задачи выполненными или снова открывает их. Задачи переходят
+// в первые колонки нужного вида, где есть место; если места нет, действие отменяется.
+func CompleteTasks(userID int, taskIDs []int, done bool) error {
+	label := "Отметка о выполнении"
+	if !done {
+		label = "Возврат в работу"
+	}
+	return withUndo(userID, label, taskIDs, func(tx *sql.Tx, _ *undoEntry) error {
+		rows, err := tx.Query(
+			"UPDATE tasks SET "+completionSQL+" WHERE id = ANY($3) AND is_done <> $1 RETURNING id, list_id",
+			done, time.Now(), pq.Array(taskIDs),
+		)
+		if err != nil {
+			return err
+		}
+		changed := make(map[int]int) // задача -> список
+		seen := make(map[int]bool)
+		var listIDs []int
+		for rows.Next() {
+			var id, listID int
+			if err := rows.Scan(&id, &listID); err != nil {
+				rows.Close()
+				return err
+			}
+			if !seen[listID] {
+				seen[listID] = true
+				listIDs = append(listIDs, listID)
+			}
+			changed[id] = listID
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		for _, id := range listIDs {
+			if err := ensureListStatuses(tx, id); err != nil {
+				return err
+			}
+		}
+		for _, id := range taskIDs {
+			listID, ok := changed[id]
+			if !ok {
+				continue
+			}
+			statusID, err := freeStatus(tx, listID, done, id)
+			if err != nil {
+				return err
+			}
+			if _, err := tx.Exec("UPDATE tasks SET status_id = $1 WHERE id = $2", statusID, id); err != nil {
+				return err
+			}
+		}
+		return autoArchive(tx, listIDs...)
+	})
+}
+
+// DeleteTasks удаляет задачи с подзадачами. Содержимое их вложений остаётся,
+// пока удаление можно отменить.
+func DeleteTasks(userID int, taskIDs []int) error {
+	return withUndo(userID, "Удаление", taskIDs, func(tx *sql.Tx, e *undoEntry) error {
+		hashes, err := attachmentHashes(tx, `task_id IN (
+			WITH RECURSIVE sub AS (
+				SELECT id FROM tasks WHERE id = ANY($1)
+				UNION ALL
+				SELECT t.id FROM tasks t JOIN sub ON t.parent_id = sub.id
+			) SELECT id FROM sub)`, pq.Array(taskIDs))
+		if err != nil {
+			return err
+		}
+		e.orphans = hashes
+		var listIDs []int64
+		if err := tx.QueryRow(
+			"WITH deleted AS (DELETE FROM tasks WHERE id = ANY($1) RETURNING list_id) SELECT COALESCE(array_agg(DISTINCT list_id), '{}') FROM deleted",
+			pq.Array(taskIDs),
+		).Scan(pq.Array(&listIDs)); err != nil {
+			return err
+		}
+		ids := make([]int, len(listIDs))
+		for i, id := range listIDs {
+			ids[i] = int(id)
+		}
+		return autoArchive(tx, ids...)
+	})
+}
+
+// SetTasksDue ставит задачам срок due; нулевое время снимает срок (см. models.Task.HasDue)
+func SetTasksDue(userID int, taskIDs []int, due time.Time) error {
+	return withUndo(userID, "Изменение срока", taskIDs, func(tx *sql.Tx, _ *undoEntry) error {
+		_, err := tx.Exec("UPDATE tasks SET due_date = $1 WHERE id = ANY($2)", due, pq.Array(taskIDs))
+		return err
+	})
+}
+
+// PostponeTasks сдвигает сроки задач на days дней; задачи без срока не меняются
+func PostponeTasks(userID int, taskIDs []int, days int) error {
+	return withUndo(userID, "Перенос срока", taskIDs, func(tx *sql.Tx, _ *undoEntry) error {
+		_, err := tx.Exec(
+			"UPDATE tasks t SET due_date = t.due_date + make_interval(days => $1) WHERE t.id = ANY($2) AND "+taskHasDue,
+			days, pq.Array(taskIDs),
+		)
+		return err
+	})
+}
+
+// TagTasks добавляет задачам тег, не трогая остальные
+func TagTasks(userID int, taskIDs []int, tag string) error {
+	tags := NormalizeTags([]string{tag})
+	if len(tags) == 0 {
+		return fmt.Errorf("тег не может быть пустым")
+	}
+	return withUndo(userID, "Тег #"+tags[0], taskIDs, func(tx *sql.Tx, _ *undoEntry) error {
+		_, err := tx.Exec(
+			"INSERT INTO task_tags (task_id, tag) SELECT id, $1 FROM tasks WHERE id = ANY($2) ON CONFLICT DO NOTHING",
+			tags[0], pq.Array(taskIDs),
+		)
+		return err
+	})
+}
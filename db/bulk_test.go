@@ -0,0 +1,51 @@
+package db
+
+import (
+	"testing"
+	"todolist/models"
+)
+
+// Содержимое вложения удалённой задачи нужно для отмены, даже если удалили и другое вложение с ним же
+func TestUndoDeleteKeepsSharedBlob(t *testing.T) {
+	openTestDB(t)
+	t.Setenv("TODO_ATTACHMENTS_DIR", "")
+	t.Cleanup(func() { DiscardUndo(0) })
+	userID := mustCreateUser(t)
+	list := mustCreateList(t, userID, "Дом")
+	first := mustCreateTask(t, userID, models.Task{ListID: list.ID, Title: "С вложением"})
+	second := mustCreateTask(t, userID, models.Task{ListID: list.ID, Title: "С тем же вложением"})
+
+	data := []byte("квитанция")
+	if _, err := AddAttachment(userID, first.ID, "a.txt", data); err != nil {
+		t.Fatal(err)
+	}
+	copied, err := AddAttachment(userID, second.ID, "b.txt", data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := DeleteTasks(userID, []int{first.ID}); err != nil {
+		t.Fatal(err)
+	}
+	if err := DeleteAttachment(userID, copied.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := UndoLast(userID); err != nil {
+		t.Fatalf("UndoLast: %v", err)
+	}
+
+	attachments, err := GetAttachments(first.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(attachments) != 1 {
+		t.Fatalf("вложений после отмены: %d, want 1", len(attachments))
+	}
+	got, err := ReadAttachment(attachments[0])
+	if err != nil {
+		t.Fatalf("ReadAttachment: %v", err)
+	}
+	if string(got) != string(data) {
+		t.Errorf("содержимое после отмены %q, want %q", got, data)
+	}
+}
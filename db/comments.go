@@ -0,0 +1,153 @@
+// comments.go
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"todolist/models"
+
+	"github.com/lib/pq"
+)
+
+// loadCommentCounts заполняет число комментариев у переданных задач одним запросом
+func loadCommentCounts(q querier, tasks []models.Task) error {
+	if len(tasks) == 0 {
+		return nil
+	}
+
+	ids := make([]int64, len(tasks))
+	index := make(map[int]int, len(tasks))
+	for i, task := range tasks {
+		ids[i] = int64(task.ID)
+		index[task.ID] = i
+	}
+
+	rows, err := q.Query("SELECT task_id, COUNT(*) FROM comments WHERE task_id = ANY($1) GROUP BY task_id", pq.Array(ids))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var taskID, count int
+		if err := rows.Scan(&taskID, &count); err != nil {
+			return err
+		}
+		if i, ok := index[taskID]; ok {
+			tasks[i].CommentCount = count
+		}
+	}
+	return rows.Err()
+}
+
+// GetComments возвращает обсуждение задачи в хронологическом порядке
+func GetComments(taskID int) ([]models.Comment, error) {
+	rows, err := DB.Query(
+		"SELECT id, task_id, COALESCE(user_id, 0), body, created_at, updated_at FROM comments WHERE task_id = $1 ORDER BY created_at, id",
+		taskID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var comments []models.Comment
+	for rows.Next() {
+		var (
+			c         models.Comment
+			updatedAt sql.NullTime
+		)
+		if err := rows.Scan(&c.ID, &c.TaskID, &c.UserID, &c.Body, &c.CreatedAt, &updatedAt); err != nil {
+			return nil, err
+		}
+		c.UpdatedAt = updatedAt.Time
+		comments = append(comments, c)
+	}
+	return comments, rows.Err()
+}
+
+// AddComment добавляет комментарий от имени comment.UserID.
+// Обсуждать задачу могут все, кому виден список, включая читателей.
+func AddComment(comment *models.Comment) error {
+	comment.Body = strings.TrimSpace(comment.Body)
+	if comment.Body == "" {
+		return fmt.Errorf("комментарий пуст")
+	}
+	return withTx(func(tx *sql.Tx) error {
+		if err := requireTaskRole(tx, comment.UserID, comment.TaskID, models.RoleViewer); err != nil {
+			return err
+		}
+		comment.CreatedAt = time.Now()
+		if err := tx.QueryRow(
+			"INSERT INTO comments (task_id, user_id, body, created_at) VALUES ($1, $2, $3, $4) RETURNING id",
+			comment.TaskID, comment.UserID, comment.Body, comment.CreatedAt,
+		).Scan(&comment.ID); err != nil {
+			return err
+		}
+
+		// Исполнителю сообщаем о новых комментариях к его задаче
+		var assigneeID sql.NullInt64
+		if err := tx.QueryRow("SELECT assignee_id FROM tasks WHERE id = $1", comment.TaskID).Scan(&assigneeID); err != nil {
+			return err
+		}
+		if assigneeID.Valid && int(assigneeID.Int64) != comment.UserID {
+			if _, err := tx.Exec(
+				"INSERT INTO notifications (user_id, actor_id, task_id, kind, created_at) VALUES ($1, $2, $3, $4, NOW())",
+				assigneeID.Int64, comment.UserID, comment.TaskID, models.NotifyCommented,
+			); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// UpdateComment меняет текст комментария; править можно только свои комментарии
+func UpdateComment(userID int, comment *models.Comment) error {
+	body := strings.TrimSpace(comment.Body)
+	if body == "" {
+		return fmt.Errorf("комментарий пуст")
+	}
+	return withTx(func(tx *sql.Tx) error {
+		authorID, _, err := commentAuthor(tx, comment.ID)
+		if err != nil {
+			return err
+		}
+		if authorID != userID {
+			return ErrAccessDenied
+		}
+		comment.Body = body
+		comment.UpdatedAt = time.Now()
+		_, err = tx.Exec("UPDATE comments SET body = $1, updated_at = $2 WHERE id = $3", comment.Body, comment.UpdatedAt, comment.ID)
+		return err
+	})
+}
+
+// DeleteComment удаляет комментарий; это может сделать автор или владелец списка
+func DeleteComment(userID, commentID int) error {
+	return withTx(func(tx *sql.Tx) error {
+		authorID, taskID, err := commentAuthor(tx, commentID)
+		if err != nil {
+			return err
+		}
+		if authorID != userID {
+			if err := requireTaskRole(tx, userID, taskID, models.RoleOwner); err != nil {
+				return err
+			}
+		}
+		_, err = tx.Exec("DELETE FROM comments WHERE id = $1", commentID)
+		return err
+	})
+}
+
+func commentAuthor(q querier, commentID int) (authorID, taskID int, err error) {
+	var author sql.NullInt64
+	err = q.QueryRow("SELECT user_id, task_id FROM comments WHERE id = $1", commentID).Scan(&author, &taskID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, 0, fmt.Errorf("комментарий не найден")
+	}
+	return int(author.Int64), taskID, err
+}
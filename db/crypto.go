@@ -0,0 +1,415 @@
+// crypto.go
+package db
+
+// Шифрование содержимого: названия и описания списков и задач, а также шаблонов списков.
+//
+// У пользователя со включённым шифрованием есть случайный ключ данных. В users.enc_key
+// он хранится зашифрованным ключом, выведенным из пароля пользователя (auth.WrapKey), поэтому
+// шифрование требует заданного пароля, причём не PIN (см. auth.CheckPassphrase), а смена пароля
+// лишь перешифровывает ключ.
+// После входа ключ держится в памяти (UnlockUserKey) и забывается при блокировке (LockUserKeys).
+//
+// Поля шифруются ключом владельца списка и хранятся в тех же колонках в виде
+// "enc:v1:<id владельца>:<шифротекст>", так что чтение расшифровывает их без лишних запросов.
+// Пока ключ владельца не открыт, вместо текста отдаётся LockedText, а запись в его списки
+// отклоняется с ErrKeyLocked. Зашифрованные списки нельзя открывать другим пользователям.
+//
+// Не шифруются теги, комментарии, вложения, умные списки, названия статусов и папок, сроки,
+// приоритеты и прочие служебные поля.
+//
+// Поиск: база видит только шифротекст, поэтому условия в SQL (LIKE, полнотекстовые индексы)
+// в зашифрованных полях ничего не находят. Искать по ним можно только в приложении после
+// расшифровки, то есть лишь в списках пользователей, чей ключ сейчас открыт.
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"todolist/auth"
+)
+
+const sealedPrefix = "enc:v1:"
+
+// LockedText подставляется вместо зашифрованного поля, когда ключ владельца не открыт
+const LockedText = "🔒 Зашифровано"
+
+var (
+	// ErrKeyLocked - запись в зашифрованный список без открытого ключа владельца
+	ErrKeyLocked = errors.New("содержимое зашифровано: войдите под владельцем списка")
+	// ErrSharedEncrypted - попытка открыть доступ к зашифрованным спискам
+	ErrSharedEncrypted = errors.New("зашифрованные списки нельзя открывать другим пользователям")
+)
+
+var (
+	keysMu   sync.RWMutex
+	userKeys = make(map[int][]byte)
+)
+
+func userKey(userID int) []byte {
+	keysMu.RLock()
+	defer keysMu.RUnlock()
+	return userKeys[userID]
+}
+
+// setUserKey запоминает открытый ключ пользователя; nil его забывает
+func setUserKey(userID int, key []byte) {
+	keysMu.Lock()
+	defer keysMu.Unlock()
+	if key == nil {
+		delete(userKeys, userID)
+	} else {
+		userKeys[userID] = key
+	}
+}
+
+// LockUserKeys забывает все открытые ключи
+func LockUserKeys() {
+	keysMu.Lock()
+	defer keysMu.Unlock()
+	userKeys = make(map[int][]byte)
+}
+
+// wrappedKey возвращает зашифрованный паролем ключ данных; пустая строка - шифрование выключено
+func wrappedKey(q querier, userID int) (string, error) {
+	var wrapped sql.NullString
+	err := q.QueryRow("SELECT enc_key FROM users WHERE id = $1", userID).Scan(&wrapped)
+	return wrapped.String, err
+}
+
+// EncryptionEnabled сообщает, шифруются ли данные пользователя
+func EncryptionEnabled(userID int) (bool, error) {
+	wrapped, err := wrappedKey(DB, userID)
+	return wrapped != "", err
+}
+
+// UnlockUserKey открывает ключ пользователя его паролем после входа.
+// Если шифрование выключено, ничего не делает.
+func UnlockUserKey(userID int, passphrase string) error {
+	wrapped, err := wrappedKey(DB, userID)
+	if err != nil || wrapped == "" {
+		return err
+	}
+	key, err := auth.UnwrapKey(wrapped, passphrase)
+	if err != nil {
+		return err
+	}
+	setUserKey(userID, key)
+	return nil
+}
+
+func isSealed(s string) bool {
+	return strings.HasPrefix(s, sealedPrefix)
+}
+
+func sealWith(ownerID int, key []byte, text string) (string, error) {
+	sealed, err := auth.Seal(key, text)
+	if err != nil {
+		return "", err
+	}
+	return sealedPrefix + strconv.Itoa(ownerID) + ":" + sealed, nil
+}
+
+// openWith расшифровывает поле указанным ключом; незашифрованное поле возвращается как есть
+func openWith(key []byte, s string) (string, error) {
+	if !isSealed(s) {
+		return s, nil
+	}
+	_, data, ok := strings.Cut(s[len(sealedPrefix):], ":")
+	if !ok {
+		return "", fmt.Errorf("повреждённое зашифрованное поле")
+	}
+	return auth.Open(key, data)
+}
+
+// openField расшифровывает поле открытым ключом владельца или возвращает LockedText
+func openField(s string) string {
+	if !isSealed(s) {
+		return s
+	}
+	idText, _, _ := strings.Cut(s[len(sealedPrefix):], ":")
+	ownerID, err := strconv.Atoi(idText)
+	if err != nil {
+		return LockedText
+	}
+	key := userKey(ownerID)
+	if key == nil {
+		return LockedText
+	}
+	text, err := openWith(key, s)
+	if err != nil {
+		return LockedText
+	}
+	return text
+}
+
+// sealer шифрует поля для записи; при выключенном шифровании возвращает их без изменений
+type sealer func(string) (string, error)
+
+func plainText(s string) (string, error) { return s, nil }
+
+// ownerSealer выбирает шифрование по владельцу: query по args должен вернуть id владельца и его enc_key
+func ownerSealer(q querier, query string, args ...any) (sealer, error) {
+	var (
+		ownerID int
+		wrapped sql.NullString
+	)
+	if err := q.QueryRow(query, args...).Scan(&ownerID, &wrapped); err != nil {
+		return nil, err
+	}
+	if !wrapped.Valid {
+		return plainText, nil
+	}
+	key := userKey(ownerID)
+	if key == nil {
+		return nil, ErrKeyLocked
+	}
+	return func(s string) (string, error) { return sealWith(ownerID, key, s) }, nil
+}
+
+func userSealer(q querier, userID int) (sealer, error) {
+	return ownerSealer(q, "SELECT id, enc_key FROM users WHERE id = $1", userID)
+}
+
+func listSealer(q querier, listID int) (sealer, error) {
+	return ownerSealer(q, "SELECT u.id, u.enc_key FROM todo_lists l JOIN users u ON u.id = l.user_id WHERE l.id = $1", listID)
+}
+
+// taskSealer шифрует ключом владельца списка, в котором задача лежит сейчас
+func taskSealer(q querier, taskID int) (sealer, error) {
+	return ownerSealer(q, "SELECT u.id, u.enc_key FROM tasks t JOIN todo_lists l ON l.id = t.list_id JOIN users u ON u.id = l.user_id WHERE t.id = $1", taskID)
+}
+
+// sealPair шифрует название и описание
+func sealPair(seal sealer, title, description string) (string, string, error) {
+	title, err := seal(title)
+	if err != nil {
+		return "", "", err
+	}
+	description, err = seal(description)
+	if err != nil {
+		return "", "", err
+	}
+	return title, description, nil
+}
+
+// EnableEncryption шифрует все списки и задачи пользователя новым ключом.
+// passphrase - текущий пароль пользователя, им же будет открываться ключ;
+// он должен пройти auth.CheckPassphrase.
+func EnableEncryption(userID int, passphrase string) error {
+	if err := checkPassphrase(userID, passphrase); err != nil {
+		return err
+	}
+	if err := auth.CheckPassphrase(passphrase); err != nil {
+		return fmt.Errorf("%v. Смените пароль и включите шифрование снова", err)
+	}
+	key, err := auth.NewDataKey()
+	if err != nil {
+		return err
+	}
+	err = withTx(func(tx *sql.Tx) error {
+		wrapped, err := wrappedKey(tx, userID)
+		if err != nil {
+			return err
+		}
+		if wrapped != "" {
+			return fmt.Errorf("шифрование уже включено")
+		}
+		var shared bool
+		if err := tx.QueryRow("SELECT EXISTS (SELECT 1 FROM list_members m JOIN todo_lists l ON l.id = m.list_id WHERE l.user_id = $1)", userID).Scan(&shared); err != nil {
+			return err
+		}
+		if shared {
+			return fmt.Errorf("%w: сначала закройте доступ к своим спискам", ErrSharedEncrypted)
+		}
+		if err := recryptUser(tx, userID, nil, key); err != nil {
+			return err
+		}
+		return storeWrappedKey(tx, userID, key, passphrase)
+	})
+	if err != nil {
+		return err
+	}
+	setUserKey(userID, key)
+	// Отмена вернула бы в базу незашифрованный текст
+	DiscardUndo(userID)
+	return nil
+}
+
+// DisableEncryption расшифровывает данные пользователя и удаляет ключ
+func DisableEncryption(userID int, passphrase string) error {
+	err := withTx(func(tx *sql.Tx) error {
+		key, err := unwrapStoredKey(tx, userID, passphrase)
+		if err != nil {
+			return err
+		}
+		if err := recryptUser(tx, userID, key, nil); err != nil {
+			return err
+		}
+		_, err = tx.Exec("UPDATE users SET enc_key = NULL WHERE id = $1", userID)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	setUserKey(userID, nil)
+	// Сохранённые для отмены строки зашифрованы прежним ключом
+	DiscardUndo(userID)
+	return nil
+}
+
+// RotateEncryptionKey перешифровывает данные пользователя новым ключом.
+// Старые резервные копии остаются зашифрованы прежним ключом и восстанавливаются
+// вместе с ним только при восстановлении пользователя целиком.
+func RotateEncryptionKey(userID int, passphrase string) error {
+	// Шифрование могли включить до проверки пароля: новый ключ под слабым паролем не кладём
+	if err := auth.CheckPassphrase(passphrase); err != nil {
+		return fmt.Errorf("%v. Сначала смените пароль", err)
+	}
+	key, err := auth.NewDataKey()
+	if err != nil {
+		return err
+	}
+	err = withTx(func(tx *sql.Tx) error {
+		old, err := unwrapStoredKey(tx, userID, passphrase)
+		if err != nil {
+			return err
+		}
+		if err := recryptUser(tx, userID, old, key); err != nil {
+			return err
+		}
+		return storeWrappedKey(tx, userID, key, passphrase)
+	})
+	if err != nil {
+		return err
+	}
+	setUserKey(userID, key)
+	DiscardUndo(userID)
+	return nil
+}
+
+// SetUserPassword сохраняет хеш нового пароля; пустой hash снимает защиту.
+// Если данные пользователя зашифрованы, ключ перешифровывается новым паролем,
+// а снять пароль или заменить его на слабый (см. auth.CheckPassphrase) нельзя, пока шифрование не выключено.
+func SetUserPassword(userID int, hash, oldSecret, newSecret string) error {
+	return withTx(func(tx *sql.Tx) error {
+		wrapped, err := wrappedKey(tx, userID)
+		if err != nil {
+			return err
+		}
+		if wrapped == "" {
+			_, err := tx.Exec("UPDATE users SET password_hash = $1 WHERE id = $2", nullString(hash), userID)
+			return err
+		}
+		if hash == "" {
+			return fmt.Errorf("данные зашифрованы паролем: сначала отключите шифрование")
+		}
+		if err := auth.CheckPassphrase(newSecret); err != nil {
+			return fmt.Errorf("данные зашифрованы паролем: %v", err)
+		}
+		key, err := auth.UnwrapKey(wrapped, oldSecret)
+		if err != nil {
+			return err
+		}
+		if err := storeWrappedKey(tx, userID, key, newSecret); err != nil {
+			return err
+		}
+		_, err = tx.Exec("UPDATE users SET password_hash = $1 WHERE id = $2", hash, userID)
+		return err
+	})
+}
+
+// checkPassphrase проверяет, что passphrase совпадает с паролем пользователя
+func checkPassphrase(userID int, passphrase string) error {
+	hash, err := GetUserPasswordHash(userID)
+	if err != nil {
+		return err
+	}
+	if hash == "" {
+		return fmt.Errorf("для шифрования сначала задайте пароль")
+	}
+	if !auth.VerifySecret(passphrase, hash) {
+		return auth.ErrWrongSecret
+	}
+	return nil
+}
+
+func unwrapStoredKey(q querier, userID int, passphrase string) ([]byte, error) {
+	wrapped, err := wrappedKey(q, userID)
+	if err != nil {
+		return nil, err
+	}
+	if wrapped == "" {
+		return nil, fmt.Errorf("шифрование не включено")
+	}
+	return auth.UnwrapKey(wrapped, passphrase)
+}
+
+func storeWrappedKey(q querier, userID int, key []byte, passphrase string) error {
+	wrapped, err := auth.WrapKey(key, passphrase)
+	if err != nil {
+		return err
+	}
+	_, err = q.Exec("UPDATE users SET enc_key = $1 WHERE id = $2", wrapped, userID)
+	return err
+}
+
+// recryptUser переводит названия и описания списков, задач и шаблонов пользователя с ключа from на ключ to;
+// nil означает открытый текст
+func recryptUser(tx *sql.Tx, userID int, from, to []byte) error {
+	convert := func(s string) (string, error) {
+		if from != nil {
+			var err error
+			if s, err = openWith(from, s); err != nil {
+				return "", fmt.Errorf("не удалось расшифровать данные: %v", err)
+			}
+		}
+		if to == nil {
+			return s, nil
+		}
+		return sealWith(userID, to, s)
+	}
+
+	tables := []struct{ name, where string }{
+		{"todo_lists", "user_id = $1"},
+		{"tasks", "list_id IN (SELECT id FROM todo_lists WHERE user_id = $1)"},
+		{"list_templates", "user_id = $1"},
+		{"template_tasks", "template_id IN (SELECT id FROM list_templates WHERE user_id = $1)"},
+	}
+	for _, table := range tables {
+		type row struct {
+			id                 int
+			title, description string
+		}
+		rows, err := tx.Query("SELECT id, title, COALESCE(description, '') FROM "+table.name+" WHERE "+table.where, userID)
+		if err != nil {
+			return err
+		}
+		var items []row
+		for rows.Next() {
+			var r row
+			if err := rows.Scan(&r.id, &r.title, &r.description); err != nil {
+				rows.Close()
+				return err
+			}
+			items = append(items, r)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		for _, r := range items {
+			title, description, err := sealPair(convert, r.title, r.description)
+			if err != nil {
+				return err
+			}
+			if _, err := tx.Exec("UPDATE "+table.name+" SET title = $1, description = $2 WHERE id = $3", title, description, r.id); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
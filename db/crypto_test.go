@@ -0,0 +1,43 @@
+package db
+
+import (
+	"strings"
+	"testing"
+	"todolist/auth"
+)
+
+// Поле хранится как "enc:v1:<id владельца>:<шифротекст>" и открывается ключом владельца
+func TestSealedFieldFormat(t *testing.T) {
+	key, err := auth.NewDataKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sealed, err := sealWith(42, key, "Купить молоко")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(sealed, "enc:v1:42:") {
+		t.Fatalf("sealWith = %q, want prefix %q", sealed, "enc:v1:42:")
+	}
+	if got, err := openWith(key, sealed); err != nil || got != "Купить молоко" {
+		t.Errorf("openWith = %q, %v", got, err)
+	}
+	if got, err := openWith(key, "Открытый текст"); err != nil || got != "Открытый текст" {
+		t.Errorf("openWith(открытый текст) = %q, %v", got, err)
+	}
+	if _, err := openWith(key, "enc:v1:42"); err == nil {
+		t.Error("openWith принял поле без шифротекста")
+	}
+
+	t.Cleanup(LockUserKeys)
+	if got := openField(sealed); got != LockedText {
+		t.Errorf("openField без ключа = %q, want LockedText", got)
+	}
+	setUserKey(42, key)
+	if got := openField(sealed); got != "Купить молоко" {
+		t.Errorf("openField = %q", got)
+	}
+	if got := openField("enc:v1:7:" + sealed[len("enc:v1:42:"):]); got != LockedText {
+		t.Errorf("openField с чужим владельцем = %q, want LockedText", got)
+	}
+}
@@ -3,8 +3,11 @@ package db
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
 	"log"
+	"strings"
+	"time"
 	"todolist/models"
 
 	_ "github.com/lib/pq"
@@ -34,19 +37,24 @@ func Init() error {
 		return fmt.Errorf("ошибка проверки подключения: %v", err)
 	}
 
+	if err = migrate(); err != nil {
+		return err
+	}
+
 	log.Println("Подключение к БД успешно")
 	return nil
 }
 
 func Close() error {
 	if DB != nil {
+		DiscardUndo(0)
 		return DB.Close()
 	}
 	return nil
 }
 
 func GetAllUsers() ([]models.User, error) {
-	rows, err := DB.Query("SELECT id, tg_id FROM users")
+	rows, err := DB.Query("SELECT id, tg_id, password_hash IS NOT NULL FROM users")
 	if err != nil {
 		return nil, err
 	}
@@ -55,7 +63,7 @@ func GetAllUsers() ([]models.User, error) {
 	var users []models.User
 	for rows.Next() {
 		var user models.User
-		if err := rows.Scan(&user.ID, &user.TgID); err != nil {
+		if err := rows.Scan(&user.ID, &user.TgID, &user.Protected); err != nil {
 			return nil, err
 		}
 		users = append(users, user)
@@ -70,12 +78,31 @@ func CreateUser(user *models.User) error {
 	).Scan(&user.ID)
 }
 
+// ErrUserNotFound - пользователя нет в базе (например, он удалён)
+var ErrUserNotFound = errors.New("пользователь не найден")
+
+// GetUserPasswordHash возвращает хеш пароля пользователя; пустая строка - пароль не задан
+func GetUserPasswordHash(userID int) (string, error) {
+	var hash sql.NullString
+	err := DB.QueryRow("SELECT password_hash FROM users WHERE id = $1", userID).Scan(&hash)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", ErrUserNotFound
+	}
+	return hash.String, err
+}
+
 func DeleteUser(userID int) error {
 	tx, err := DB.Begin()
 	if err != nil {
 		return fmt.Errorf("ошибка начала транзакции: %v", err)
 	}
 
+	hashes, err := attachmentHashes(tx, "task_id IN (SELECT t.id FROM tasks t JOIN todo_lists l ON l.id = t.list_id WHERE l.user_id = $1)", userID)
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("ошибка поиска вложений: %v", err)
+	}
+
 	//Удаляет пользователя
 	if _, err := tx.Exec("DELETE FROM tasks WHERE list_id IN (SELECT id FROM todo_lists WHERE user_id = $1)", userID); err != nil {
 		tx.Rollback()
@@ -94,23 +121,61 @@ func DeleteUser(userID int) error {
 		return fmt.Errorf("ошибка удаления пользователя: %v", err)
 	}
 
+	orphans, err := removeOrphanBlobs(tx, hashes)
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("ошибка удаления вложений: %v", err)
+	}
+
 	if err := tx.Commit(); err != nil {
 		return fmt.Errorf("ошибка коммита транзакции: %v", err)
 	}
 
+	setUserKey(userID, nil)
+	DiscardUndo(userID)
+	removeBlobFiles(orphans)
 	return nil
 }
 
 func CreateTodoList(list *models.TodoList) error {
-	return DB.QueryRow(
-		"INSERT INTO todo_lists (user_id, title, description, created_at) VALUES ($1, $2, $3, $4) RETURNING id",
-		list.UserID, list.Title, list.Description, list.CreatedAt,
+	return createTodoList(DB, list)
+}
+
+func createTodoList(q querier, list *models.TodoList) error {
+	list.Role = models.RoleOwner
+	if list.FolderID != 0 {
+		if err := requireFolder(q, list.UserID, list.FolderID); err != nil {
+			return err
+		}
+	}
+	seal, err := userSealer(q, list.UserID)
+	if err != nil {
+		return err
+	}
+	title, description, err := sealPair(seal, list.Title, list.Description)
+	if err != nil {
+		return err
+	}
+	// Новый список встаёт первым, как и при сортировке по дате создания
+	return q.QueryRow(
+		"INSERT INTO todo_lists (user_id, title, description, created_at, manual_order, auto_archive, folder_id, position) VALUES ($1, $2, $3, $4, $5, $6, $7, (SELECT MIN(position) - 1 FROM ("+userListRanks+") r)) RETURNING id",
+		list.UserID, title, description, list.CreatedAt, list.ManualOrder, list.AutoArchive, nullInt(list.FolderID),
 	).Scan(&list.ID)
 }
 
+// GetTodoLists возвращает собственные списки пользователя и списки, открытые ему другими,
+// с ролью пользователя в каждом
 func GetTodoLists(userID int) ([]models.TodoList, error) {
 	rows, err := DB.Query(
-		"SELECT id, user_id, title, description, created_at FROM todo_lists WHERE user_id = $1 ORDER BY created_at DESC",
+		`SELECT l.id, l.user_id, l.title, l.description, l.created_at,
+			CASE WHEN l.user_id = $1 THEN 'owner' ELSE m.role END,
+			EXISTS (SELECT 1 FROM list_members x WHERE x.list_id = l.id), l.manual_order,
+			l.archived_at, l.auto_archive,
+			CASE WHEN l.user_id = $1 THEN l.folder_id ELSE m.folder_id END,
+			CASE WHEN l.user_id = $1 THEN l.pinned ELSE m.pinned END
+		FROM todo_lists l LEFT JOIN list_members m ON m.list_id = l.id AND m.user_id = $1
+		WHERE l.user_id = $1 OR m.user_id IS NOT NULL
+		ORDER BY CASE WHEN l.user_id = $1 THEN l.position ELSE m.position END NULLS LAST, l.created_at DESC`,
 		userID,
 	)
 	if err != nil {
@@ -120,57 +185,304 @@ func GetTodoLists(userID int) ([]models.TodoList, error) {
 
 	var lists []models.TodoList
 	for rows.Next() {
-		var list models.TodoList
-		if err := rows.Scan(&list.ID, &list.UserID, &list.Title, &list.Description, &list.CreatedAt); err != nil {
+		var (
+			list       models.TodoList
+			role       string
+			archivedAt sql.NullTime
+			folderID   sql.NullInt64
+		)
+		if err := rows.Scan(&list.ID, &list.UserID, &list.Title, &list.Description, &list.CreatedAt, &role, &list.Shared, &list.ManualOrder,
+			&archivedAt, &list.AutoArchive, &folderID, &list.Pinned); err != nil {
 			return nil, err
 		}
+		list.Role = models.Role(role)
+		list.ArchivedAt = archivedAt.Time
+		list.FolderID = int(folderID.Int64)
+		list.Title = openField(list.Title)
+		list.Description = openField(list.Description)
 		lists = append(lists, list)
 	}
 	return lists, rows.Err()
 }
 
-func DeleteTodoList(listID int) error {
-	_, err := DB.Exec("DELETE FROM todo_lists WHERE id = $1", listID)
-	return err
+// GetListsWithTasks возвращает все списки пользователя вместе с задачами
+func GetListsWithTasks(userID int) ([]models.ListWithTasks, error) {
+	lists, err := GetTodoLists(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]models.ListWithTasks, 0, len(lists))
+	for _, list := range lists {
+		tasks, err := GetTasksByList(list.ID)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, models.ListWithTasks{TodoList: list, Tasks: tasks})
+	}
+	return result, nil
 }
 
-func CreateTask(task *models.Task) error {
-	return DB.QueryRow(
-		"INSERT INTO tasks (list_id, title, description, due_date, is_done, created_at) VALUES ($1, $2, $3, $4, $5, $6) RETURNING id",
-		task.ListID, task.Title, task.Description, task.DueDate, task.IsDone, task.CreatedAt,
-	).Scan(&task.ID)
+// DeleteTodoList удаляет список; это может сделать только владелец
+func DeleteTodoList(userID, listID int) error {
+	var orphans []string
+	err := withTx(func(tx *sql.Tx) error {
+		if err := requireListRole(tx, userID, listID, models.RoleOwner); err != nil {
+			return err
+		}
+		hashes, err := attachmentHashes(tx, "task_id IN (SELECT id FROM tasks WHERE list_id = $1)", listID)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec("DELETE FROM todo_lists WHERE id = $1", listID); err != nil {
+			return err
+		}
+		orphans, err = removeOrphanBlobs(tx, hashes)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	removeBlobFiles(orphans)
+	return nil
 }
 
-func GetTasksByList(listID int) ([]models.Task, error) {
-	rows, err := DB.Query(
-		"SELECT id, list_id, title, description, due_date, is_done, created_at FROM tasks WHERE list_id = $1 ORDER BY due_date NULLS LAST, created_at DESC",
-		listID,
-	)
+// taskHasDue - условие "у задачи t задан срок" (см. models.Task.HasDue); NULL в старых строках
+// тоже означает "без срока"
+const taskHasDue = "t.due_date > '0001-01-01'"
+
+// Колонки задачи в порядке, который ожидает scanTask
+const taskColumns = "id, list_id, title, description, due_date, is_done, created_at, external_uid, priority, completed_at, parent_id, assignee_id, status_id"
+
+// prefixColumns добавляет псевдоним таблицы к списку колонок для запросов с JOIN
+func prefixColumns(alias, columns string) string {
+	parts := strings.Split(columns, ", ")
+	for i, p := range parts {
+		parts[i] = alias + "." + p
+	}
+	return strings.Join(parts, ", ")
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+// querier - общее у *sql.DB и *sql.Tx, чтобы одни и те же запросы работали и внутри транзакции
+type querier interface {
+	Exec(query string, args ...any) (sql.Result, error)
+	Query(query string, args ...any) (*sql.Rows, error)
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+func withTx(fn func(tx *sql.Tx) error) error {
+	tx, err := DB.Begin()
 	if err != nil {
-		return nil, err
+		return fmt.Errorf("ошибка начала транзакции: %v", err)
 	}
-	defer rows.Close()
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("ошибка коммита транзакции: %v", err)
+	}
+	return nil
+}
+
+func scanTask(s scanner, task *models.Task) error {
+	var (
+		externalUID sql.NullString
+		completedAt sql.NullTime
+		parentID    sql.NullInt64
+		assigneeID  sql.NullInt64
+		statusID    sql.NullInt64
+	)
+	if err := s.Scan(&task.ID, &task.ListID, &task.Title, &task.Description, &task.DueDate, &task.IsDone, &task.CreatedAt,
+		&externalUID, &task.Priority, &completedAt, &parentID, &assigneeID, &statusID); err != nil {
+		return err
+	}
+	task.Title = openField(task.Title)
+	task.Description = openField(task.Description)
+	task.ExternalUID = externalUID.String
+	task.CompletedAt = completedAt.Time
+	task.ParentID = int(parentID.Int64)
+	task.AssigneeID = int(assigneeID.Int64)
+	task.StatusID = int(statusID.Int64)
+	return nil
+}
 
+// scanTasks читает задачи из результата запроса вместе с их тегами и числом комментариев
+func scanTasks(q querier, rows *sql.Rows) ([]models.Task, error) {
 	var tasks []models.Task
 	for rows.Next() {
 		var task models.Task
-		if err := rows.Scan(&task.ID, &task.ListID, &task.Title, &task.Description, &task.DueDate, &task.IsDone, &task.CreatedAt); err != nil {
+		if err := scanTask(rows, &task); err != nil {
+			rows.Close()
 			return nil, err
 		}
 		tasks = append(tasks, task)
 	}
-	return tasks, rows.Err()
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := loadTags(q, tasks); err != nil {
+		return nil, err
+	}
+	if err := loadCommentCounts(q, tasks); err != nil {
+		return nil, err
+	}
+	return tasks, nil
+}
+
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
+func nullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t, Valid: !t.IsZero()}
+}
+
+func nullInt(i int) sql.NullInt64 {
+	return sql.NullInt64{Int64: int64(i), Valid: i != 0}
+}
+
+// markCompletion проставляет дату выполнения при отметке задачи и сбрасывает её при снятии отметки
+func markCompletion(task *models.Task) {
+	if !task.IsDone {
+		task.CompletedAt = time.Time{}
+	} else if task.CompletedAt.IsZero() {
+		task.CompletedAt = time.Now()
+	}
+}
+
+func insertTask(q querier, task *models.Task) error {
+	markCompletion(task)
+	if err := syncStatus(q, task); err != nil {
+		return err
+	}
+	seal, err := listSealer(q, task.ListID)
+	if err != nil {
+		return err
+	}
+	title, description, err := sealPair(seal, task.Title, task.Description)
+	if err != nil {
+		return err
+	}
+	if err := q.QueryRow(
+		"INSERT INTO tasks (list_id, title, description, due_date, is_done, created_at, external_uid, priority, completed_at, parent_id, assignee_id, status_id, position) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, "+nextTaskPosition+") RETURNING id",
+		task.ListID, title, description, task.DueDate, task.IsDone, task.CreatedAt, nullString(task.ExternalUID), task.Priority, nullTime(task.CompletedAt), nullInt(task.ParentID), nullInt(task.AssigneeID), nullInt(task.StatusID),
+	).Scan(&task.ID); err != nil {
+		return err
+	}
+	if err := saveTags(q, task.ID, task.Tags); err != nil {
+		return err
+	}
+	return autoArchive(q, task.ListID)
+}
+
+func updateTask(q querier, task *models.Task) error {
+	markCompletion(task)
+	if err := syncStatus(q, task); err != nil {
+		return err
+	}
+	seal, err := taskSealer(q, task.ID)
+	if err != nil {
+		return err
+	}
+	title, description, err := sealPair(seal, task.Title, task.Description)
+	if err != nil {
+		return err
+	}
+	if _, err := q.Exec(
+		"UPDATE tasks SET title = $1, description = $2, due_date = $3, is_done = $4, priority = $5, completed_at = $6, parent_id = $7, assignee_id = $8, status_id = $9 WHERE id = $10",
+		title, description, task.DueDate, task.IsDone, task.Priority, nullTime(task.CompletedAt), nullInt(task.ParentID), nullInt(task.AssigneeID), nullInt(task.StatusID), task.ID,
+	); err != nil {
+		return err
+	}
+	if err := saveTags(q, task.ID, task.Tags); err != nil {
+		return err
+	}
+	return autoArchive(q, task.ListID)
+}
+
+// CreateTask добавляет задачу от имени пользователя userID, которому нужна роль редактора
+func CreateTask(userID int, task *models.Task) error {
+	return withTx(func(tx *sql.Tx) error {
+		if err := requireListRole(tx, userID, task.ListID, models.RoleEditor); err != nil {
+			return err
+		}
+		if err := checkAssignee(tx, task); err != nil {
+			return err
+		}
+		if err := insertTask(tx, task); err != nil {
+			return err
+		}
+		return notifyAssigned(tx, userID, task, 0)
+	})
 }
 
-func UpdateTask(task *models.Task) error {
-	_, err := DB.Exec(
-		"UPDATE tasks SET title = $1, description = $2, due_date = $3, is_done = $4 WHERE id = $5",
-		task.Title, task.Description, task.DueDate, task.IsDone, task.ID,
+// GetTasksByList возвращает задачи списка в ручном порядке или, если он выключен, по сроку
+func GetTasksByList(listID int) ([]models.Task, error) {
+	rows, err := DB.Query(
+		"SELECT "+taskColumns+" FROM tasks WHERE list_id = $1 ORDER BY CASE WHEN (SELECT manual_order FROM todo_lists WHERE id = $1) THEN position END NULLS LAST, due_date NULLS LAST, created_at DESC",
+		listID,
 	)
-	return err
+	if err != nil {
+		return nil, err
+	}
+	return scanTasks(DB, rows)
+}
+
+func UpdateTask(userID int, task *models.Task) error {
+	return withTx(func(tx *sql.Tx) error {
+		if err := requireTaskRole(tx, userID, task.ID, models.RoleEditor); err != nil {
+			return err
+		}
+		if err := checkAssignee(tx, task); err != nil {
+			return err
+		}
+		var previous sql.NullInt64
+		if err := tx.QueryRow("SELECT assignee_id FROM tasks WHERE id = $1", task.ID).Scan(&previous); err != nil {
+			return err
+		}
+		if err := updateTask(tx, task); err != nil {
+			return err
+		}
+		return notifyAssigned(tx, userID, task, int(previous.Int64))
+	})
 }
 
-func DeleteTask(taskID int) error {
-	_, err := DB.Exec("DELETE FROM tasks WHERE id = $1", taskID)
-	return err
+// DeleteTask удаляет задачу с подзадачами, а также ставшее ненужным содержимое их вложений
+func DeleteTask(userID, taskID int) error {
+	var orphans []string
+	err := withTx(func(tx *sql.Tx) error {
+		if err := requireTaskRole(tx, userID, taskID, models.RoleEditor); err != nil {
+			return err
+		}
+		hashes, err := attachmentHashes(tx, `task_id IN (
+			WITH RECURSIVE sub AS (
+				SELECT id FROM tasks WHERE id = $1
+				UNION ALL
+				SELECT t.id FROM tasks t JOIN sub ON t.parent_id = sub.id
+			) SELECT id FROM sub)`, taskID)
+		if err != nil {
+			return err
+		}
+		var listID int
+		if err := tx.QueryRow("DELETE FROM tasks WHERE id = $1 RETURNING list_id", taskID).Scan(&listID); err != nil {
+			return err
+		}
+		if err := autoArchive(tx, listID); err != nil {
+			return err
+		}
+		orphans, err = removeOrphanBlobs(tx, hashes)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	removeBlobFiles(orphans)
+	return nil
 }
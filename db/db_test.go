@@ -0,0 +1,109 @@
+package db
+
+// Тесты пакета работают с настоящим PostgreSQL. Базу для них задаёт переменная TODO_TEST_DB -
+// строка подключения lib/pq в формате key=value, например:
+//
+//	docker run -d --name todo-test -e POSTGRES_PASSWORD=postgres -p 5433:5432 postgres:16
+//	TODO_TEST_DB="host=localhost port=5433 user=postgres password=postgres dbname=postgres sslmode=disable" \
+//		go test -tags ci ./db
+//
+// Каждый тест пересоздаёт в этой базе схему todo_test. Без TODO_TEST_DB тесты пакета пропускаются.
+
+import (
+	"database/sql"
+	"os"
+	"testing"
+	"time"
+	"todolist/models"
+)
+
+// Исходные таблицы, поверх которых работают миграции из schema.go
+var baseSchema = []string{
+	`CREATE TABLE users (
+		id SERIAL PRIMARY KEY,
+		tg_id BIGINT
+	)`,
+	`CREATE TABLE todo_lists (
+		id SERIAL PRIMARY KEY,
+		user_id INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+		title VARCHAR(255) NOT NULL,
+		description TEXT,
+		created_at TIMESTAMP NOT NULL DEFAULT NOW()
+	)`,
+	`CREATE TABLE tasks (
+		id SERIAL PRIMARY KEY,
+		list_id INTEGER NOT NULL REFERENCES todo_lists (id) ON DELETE CASCADE,
+		title VARCHAR(255) NOT NULL,
+		description TEXT,
+		due_date TIMESTAMP,
+		is_done BOOLEAN NOT NULL DEFAULT FALSE,
+		created_at TIMESTAMP NOT NULL DEFAULT NOW()
+	)`,
+}
+
+// openTestDB подключается к базе из TODO_TEST_DB (строка подключения вида "host=... dbname=...")
+// и создаёт в ней заново схему todo_test. Без TODO_TEST_DB тест пропускается.
+func openTestDB(t *testing.T) {
+	t.Helper()
+	connStr := os.Getenv("TODO_TEST_DB")
+	if connStr == "" {
+		t.Skip("TODO_TEST_DB не задана")
+	}
+
+	admin, err := sql.Open("postgres", connStr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer admin.Close()
+	for _, stmt := range []string{"DROP SCHEMA IF EXISTS todo_test CASCADE", "CREATE SCHEMA todo_test"} {
+		if _, err := admin.Exec(stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	DB, err = sql.Open("postgres", connStr+" search_path=todo_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		DB.Close()
+		DB = nil
+	})
+	for _, stmt := range baseSchema {
+		if _, err := DB.Exec(stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := migrate(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func mustCreateUser(t *testing.T) int {
+	t.Helper()
+	user := models.User{TgID: time.Now().UnixNano()}
+	if err := CreateUser(&user); err != nil {
+		t.Fatal(err)
+	}
+	return user.ID
+}
+
+func mustCreateList(t *testing.T, userID int, title string) models.TodoList {
+	t.Helper()
+	list := models.TodoList{UserID: userID, Title: title, CreatedAt: time.Now()}
+	if err := CreateTodoList(&list); err != nil {
+		t.Fatal(err)
+	}
+	return list
+}
+
+func mustCreateTask(t *testing.T, userID int, task models.Task) models.Task {
+	t.Helper()
+	if task.CreatedAt.IsZero() {
+		task.CreatedAt = time.Now()
+	}
+	if err := CreateTask(userID, &task); err != nil {
+		t.Fatal(err)
+	}
+	return task
+}
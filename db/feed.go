@@ -0,0 +1,83 @@
+// feed.go
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+	"todolist/models"
+)
+
+func CreateFeedToken(feed *models.FeedToken) error {
+	var listID sql.NullInt64
+	if feed.ListID != 0 {
+		listID = sql.NullInt64{Int64: int64(feed.ListID), Valid: true}
+	}
+	_, err := DB.Exec(
+		"INSERT INTO feed_tokens (token, user_id, list_id, created_at) VALUES ($1, $2, $3, $4)",
+		feed.Token, feed.UserID, listID, feed.CreatedAt,
+	)
+	return err
+}
+
+// GetFeedToken возвращает действующую подписку по секрету или nil, если она не найдена или отозвана
+func GetFeedToken(token string) (*models.FeedToken, error) {
+	var (
+		feed   models.FeedToken
+		listID sql.NullInt64
+	)
+	err := DB.QueryRow(
+		"SELECT token, user_id, list_id, created_at FROM feed_tokens WHERE token = $1 AND revoked_at IS NULL",
+		token,
+	).Scan(&feed.Token, &feed.UserID, &listID, &feed.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	feed.ListID = int(listID.Int64)
+	return &feed, nil
+}
+
+// GetFeedTokens возвращает действующие подписки пользователя; listID = 0 - подписки на все списки
+func GetFeedTokens(userID, listID int) ([]models.FeedToken, error) {
+	rows, err := DB.Query(
+		"SELECT token, user_id, COALESCE(list_id, 0), created_at FROM feed_tokens WHERE user_id = $1 AND COALESCE(list_id, 0) = $2 AND revoked_at IS NULL ORDER BY created_at",
+		userID, listID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var feeds []models.FeedToken
+	for rows.Next() {
+		var feed models.FeedToken
+		if err := rows.Scan(&feed.Token, &feed.UserID, &feed.ListID, &feed.CreatedAt); err != nil {
+			return nil, err
+		}
+		feeds = append(feeds, feed)
+	}
+	return feeds, rows.Err()
+}
+
+// RevokeFeedToken отзывает подписку; отозвать можно только свою
+func RevokeFeedToken(userID int, token string) error {
+	res, err := DB.Exec("UPDATE feed_tokens SET revoked_at = $1 WHERE token = $2 AND user_id = $3", time.Now(), token, userID)
+	return requireAffected(res, err)
+}
+
+// TouchFeedVersion запоминает ETag отданного по подписке календаря и возвращает момент,
+// когда его содержимое изменилось в последний раз. Хранится в базе, чтобы условные
+// запросы календарей работали и после перезапуска сервера.
+func TouchFeedVersion(token, etag string) (time.Time, error) {
+	// Заголовок Last-Modified имеет точность до секунды
+	var modified time.Time
+	err := DB.QueryRow(
+		`UPDATE feed_tokens SET modified_at = CASE WHEN etag IS DISTINCT FROM $2 THEN $3 ELSE modified_at END, etag = $2
+		WHERE token = $1 RETURNING modified_at`,
+		token, etag, time.Now().Truncate(time.Second),
+	).Scan(&modified)
+	return modified, err
+}
@@ -0,0 +1,200 @@
+// filter.go
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"todolist/filter"
+	"todolist/models"
+)
+
+// filterSQL переводит выражение фильтра в условие SQL над задачей t и её списком l,
+// добавляя значения в args. Условия по тексту зашифрованных полей база проверить
+// не может и получает для них NULL: такие строки отбираются с запасом и
+// окончательно проверяются в приложении после расшифровки (см. FilterTasks).
+func filterSQL(e filter.Expr, args *[]any) (string, error) {
+	arg := func(v any) string {
+		*args = append(*args, v)
+		return "$" + strconv.Itoa(len(*args))
+	}
+
+	switch e := e.(type) {
+	case filter.And:
+		return binarySQL(e.Left, "AND", e.Right, args)
+	case filter.Or:
+		return binarySQL(e.Left, "OR", e.Right, args)
+	case filter.Not:
+		x, err := filterSQL(e.X, args)
+		if err != nil {
+			return "", err
+		}
+		return "(NOT " + x + ")", nil
+	case filter.Done:
+		return "t.is_done", nil
+	case filter.Archived:
+		return "(l.archived_at IS NOT NULL)", nil
+	case filter.DueNone:
+		return "((" + taskHasDue + ") IS NOT TRUE)", nil
+	case filter.DueRange:
+		conds := []string{taskHasDue}
+		if !e.From.IsZero() {
+			conds = append(conds, "t.due_date >= "+arg(e.From))
+		}
+		if !e.To.IsZero() {
+			conds = append(conds, "t.due_date < "+arg(e.To))
+		}
+		return "(" + strings.Join(conds, " AND ") + ")", nil
+	case filter.List:
+		return sealedGuard("l.title", "lower(l.title) = lower("+arg(e.Name)+")", arg), nil
+	case filter.Tag:
+		return "EXISTS (SELECT 1 FROM task_tags WHERE task_id = t.id AND lower(tag) = lower(" + arg(e.Name) + "))", nil
+	case filter.Priority:
+		return "(t.priority BETWEEN " + arg(e.Min) + " AND " + arg(e.Max) + ")", nil
+	case filter.Text:
+		pattern := arg("%" + escapeLike(e.Value) + "%")
+		return sealedGuard("t.title", "(t.title ILIKE "+pattern+" OR COALESCE(t.description, '') ILIKE "+pattern+")", arg), nil
+	}
+	return "", fmt.Errorf("неподдерживаемое условие фильтра %T", e)
+}
+
+func binarySQL(left filter.Expr, op string, right filter.Expr, args *[]any) (string, error) {
+	l, err := filterSQL(left, args)
+	if err != nil {
+		return "", err
+	}
+	r, err := filterSQL(right, args)
+	if err != nil {
+		return "", err
+	}
+	return "(" + l + " " + op + " " + r + ")", nil
+}
+
+// sealedGuard возвращает NULL вместо cond для строк, где column зашифрована
+func sealedGuard(column, cond string, arg func(any) string) string {
+	return "(CASE WHEN " + column + " LIKE " + arg(sealedPrefix+"%") + " THEN NULL ELSE " + cond + " END)"
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// FilterTasks возвращает задачи из всех доступных пользователю списков, подходящие под фильтр.
+// Архивные списки просматриваются, только если фильтр упоминает is:archived.
+func FilterTasks(userID int, expr filter.Expr) ([]models.Task, error) {
+	args := []any{userID}
+	cond, err := filterSQL(expr, &args)
+	if err != nil {
+		return nil, err
+	}
+	if !filter.MentionsArchived(expr) {
+		cond = "(" + cond + " AND " + activeTasks + ")"
+	}
+
+	rows, err := DB.Query(
+		"SELECT "+prefixColumns("t", taskColumns)+` FROM tasks t JOIN todo_lists l ON l.id = t.list_id
+		WHERE `+accessibleTasks+` AND `+cond+` IS NOT FALSE
+		ORDER BY t.due_date NULLS LAST, t.created_at DESC`,
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	tasks, err := scanTasks(DB, rows)
+	if err != nil || len(tasks) == 0 {
+		return nil, err
+	}
+
+	lists, err := GetTodoLists(userID)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[int]*models.TodoList, len(lists))
+	for i := range lists {
+		byID[lists[i].ID] = &lists[i]
+	}
+
+	// Повторная проверка уже расшифрованных задач; для остальных она совпадает с SQL
+	matched := tasks[:0]
+	for i := range tasks {
+		if list := byID[tasks[i].ListID]; list != nil && expr.Match(&tasks[i], list) {
+			matched = append(matched, tasks[i])
+		}
+	}
+	return matched, nil
+}
+
+// checkSmartList проверяет название и синтаксис фильтра перед сохранением
+func checkSmartList(list *models.SmartList) error {
+	list.Title = strings.TrimSpace(list.Title)
+	if list.Title == "" {
+		return fmt.Errorf("название умного списка не может быть пустым")
+	}
+	if _, err := filter.Parse(list.Query, time.Now()); err != nil {
+		return fmt.Errorf("ошибка в фильтре: %v", err)
+	}
+	return nil
+}
+
+func CreateSmartList(list *models.SmartList) error {
+	if err := checkSmartList(list); err != nil {
+		return err
+	}
+	if list.CreatedAt.IsZero() {
+		list.CreatedAt = time.Now()
+	}
+	return DB.QueryRow(
+		"INSERT INTO smart_lists (user_id, title, query, created_at) VALUES ($1, $2, $3, $4) RETURNING id",
+		list.UserID, list.Title, list.Query, list.CreatedAt,
+	).Scan(&list.ID)
+}
+
+func GetSmartLists(userID int) ([]models.SmartList, error) {
+	rows, err := DB.Query("SELECT id, user_id, title, query, created_at FROM smart_lists WHERE user_id = $1 ORDER BY title", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var lists []models.SmartList
+	for rows.Next() {
+		var list models.SmartList
+		if err := rows.Scan(&list.ID, &list.UserID, &list.Title, &list.Query, &list.CreatedAt); err != nil {
+			return nil, err
+		}
+		lists = append(lists, list)
+	}
+	return lists, rows.Err()
+}
+
+// UpdateSmartList меняет название и фильтр; умные списки личные, менять их может только автор
+func UpdateSmartList(list *models.SmartList) error {
+	if err := checkSmartList(list); err != nil {
+		return err
+	}
+	res, err := DB.Exec("UPDATE smart_lists SET title = $1, query = $2 WHERE id = $3 AND user_id = $4",
+		list.Title, list.Query, list.ID, list.UserID)
+	return requireAffected(res, err)
+}
+
+func DeleteSmartList(userID, id int) error {
+	res, err := DB.Exec("DELETE FROM smart_lists WHERE id = $1 AND user_id = $2", id, userID)
+	return requireAffected(res, err)
+}
+
+// requireAffected превращает изменение ноля строк в ErrAccessDenied
+func requireAffected(res sql.Result, err error) error {
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrAccessDenied
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package db
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+	"todolist/filter"
+	"todolist/models"
+)
+
+// Условие SQL из filterSQL должно отбирать те же задачи, что и Match
+func TestFilterSQLAgreesWithMatch(t *testing.T) {
+	openTestDB(t)
+	userID := mustCreateUser(t)
+	list := mustCreateList(t, userID, "Работа")
+
+	now := time.Date(2026, 10, 14, 15, 0, 0, 0, time.UTC)
+	due := func(month time.Month, day, hour int) time.Time {
+		return time.Date(2026, month, day, hour, 0, 0, 0, time.UTC)
+	}
+	for _, task := range []models.Task{
+		{Title: "Купить молоко"},
+		{Title: "Сдать отчёт", DueDate: due(10, 10, 0), Priority: 1, Tags: []string{"urgent"}},
+		{Title: "Позвонить врачу", DueDate: due(10, 14, 18), Priority: 2},
+		{Title: "Оплатить счёт", DueDate: due(10, 15, 0), IsDone: true},
+		{Title: "Отпуск", DueDate: due(11, 3, 0)},
+	} {
+		task.ListID = list.ID
+		mustCreateTask(t, userID, task)
+	}
+	tasks, err := GetTasksByList(list.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, query := range []string{
+		"due:none",
+		"due:any",
+		"-due<today",
+		"due<=tomorrow",
+		"due:week",
+		"is:overdue",
+		"due:none -is:done",
+		"not (is:done or due:none)",
+		"#urgent or pri:B",
+		"молоко or -list:работа",
+	} {
+		expr, err := filter.Parse(query, now)
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", query, err)
+		}
+		var want []int
+		for i := range tasks {
+			if expr.Match(&tasks[i], &list) {
+				want = append(want, tasks[i].ID)
+			}
+		}
+
+		args := []any{list.ID}
+		cond, err := filterSQL(expr, &args)
+		if err != nil {
+			t.Fatalf("filterSQL(%q): %v", query, err)
+		}
+		rows, err := DB.Query("SELECT t.id FROM tasks t JOIN todo_lists l ON l.id = t.list_id WHERE t.list_id = $1 AND "+cond+" ORDER BY t.id", args...)
+		if err != nil {
+			t.Fatalf("%q: %v", query, err)
+		}
+		var got []int
+		for rows.Next() {
+			var id int
+			if err := rows.Scan(&id); err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, id)
+		}
+		rows.Close()
+
+		sort.Ints(want)
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%q: SQL отобрал задачи %v, Match - %v", query, got, want)
+		}
+	}
+}
+
+// Фильтр встроенного представления "Без срока" (gui/views.go) по задачам,
+// созданным обычным путём: без срока они хранятся с нулевой датой
+func TestFilterTasksNoDue(t *testing.T) {
+	openTestDB(t)
+	userID := mustCreateUser(t)
+	list := mustCreateList(t, userID, "Дом")
+	undated := mustCreateTask(t, userID, models.Task{ListID: list.ID, Title: "Разобрать шкаф"})
+	mustCreateTask(t, userID, models.Task{ListID: list.ID, Title: "Полить цветы", IsDone: true})
+	mustCreateTask(t, userID, models.Task{ListID: list.ID, Title: "Вынести мусор", DueDate: time.Now().AddDate(0, 0, 1)})
+
+	expr, err := filter.Parse("due:none -is:done", time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	tasks, err := FilterTasks(userID, expr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tasks) != 1 || tasks[0].ID != undated.ID {
+		t.Errorf("FilterTasks(due:none -is:done) = %+v, want только %q", tasks, undated.Title)
+	}
+}
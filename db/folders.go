@@ -0,0 +1,217 @@
+// folders.go
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"todolist/models"
+)
+
+// Папки личные: каждый пользователь раскладывает по ним и свои, и открытые ему списки.
+
+// checkFolder проверяет название и то, что родительская папка принадлежит тому же
+// пользователю и не лежит внутри самой папки
+func checkFolder(q querier, folder *models.Folder) error {
+	folder.Title = strings.TrimSpace(folder.Title)
+	if folder.Title == "" {
+		return fmt.Errorf("название папки не может быть пустым")
+	}
+	if folder.ParentID == 0 {
+		return nil
+	}
+	var cycle bool
+	err := q.QueryRow(
+		`WITH RECURSIVE up AS (
+			SELECT id, parent_id FROM list_folders WHERE id = $1 AND user_id = $2
+			UNION SELECT f.id, f.parent_id FROM list_folders f JOIN up ON f.id = up.parent_id
+		) SELECT COALESCE(bool_or(id = $3), FALSE) FROM up`,
+		folder.ParentID, folder.UserID, folder.ID,
+	).Scan(&cycle)
+	if err != nil {
+		return err
+	}
+	if cycle {
+		return fmt.Errorf("папку нельзя вложить в саму себя")
+	}
+	return nil
+}
+
+// requireFolder проверяет, что папка принадлежит пользователю
+func requireFolder(q querier, userID, folderID int) error {
+	var exists bool
+	if err := q.QueryRow(
+		"SELECT EXISTS (SELECT 1 FROM list_folders WHERE id = $1 AND user_id = $2)", folderID, userID,
+	).Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		return ErrAccessDenied
+	}
+	return nil
+}
+
+func GetFolders(userID int) ([]models.Folder, error) {
+	rows, err := DB.Query(
+		"SELECT id, user_id, parent_id, title, collapsed, created_at FROM list_folders WHERE user_id = $1 ORDER BY lower(title), id",
+		userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var folders []models.Folder
+	for rows.Next() {
+		var (
+			folder   models.Folder
+			parentID sql.NullInt64
+		)
+		if err := rows.Scan(&folder.ID, &folder.UserID, &parentID, &folder.Title, &folder.Collapsed, &folder.CreatedAt); err != nil {
+			return nil, err
+		}
+		folder.ParentID = int(parentID.Int64)
+		folders = append(folders, folder)
+	}
+	return folders, rows.Err()
+}
+
+func CreateFolder(folder *models.Folder) error {
+	return withTx(func(tx *sql.Tx) error {
+		if folder.ParentID != 0 {
+			if err := requireFolder(tx, folder.UserID, folder.ParentID); err != nil {
+				return err
+			}
+		}
+		if err := checkFolder(tx, folder); err != nil {
+			return err
+		}
+		if folder.CreatedAt.IsZero() {
+			folder.CreatedAt = time.Now()
+		}
+		return tx.QueryRow(
+			"INSERT INTO list_folders (user_id, parent_id, title, collapsed, created_at) VALUES ($1, $2, $3, $4, $5) RETURNING id",
+			folder.UserID, nullInt(folder.ParentID), folder.Title, folder.Collapsed, folder.CreatedAt,
+		).Scan(&folder.ID)
+	})
+}
+
+// UpdateFolder переименовывает папку и переносит её в другую родительскую папку
+func UpdateFolder(folder *models.Folder) error {
+	return withTx(func(tx *sql.Tx) error {
+		if err := requireFolder(tx, folder.UserID, folder.ID); err != nil {
+			return err
+		}
+		if folder.ParentID != 0 {
+			if err := requireFolder(tx, folder.UserID, folder.ParentID); err != nil {
+				return err
+			}
+		}
+		if err := checkFolder(tx, folder); err != nil {
+			return err
+		}
+		_, err := tx.Exec(
+			"UPDATE list_folders SET title = $1, parent_id = $2 WHERE id = $3",
+			folder.Title, nullInt(folder.ParentID), folder.ID,
+		)
+		return err
+	})
+}
+
+// SetFolderCollapsed сворачивает папку на экране списков или разворачивает её
+func SetFolderCollapsed(userID, folderID int, collapsed bool) error {
+	res, err := DB.Exec("UPDATE list_folders SET collapsed = $1 WHERE id = $2 AND user_id = $3", collapsed, folderID, userID)
+	return requireAffected(res, err)
+}
+
+// DeleteFolder удаляет папку; её списки и вложенные папки переходят в родительскую
+func DeleteFolder(userID, folderID int) error {
+	return withTx(func(tx *sql.Tx) error {
+		var parentID sql.NullInt64
+		err := tx.QueryRow("SELECT parent_id FROM list_folders WHERE id = $1 AND user_id = $2", folderID, userID).Scan(&parentID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrAccessDenied
+		}
+		if err != nil {
+			return err
+		}
+		for _, stmt := range []string{
+			"UPDATE list_folders SET parent_id = $1 WHERE parent_id = $2",
+			"UPDATE todo_lists SET folder_id = $1 WHERE folder_id = $2",
+			"UPDATE list_members SET folder_id = $1 WHERE folder_id = $2",
+		} {
+			if _, err := tx.Exec(stmt, parentID, folderID); err != nil {
+				return err
+			}
+		}
+		_, err = tx.Exec("DELETE FROM list_folders WHERE id = $1", folderID)
+		return err
+	})
+}
+
+// setListPlacement меняет колонку column (папку или закрепление) у списка в раскладке
+// пользователя: у своего списка - в todo_lists, у открытого ему - в list_members
+func setListPlacement(q querier, userID, listID int, column string, value any) error {
+	if err := requireListRole(q, userID, listID, models.RoleViewer); err != nil {
+		return err
+	}
+	res, err := q.Exec("UPDATE todo_lists SET "+column+" = $1 WHERE id = $2 AND user_id = $3", value, listID, userID)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil || n > 0 {
+		return err
+	}
+	_, err = q.Exec("UPDATE list_members SET "+column+" = $1 WHERE list_id = $2 AND user_id = $3", value, listID, userID)
+	return err
+}
+
+// SetListFolder кладёт список в папку пользователя; folderID = 0 - убирает из папок
+func SetListFolder(userID, listID, folderID int) error {
+	return withTx(func(tx *sql.Tx) error {
+		if folderID != 0 {
+			if err := requireFolder(tx, userID, folderID); err != nil {
+				return err
+			}
+		}
+		return setListPlacement(tx, userID, listID, "folder_id", nullInt(folderID))
+	})
+}
+
+// SetListPinned закрепляет список наверху экрана списков или открепляет его
+func SetListPinned(userID, listID int, pinned bool) error {
+	return withTx(func(tx *sql.Tx) error {
+		return setListPlacement(tx, userID, listID, "pinned", pinned)
+	})
+}
+
+// GetListCounts возвращает по ID списка число всех, открытых и просроченных задач во всех
+// доступных пользователю списках одним запросом; пустых списков в ответе нет
+func GetListCounts(userID int) (map[int]models.ListCounts, error) {
+	rows, err := DB.Query(
+		`SELECT t.list_id, COUNT(*), COUNT(*) FILTER (WHERE NOT t.is_done),
+			COUNT(*) FILTER (WHERE NOT t.is_done AND `+taskHasDue+` AND t.due_date < $2)
+		FROM tasks t WHERE `+accessibleTasks+`
+		GROUP BY t.list_id`,
+		userID, time.Now(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := make(map[int]models.ListCounts)
+	for rows.Next() {
+		var (
+			listID int
+			c      models.ListCounts
+		)
+		if err := rows.Scan(&listID, &c.Total, &c.Open, &c.Overdue); err != nil {
+			return nil, err
+		}
+		counts[listID] = c
+	}
+	return counts, rows.Err()
+}
@@ -0,0 +1,111 @@
+// import.go
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"todolist/models"
+)
+
+// ImportTasks добавляет задачи в список или обновляет уже импортированные ранее.
+// Задача считается существующей, если в списке уже есть задача с тем же ID
+// либо с тем же внешним идентификатором, поэтому повторный импорт одного
+// и того же файла не создаёт дубликатов. Подзадачи (Subtasks) импортируются вместе с родителем.
+// Пользователю userID нужна роль редактора в списке.
+func ImportTasks(userID, listID int, tasks []models.Task) (created, updated int, err error) {
+	err = withTx(func(tx *sql.Tx) error {
+		if err := requireListRole(tx, userID, listID, models.RoleEditor); err != nil {
+			return err
+		}
+		for i := range tasks {
+			if err := importTask(tx, listID, 0, &tasks[i], &created, &updated); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+	return created, updated, nil
+}
+
+func importTask(tx *sql.Tx, listID, parentID int, task *models.Task, created, updated *int) error {
+	task.ListID = listID
+	if parentID != 0 {
+		task.ParentID = parentID
+	}
+
+	existing, err := findImportedTask(tx, listID, task)
+	if err != nil {
+		return err
+	}
+
+	if existing != nil {
+		// Поля, которых нет в исходном формате, не затираем
+		task.ID = existing.ID
+		if task.Tags == nil {
+			task.Tags = existing.Tags
+		}
+		if task.Priority == 0 {
+			task.Priority = existing.Priority
+		}
+		if task.ParentID == 0 {
+			task.ParentID = existing.ParentID
+		}
+		if task.AssigneeID == 0 {
+			task.AssigneeID = existing.AssigneeID
+		}
+		if task.StatusID == 0 {
+			task.StatusID = existing.StatusID
+		}
+		if err := updateTask(tx, task); err != nil {
+			return fmt.Errorf("ошибка обновления задачи %q: %v", task.Title, err)
+		}
+		*updated++
+	} else {
+		if err := insertTask(tx, task); err != nil {
+			return fmt.Errorf("ошибка создания задачи %q: %v", task.Title, err)
+		}
+		*created++
+	}
+
+	for i := range task.Subtasks {
+		if err := importTask(tx, listID, task.ID, &task.Subtasks[i], created, updated); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func findImportedTask(tx *sql.Tx, listID int, task *models.Task) (*models.Task, error) {
+	if task.ID != 0 {
+		existing, err := queryTask(tx, "SELECT "+taskColumns+" FROM tasks WHERE id = $1 AND list_id = $2", task.ID, listID)
+		if existing != nil || err != nil {
+			return existing, err
+		}
+	}
+	if task.ExternalUID != "" {
+		return queryTask(tx, "SELECT "+taskColumns+" FROM tasks WHERE list_id = $1 AND external_uid = $2 LIMIT 1", listID, task.ExternalUID)
+	}
+	return nil, nil
+}
+
+// queryTask возвращает одну задачу с тегами или nil, если она не найдена
+func queryTask(q querier, query string, args ...any) (*models.Task, error) {
+	var task models.Task
+	err := scanTask(q.QueryRow(query, args...), &task)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	tasks := []models.Task{task}
+	if err := loadTags(q, tasks); err != nil {
+		return nil, err
+	}
+	return &tasks[0], nil
+}
@@ -0,0 +1,180 @@
+// move.go
+package db
+
+import (
+	"database/sql"
+	"time"
+	"todolist/models"
+
+	"github.com/lib/pq"
+)
+
+// loadSubtrees возвращает задачи ids вместе со всеми подзадачами, вложенными по ParentID.
+// Выбранная подзадача, чей родитель не выбран, становится корнем.
+func loadSubtrees(q querier, ids []int) ([]models.Task, error) {
+	rows, err := q.Query(
+		`WITH RECURSIVE sub AS (
+			SELECT id FROM tasks WHERE id = ANY($1)
+			UNION SELECT t.id FROM tasks t JOIN sub ON t.parent_id = sub.id
+		)
+		SELECT `+taskColumns+` FROM tasks WHERE id IN (SELECT id FROM sub)
+		ORDER BY position NULLS LAST, due_date NULLS LAST, created_at DESC`,
+		pq.Array(ids),
+	)
+	if err != nil {
+		return nil, err
+	}
+	tasks, err := scanTasks(q, rows)
+	if err != nil {
+		return nil, err
+	}
+	return models.NestTasks(tasks), nil
+}
+
+// prepareTransfer проверяет права на перенос или копирование задач в список listID
+// и загружает их поддеревья. Для переноса нужна роль редактора в исходных списках,
+// для копирования достаточно читателя. Задачи списка с закрытым ключом владельца
+// не переносятся: их текст нельзя перешифровать.
+func prepareTransfer(tx *sql.Tx, userID int, taskIDs []int, listID int, sourceRole models.Role) ([]models.Task, error) {
+	if err := requireListRole(tx, userID, listID, models.RoleEditor); err != nil {
+		return nil, err
+	}
+	roots, err := loadSubtrees(tx, taskIDs)
+	if err != nil {
+		return nil, err
+	}
+	checked := map[int]bool{listID: true}
+	for _, task := range roots {
+		if checked[task.ListID] {
+			continue
+		}
+		checked[task.ListID] = true
+		if err := requireListRole(tx, userID, task.ListID, sourceRole); err != nil {
+			return nil, err
+		}
+		if _, err := listSealer(tx, task.ListID); err != nil {
+			return nil, err
+		}
+	}
+	return roots, nil
+}
+
+// dropInaccessibleAssignee снимает исполнителя, у которого нет доступа к новому списку задачи
+func dropInaccessibleAssignee(q querier, task *models.Task) error {
+	if task.AssigneeID == 0 {
+		return nil
+	}
+	role, err := listRole(q, task.AssigneeID, task.ListID)
+	if err != nil {
+		return err
+	}
+	if role == "" {
+		task.AssigneeID = 0
+	}
+	return nil
+}
+
+// MoveTasks переносит задачи с подзадачами, тегами, комментариями и вложениями в список listID.
+// Текст перешифровывается ключом нового владельца, статус выбирается среди колонок
+// нового списка, задачи встают в его конец. Перенос можно отменить (см. UndoLast).
+func MoveTasks(userID int, taskIDs []int, listID int) error {
+	return withUndo(userID, "Перенос", taskIDs, func(tx *sql.Tx, e *undoEntry) error {
+		roots, err := prepareTransfer(tx, userID, taskIDs, listID, models.RoleEditor)
+		if err != nil {
+			return err
+		}
+		// Открытые задачи вернут список из архива, отмена должна убрать его обратно
+		if err := e.saveArchived(tx, []int64{int64(listID)}); err != nil {
+			return err
+		}
+
+		var move func(tasks []models.Task, parentID int) error
+		move = func(tasks []models.Task, parentID int) error {
+			for i := range tasks {
+				task := &tasks[i]
+				if _, err := tx.Exec("UPDATE tasks SET list_id = $1, position = "+nextTaskPosition+" WHERE id = $2", listID, task.ID); err != nil {
+					return err
+				}
+				task.ListID = listID
+				task.ParentID = parentID
+				if err := dropInaccessibleAssignee(tx, task); err != nil {
+					return err
+				}
+				if err := updateTask(tx, task); err != nil {
+					return err
+				}
+				if err := move(task.Subtasks, task.ID); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+
+		// Задачи, которые уже лежат в этом списке, не трогаем
+		var moving []models.Task
+		for _, task := range roots {
+			if task.ListID != listID {
+				moving = append(moving, task)
+			}
+		}
+		return move(moving, 0)
+	})
+}
+
+// CopyTasks копирует задачи с подзадачами, тегами и вложениями в список listID.
+// Комментарии остаются у исходных задач: это обсуждение конкретной задачи, а не её содержимое.
+func CopyTasks(userID int, taskIDs []int, listID int) error {
+	return withTx(func(tx *sql.Tx) error {
+		roots, err := prepareTransfer(tx, userID, taskIDs, listID, models.RoleViewer)
+		if err != nil {
+			return err
+		}
+		_, err = copyTaskTrees(tx, roots, listID, nil, false)
+		return err
+	})
+}
+
+// copyTaskTrees вставляет копии деревьев задач в список listID и возвращает ID копий по ID
+// исходных задач. Колонки доски переводятся по statuses (без перевода статус выбирается
+// заново), при reopen копии снова открываются; иначе выполненные копии сохраняют дату выполнения.
+func copyTaskTrees(tx *sql.Tx, roots []models.Task, listID int, statuses map[int]int, reopen bool) (map[int]int, error) {
+	now := time.Now()
+	copies := make(map[int]int)
+	var copyTasks func(tasks []models.Task, parentID int) error
+	copyTasks = func(tasks []models.Task, parentID int) error {
+		for _, task := range tasks {
+			sourceID := task.ID
+			task.ID = 0
+			task.ListID = listID
+			task.ParentID = parentID
+			task.StatusID = statuses[task.StatusID]
+			task.ExternalUID = ""
+			task.CreatedAt = now
+			if reopen {
+				task.IsDone = false
+				task.StatusID = 0
+				task.CompletedAt = time.Time{}
+			}
+			if err := dropInaccessibleAssignee(tx, &task); err != nil {
+				return err
+			}
+			if err := insertTask(tx, &task); err != nil {
+				return err
+			}
+			copies[sourceID] = task.ID
+			// Содержимое вложений хранится по хешу, поэтому копия ссылается на те же блобы
+			if _, err := tx.Exec(
+				`INSERT INTO attachments (task_id, user_id, name, mime_type, size, hash, created_at)
+				SELECT $1, user_id, name, mime_type, size, hash, created_at FROM attachments WHERE task_id = $2`,
+				task.ID, sourceID,
+			); err != nil {
+				return err
+			}
+			if err := copyTasks(task.Subtasks, task.ID); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return copies, copyTasks(roots, 0)
+}
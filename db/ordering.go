@@ -0,0 +1,170 @@
+// ordering.go
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"todolist/models"
+)
+
+// Ручной порядок хранится дробными позициями: перенос задачи или списка между соседями
+// получает среднее их позиций и меняет одну строку. Все строки области перенумеровываются,
+// только если у кого-то позиции ещё нет или между соседями не осталось места.
+
+// nextTaskPosition - позиция новой задачи в конце списка $1; NULL, пока порядок в списке не задавали
+const nextTaskPosition = "(SELECT MAX(position) + 1 FROM tasks WHERE list_id = $1)"
+
+// userListRanks - позиции всех списков, которые видит пользователь $1: свои хранятся
+// в todo_lists, открытые ему - в list_members, чтобы у каждого участника был свой порядок
+const userListRanks = "SELECT id, position FROM todo_lists WHERE user_id = $1 UNION ALL SELECT list_id, position FROM list_members WHERE user_id = $1"
+
+// rankBetween возвращает позицию между lo и hi (NULL - края); ok = false, если места нет
+func rankBetween(lo, hi sql.NullFloat64) (rank float64, ok bool) {
+	switch {
+	case !lo.Valid && !hi.Valid:
+		return 0, true
+	case !lo.Valid:
+		return hi.Float64 - 1, true
+	case !hi.Valid:
+		return lo.Float64 + 1, true
+	}
+	rank = (lo.Float64 + hi.Float64) / 2
+	return rank, rank > lo.Float64 && rank < hi.Float64
+}
+
+// neighbourRank ищет позицию рядом с target в наборе строк ranks (колонки id, position):
+// перед ним или после него, не считая переносимую строку id
+func neighbourRank(q querier, ranks string, id, target int, after bool, args ...any) (float64, bool, error) {
+	var missing bool
+	if err := q.QueryRow("SELECT EXISTS (SELECT 1 FROM ("+ranks+") r WHERE position IS NULL)", args...).Scan(&missing); err != nil {
+		return 0, false, err
+	}
+	if missing {
+		return 0, false, nil
+	}
+
+	n := len(args)
+	var pos sql.NullFloat64
+	if err := q.QueryRow(fmt.Sprintf("SELECT position FROM (%s) r WHERE id = $%d", ranks, n+1), append(args, target)...).Scan(&pos); err != nil {
+		return 0, false, err
+	}
+	neighbour := fmt.Sprintf("SELECT MAX(position) FROM (%s) r WHERE position < $%d AND id <> $%d", ranks, n+1, n+2)
+	if after {
+		neighbour = fmt.Sprintf("SELECT MIN(position) FROM (%s) r WHERE position > $%d AND id <> $%d", ranks, n+1, n+2)
+	}
+	var other sql.NullFloat64
+	if err := q.QueryRow(neighbour, append(args, pos.Float64, id)...).Scan(&other); err != nil {
+		return 0, false, err
+	}
+	if after {
+		rank, ok := rankBetween(pos, other)
+		return rank, ok, nil
+	}
+	rank, ok := rankBetween(other, pos)
+	return rank, ok, nil
+}
+
+// MoveTask ставит задачу перед задачей targetID того же списка или после неё
+func MoveTask(userID, taskID, targetID int, after bool) error {
+	if taskID == targetID {
+		return nil
+	}
+	return withTx(func(tx *sql.Tx) error {
+		if err := requireTaskRole(tx, userID, taskID, models.RoleEditor); err != nil {
+			return err
+		}
+		var listID int
+		err := tx.QueryRow(
+			"SELECT t.list_id FROM tasks t JOIN tasks x ON x.list_id = t.list_id WHERE t.id = $1 AND x.id = $2",
+			taskID, targetID,
+		).Scan(&listID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("задачи находятся в разных списках")
+		}
+		if err != nil {
+			return err
+		}
+
+		const ranks = "SELECT id, position FROM tasks WHERE list_id = $1"
+		rank, ok, err := neighbourRank(tx, ranks, taskID, targetID, after, listID)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			// Нумеруем в том порядке, который пользователь сейчас видит
+			if _, err := tx.Exec(
+				`UPDATE tasks t SET position = o.n FROM (
+					SELECT id, row_number() OVER (ORDER BY position NULLS LAST, due_date NULLS LAST, created_at DESC) AS n
+					FROM tasks WHERE list_id = $1) o
+				WHERE t.id = o.id`,
+				listID,
+			); err != nil {
+				return err
+			}
+			if rank, _, err = neighbourRank(tx, ranks, taskID, targetID, after, listID); err != nil {
+				return err
+			}
+		}
+		_, err = tx.Exec("UPDATE tasks SET position = $1 WHERE id = $2", rank, taskID)
+		return err
+	})
+}
+
+// MoveTodoList ставит список перед списком targetID или после него в порядке пользователя userID
+func MoveTodoList(userID, listID, targetID int, after bool) error {
+	if listID == targetID {
+		return nil
+	}
+	return withTx(func(tx *sql.Tx) error {
+		for _, id := range []int{listID, targetID} {
+			if err := requireListRole(tx, userID, id, models.RoleViewer); err != nil {
+				return err
+			}
+		}
+
+		rank, ok, err := neighbourRank(tx, userListRanks, listID, targetID, after, userID)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			if _, err := tx.Exec(
+				`WITH o AS (
+					SELECT r.id, row_number() OVER (ORDER BY r.position NULLS LAST, l.created_at DESC) AS n
+					FROM (`+userListRanks+`) r JOIN todo_lists l ON l.id = r.id
+				), owned AS (
+					UPDATE todo_lists l SET position = o.n FROM o WHERE l.id = o.id AND l.user_id = $1
+				)
+				UPDATE list_members m SET position = o.n FROM o WHERE m.list_id = o.id AND m.user_id = $1`,
+				userID,
+			); err != nil {
+				return err
+			}
+			if rank, _, err = neighbourRank(tx, userListRanks, listID, targetID, after, userID); err != nil {
+				return err
+			}
+		}
+
+		res, err := tx.Exec("UPDATE todo_lists SET position = $1 WHERE id = $2 AND user_id = $3", rank, listID, userID)
+		if err != nil {
+			return err
+		}
+		if n, err := res.RowsAffected(); err != nil || n > 0 {
+			return err
+		}
+		_, err = tx.Exec("UPDATE list_members SET position = $1 WHERE list_id = $2 AND user_id = $3", rank, listID, userID)
+		return err
+	})
+}
+
+// SetListManualOrder включает ручной порядок задач списка или возвращает сортировку по сроку.
+// Порядок общий для всех участников, поэтому менять его может редактор.
+func SetListManualOrder(userID, listID int, manual bool) error {
+	return withTx(func(tx *sql.Tx) error {
+		if err := requireListRole(tx, userID, listID, models.RoleEditor); err != nil {
+			return err
+		}
+		_, err := tx.Exec("UPDATE todo_lists SET manual_order = $1 WHERE id = $2", manual, listID)
+		return err
+	})
+}
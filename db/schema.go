@@ -0,0 +1,168 @@
+// schema.go
+package db
+
+import "fmt"
+
+// Изменения схемы поверх исходных таблиц users, todo_lists и tasks.
+// Каждая команда должна быть идемпотентной: они выполняются при каждом запуске.
+var migrations = []string{
+	`ALTER TABLE tasks ADD COLUMN IF NOT EXISTS external_uid TEXT`,
+	`CREATE INDEX IF NOT EXISTS tasks_list_external_uid_idx ON tasks (list_id, external_uid)`,
+	`CREATE TABLE IF NOT EXISTS feed_tokens (
+		token TEXT PRIMARY KEY,
+		user_id INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+		list_id INTEGER REFERENCES todo_lists (id) ON DELETE CASCADE,
+		created_at TIMESTAMP NOT NULL,
+		revoked_at TIMESTAMP
+	)`,
+	// Версия календаря, отданного по подписке: ETag и момент его последнего изменения
+	`ALTER TABLE feed_tokens ADD COLUMN IF NOT EXISTS etag TEXT`,
+	`ALTER TABLE feed_tokens ADD COLUMN IF NOT EXISTS modified_at TIMESTAMP`,
+	`ALTER TABLE tasks ADD COLUMN IF NOT EXISTS priority SMALLINT NOT NULL DEFAULT 0`,
+	`ALTER TABLE tasks ADD COLUMN IF NOT EXISTS completed_at TIMESTAMP`,
+	`CREATE TABLE IF NOT EXISTS task_tags (
+		task_id INTEGER NOT NULL REFERENCES tasks (id) ON DELETE CASCADE,
+		tag TEXT NOT NULL,
+		PRIMARY KEY (task_id, tag)
+	)`,
+	`CREATE INDEX IF NOT EXISTS task_tags_tag_idx ON task_tags (tag)`,
+	`ALTER TABLE tasks ADD COLUMN IF NOT EXISTS parent_id INTEGER REFERENCES tasks (id) ON DELETE CASCADE`,
+	`CREATE TABLE IF NOT EXISTS list_members (
+		list_id INTEGER NOT NULL REFERENCES todo_lists (id) ON DELETE CASCADE,
+		user_id INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+		role TEXT NOT NULL CHECK (role IN ('editor', 'viewer')),
+		created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+		PRIMARY KEY (list_id, user_id)
+	)`,
+	`CREATE INDEX IF NOT EXISTS list_members_user_idx ON list_members (user_id)`,
+	`ALTER TABLE tasks ADD COLUMN IF NOT EXISTS assignee_id INTEGER REFERENCES users (id) ON DELETE SET NULL`,
+	`CREATE INDEX IF NOT EXISTS tasks_assignee_idx ON tasks (assignee_id)`,
+	`CREATE TABLE IF NOT EXISTS notifications (
+		id SERIAL PRIMARY KEY,
+		user_id INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+		actor_id INTEGER REFERENCES users (id) ON DELETE SET NULL,
+		task_id INTEGER NOT NULL REFERENCES tasks (id) ON DELETE CASCADE,
+		kind TEXT NOT NULL,
+		created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+		read_at TIMESTAMP
+	)`,
+	`CREATE INDEX IF NOT EXISTS notifications_user_idx ON notifications (user_id, read_at)`,
+	`CREATE TABLE IF NOT EXISTS comments (
+		id SERIAL PRIMARY KEY,
+		task_id INTEGER NOT NULL REFERENCES tasks (id) ON DELETE CASCADE,
+		user_id INTEGER REFERENCES users (id) ON DELETE SET NULL,
+		body TEXT NOT NULL,
+		created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+		updated_at TIMESTAMP
+	)`,
+	`CREATE INDEX IF NOT EXISTS comments_task_idx ON comments (task_id, created_at)`,
+	`CREATE TABLE IF NOT EXISTS attachment_blobs (
+		hash TEXT PRIMARY KEY,
+		data BYTEA NOT NULL
+	)`,
+	`CREATE TABLE IF NOT EXISTS attachments (
+		id SERIAL PRIMARY KEY,
+		task_id INTEGER NOT NULL REFERENCES tasks (id) ON DELETE CASCADE,
+		user_id INTEGER REFERENCES users (id) ON DELETE SET NULL,
+		name TEXT NOT NULL,
+		mime_type TEXT NOT NULL,
+		size BIGINT NOT NULL,
+		hash TEXT NOT NULL,
+		created_at TIMESTAMP NOT NULL DEFAULT NOW()
+	)`,
+	`CREATE INDEX IF NOT EXISTS attachments_task_idx ON attachments (task_id)`,
+	`CREATE INDEX IF NOT EXISTS attachments_hash_idx ON attachments (hash)`,
+	`ALTER TABLE users ADD COLUMN IF NOT EXISTS password_hash TEXT`,
+	`ALTER TABLE users ADD COLUMN IF NOT EXISTS enc_key TEXT`,
+	// Зашифрованные поля длиннее исходных, поэтому ограничений длины у них быть не должно.
+	// Тип меняется, только если он ещё не TEXT: ALTER ... TYPE блокирует таблицу и перестраивает индексы.
+	`DO $$
+	DECLARE c RECORD;
+	BEGIN
+		FOR c IN SELECT table_name, column_name FROM information_schema.columns
+			WHERE table_schema = current_schema() AND table_name IN ('todo_lists', 'tasks')
+				AND column_name IN ('title', 'description') AND data_type <> 'text'
+		LOOP
+			EXECUTE format('ALTER TABLE %I ALTER COLUMN %I TYPE TEXT', c.table_name, c.column_name);
+		END LOOP;
+	END $$`,
+	// Полнотекстовый поиск: текст разбирается сразу русской и английской конфигурациями
+	`CREATE OR REPLACE FUNCTION search_vector(body TEXT) RETURNS tsvector AS $$
+		SELECT to_tsvector('russian', COALESCE(body, '')) || to_tsvector('english', COALESCE(body, ''))
+	$$ LANGUAGE SQL IMMUTABLE`,
+	`CREATE OR REPLACE FUNCTION task_search_vector(title TEXT, description TEXT) RETURNS tsvector AS $$
+		SELECT setweight(search_vector(title), 'A') || setweight(search_vector(description), 'B')
+	$$ LANGUAGE SQL IMMUTABLE`,
+	`CREATE INDEX IF NOT EXISTS tasks_search_idx ON tasks USING GIN (task_search_vector(title, description))`,
+	`CREATE TABLE IF NOT EXISTS smart_lists (
+		id SERIAL PRIMARY KEY,
+		user_id INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+		title TEXT NOT NULL,
+		query TEXT NOT NULL,
+		created_at TIMESTAMP NOT NULL DEFAULT NOW()
+	)`,
+	`CREATE INDEX IF NOT EXISTS smart_lists_user_idx ON smart_lists (user_id)`,
+	`CREATE TABLE IF NOT EXISTS list_statuses (
+		id SERIAL PRIMARY KEY,
+		list_id INTEGER NOT NULL REFERENCES todo_lists (id) ON DELETE CASCADE,
+		title TEXT NOT NULL,
+		position INTEGER NOT NULL DEFAULT 0,
+		wip_limit INTEGER NOT NULL DEFAULT 0,
+		is_final BOOLEAN NOT NULL DEFAULT FALSE
+	)`,
+	`CREATE INDEX IF NOT EXISTS list_statuses_list_idx ON list_statuses (list_id)`,
+	`ALTER TABLE tasks ADD COLUMN IF NOT EXISTS status_id INTEGER REFERENCES list_statuses (id) ON DELETE SET NULL`,
+	`CREATE INDEX IF NOT EXISTS tasks_status_idx ON tasks (status_id)`,
+	// Ручной порядок: NULL - позиция ещё не назначена, такие строки идут после остальных
+	`ALTER TABLE tasks ADD COLUMN IF NOT EXISTS position DOUBLE PRECISION`,
+	`ALTER TABLE todo_lists ADD COLUMN IF NOT EXISTS position DOUBLE PRECISION`,
+	`ALTER TABLE list_members ADD COLUMN IF NOT EXISTS position DOUBLE PRECISION`,
+	`ALTER TABLE todo_lists ADD COLUMN IF NOT EXISTS manual_order BOOLEAN NOT NULL DEFAULT FALSE`,
+	`ALTER TABLE todo_lists ADD COLUMN IF NOT EXISTS archived_at TIMESTAMP`,
+	`ALTER TABLE todo_lists ADD COLUMN IF NOT EXISTS auto_archive BOOLEAN NOT NULL DEFAULT FALSE`,
+	`CREATE TABLE IF NOT EXISTS list_folders (
+		id SERIAL PRIMARY KEY,
+		user_id INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+		parent_id INTEGER REFERENCES list_folders (id) ON DELETE CASCADE,
+		title TEXT NOT NULL,
+		collapsed BOOLEAN NOT NULL DEFAULT FALSE,
+		created_at TIMESTAMP NOT NULL DEFAULT NOW()
+	)`,
+	`CREATE INDEX IF NOT EXISTS list_folders_user_idx ON list_folders (user_id)`,
+	// Папка и закрепление, как и позиция, у каждого пользователя свои: у владельца - в todo_lists,
+	// у участника - в list_members
+	`ALTER TABLE todo_lists ADD COLUMN IF NOT EXISTS folder_id INTEGER REFERENCES list_folders (id) ON DELETE SET NULL`,
+	`ALTER TABLE list_members ADD COLUMN IF NOT EXISTS folder_id INTEGER REFERENCES list_folders (id) ON DELETE SET NULL`,
+	`ALTER TABLE todo_lists ADD COLUMN IF NOT EXISTS pinned BOOLEAN NOT NULL DEFAULT FALSE`,
+	`ALTER TABLE list_members ADD COLUMN IF NOT EXISTS pinned BOOLEAN NOT NULL DEFAULT FALSE`,
+	`CREATE TABLE IF NOT EXISTS list_templates (
+		id SERIAL PRIMARY KEY,
+		user_id INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+		title TEXT NOT NULL,
+		description TEXT,
+		created_at TIMESTAMP NOT NULL DEFAULT NOW()
+	)`,
+	`CREATE INDEX IF NOT EXISTS list_templates_user_idx ON list_templates (user_id)`,
+	// due_offset - срок в днях от даты начала списка; NULL - задача без срока
+	`CREATE TABLE IF NOT EXISTS template_tasks (
+		id SERIAL PRIMARY KEY,
+		template_id INTEGER NOT NULL REFERENCES list_templates (id) ON DELETE CASCADE,
+		parent_id INTEGER REFERENCES template_tasks (id) ON DELETE CASCADE,
+		title TEXT NOT NULL,
+		description TEXT,
+		priority INTEGER NOT NULL DEFAULT 0,
+		due_offset INTEGER,
+		tags TEXT[] NOT NULL DEFAULT '{}',
+		position INTEGER NOT NULL DEFAULT 0
+	)`,
+	`CREATE INDEX IF NOT EXISTS template_tasks_template_idx ON template_tasks (template_id)`,
+}
+
+func migrate() error {
+	for _, stmt := range migrations {
+		if _, err := DB.Exec(stmt); err != nil {
+			return fmt.Errorf("ошибка обновления схемы: %v", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,190 @@
+// search.go
+package db
+
+// Полнотекстовый поиск задач во всех доступных пользователю списках.
+//
+// Название, описание, теги и комментарии разбираются сразу русской и английской конфигурациями
+// Postgres (функции search_vector и task_search_vector в schema.go, по названию и описанию
+// есть GIN-индекс). Запрос понимает синтаксис websearch_to_tsquery: слова, "фразы", or и -слово.
+// Веса: название и теги - A, описание - B, комментарии - C; выдача упорядочена по ts_rank.
+//
+// Зашифрованные задачи (см. crypto.go) база прочитать не может, поэтому их ищет само приложение
+// после расшифровки: задача подходит, если в названии, описании или тегах есть все слова запроса.
+// Словоформы и операторы при этом не учитываются, а списки с закрытым ключом не просматриваются.
+
+import (
+	"sort"
+	"strings"
+	"todolist/models"
+
+	"github.com/lib/pq"
+)
+
+const searchLimit = 50
+
+// Задачи из собственных и открытых пользователю $1 списков
+const accessibleTasks = "t.list_id IN (SELECT id FROM todo_lists WHERE user_id = $1 UNION SELECT list_id FROM list_members WHERE user_id = $1)"
+
+// SearchTasks ищет задачи по тексту запроса, самые релевантные первыми; архивные списки не просматриваются
+func SearchTasks(userID int, query string) ([]models.SearchResult, error) {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return nil, nil
+	}
+
+	results, err := searchIndexed(userID, query)
+	if err != nil {
+		return nil, err
+	}
+	sealed, err := searchSealed(userID, query)
+	if err != nil {
+		return nil, err
+	}
+
+	results = append(results, sealed...)
+	sort.SliceStable(results, func(i, j int) bool { return results[i].Rank > results[j].Rank })
+	if len(results) > searchLimit {
+		results = results[:searchLimit]
+	}
+	return results, nil
+}
+
+// searchIndexed ищет средствами Postgres среди незашифрованных задач. Задача без тегов и
+// комментариев может подойти только по названию и описанию, поэтому такие задачи отбираются
+// GIN-индексом, и полный документ собирается лишь для них и задач с тегами или комментариями.
+func searchIndexed(userID int, query string) ([]models.SearchResult, error) {
+	rows, err := DB.Query(
+		`WITH q AS (SELECT websearch_to_tsquery('russian', $2) || websearch_to_tsquery('english', $2) AS query)
+		SELECT t.id, l.title, ts_rank(d.doc, q.query) AS rank,
+			ts_headline('russian', COALESCE(t.description, ''), q.query, 'MaxWords=20, MinWords=8, StartSel=«, StopSel=»')
+		FROM tasks t
+		JOIN todo_lists l ON l.id = t.list_id
+		CROSS JOIN q
+		CROSS JOIN LATERAL (SELECT task_search_vector(t.title, t.description)
+			|| setweight(search_vector((SELECT string_agg(tag, ' ') FROM task_tags WHERE task_id = t.id)), 'A')
+			|| setweight(search_vector((SELECT string_agg(body, ' ') FROM comments WHERE task_id = t.id)), 'C') AS doc) d
+		WHERE `+accessibleTasks+` AND `+activeTasks+` AND t.title NOT LIKE $3 AND t.id IN (
+			SELECT id FROM tasks WHERE task_search_vector(title, description) @@ (SELECT query FROM q)
+			UNION SELECT task_id FROM task_tags
+			UNION SELECT task_id FROM comments
+		) AND d.doc @@ q.query
+		ORDER BY rank DESC, t.created_at DESC
+		LIMIT $4`,
+		userID, query, sealedPrefix+"%", searchLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		results []models.SearchResult
+		ids     []int64
+	)
+	for rows.Next() {
+		var r models.SearchResult
+		if err := rows.Scan(&r.Task.ID, &r.ListTitle, &r.Rank, &r.Snippet); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		r.ListTitle = openField(r.ListTitle)
+		results = append(results, r)
+		ids = append(ids, int64(r.Task.ID))
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(results) == 0 {
+		return nil, nil
+	}
+
+	// Сами задачи загружаем обычным путём, чтобы получить теги и число комментариев
+	taskRows, err := DB.Query("SELECT "+taskColumns+" FROM tasks WHERE id = ANY($1)", pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	tasks, err := scanTasks(DB, taskRows)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[int]models.Task, len(tasks))
+	for _, task := range tasks {
+		byID[task.ID] = task
+	}
+	for i := range results {
+		results[i].Task = byID[results[i].Task.ID]
+	}
+	return results, nil
+}
+
+// searchSealed ищет в приложении среди зашифрованных задач с открытым ключом
+func searchSealed(userID int, query string) ([]models.SearchResult, error) {
+	rows, err := DB.Query(
+		"SELECT "+prefixColumns("t", taskColumns)+" FROM tasks t WHERE "+accessibleTasks+" AND "+activeTasks+" AND t.title LIKE $2",
+		userID, sealedPrefix+"%",
+	)
+	if err != nil {
+		return nil, err
+	}
+	tasks, err := scanTasks(DB, rows)
+	if err != nil || len(tasks) == 0 {
+		return nil, err
+	}
+
+	lists, err := GetTodoLists(userID)
+	if err != nil {
+		return nil, err
+	}
+	listTitles := make(map[int]string, len(lists))
+	for _, l := range lists {
+		listTitles[l.ID] = l.Title
+	}
+
+	words := strings.Fields(strings.ToLower(query))
+	var results []models.SearchResult
+	for _, task := range tasks {
+		if task.Title == LockedText {
+			continue
+		}
+		if rank := matchRank(words, task); rank > 0 {
+			results = append(results, models.SearchResult{
+				Task:      task,
+				ListTitle: listTitles[task.ListID],
+				Rank:      rank,
+				Snippet:   snippet(task.Description),
+			})
+		}
+	}
+	return results, nil
+}
+
+// matchRank - грубая оценка релевантности, по порядку величины сравнимая с ts_rank:
+// слово в названии или тегах весит больше, чем в описании. 0 - подходят не все слова.
+func matchRank(words []string, task models.Task) float64 {
+	title := strings.ToLower(task.Title)
+	tags := strings.ToLower(strings.Join(task.Tags, " "))
+	description := strings.ToLower(task.Description)
+
+	var score float64
+	for _, word := range words {
+		switch {
+		case strings.Contains(title, word), strings.Contains(tags, word):
+			score += 1
+		case strings.Contains(description, word):
+			score += 0.4
+		default:
+			return 0
+		}
+	}
+	return score / float64(len(words)) * 0.1
+}
+
+// snippet - начало описания для выдачи
+func snippet(description string) string {
+	const maxRunes = 120
+	runes := []rune(strings.Join(strings.Fields(description), " "))
+	if len(runes) <= maxRunes {
+		return string(runes)
+	}
+	return string(runes[:maxRunes]) + "…"
+}
@@ -0,0 +1,134 @@
+// sharing.go
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"todolist/models"
+)
+
+// ErrAccessDenied возвращается, когда у пользователя нет нужной роли в списке
+var ErrAccessDenied = errors.New("недостаточно прав для этого действия")
+
+// listRole возвращает роль пользователя в списке или пустую роль, если доступа нет
+func listRole(q querier, userID, listID int) (models.Role, error) {
+	var role sql.NullString
+	err := q.QueryRow(
+		`SELECT CASE WHEN l.user_id = $1 THEN 'owner' ELSE m.role END
+		FROM todo_lists l LEFT JOIN list_members m ON m.list_id = l.id AND m.user_id = $1
+		WHERE l.id = $2`,
+		userID, listID,
+	).Scan(&role)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", fmt.Errorf("список %d не найден", listID)
+	}
+	if err != nil {
+		return "", err
+	}
+	return models.Role(role.String), nil
+}
+
+// requireListRole проверяет, что роль пользователя в списке не ниже need
+func requireListRole(q querier, userID, listID int, need models.Role) error {
+	role, err := listRole(q, userID, listID)
+	if err != nil {
+		return err
+	}
+	if !role.Allows(need) {
+		return ErrAccessDenied
+	}
+	return nil
+}
+
+// requireTaskRole проверяет права на список, в котором сейчас находится задача
+func requireTaskRole(q querier, userID, taskID int, need models.Role) error {
+	var listID int
+	err := q.QueryRow("SELECT list_id FROM tasks WHERE id = $1", taskID).Scan(&listID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("задача %d не найдена", taskID)
+	}
+	if err != nil {
+		return err
+	}
+	return requireListRole(q, userID, listID, need)
+}
+
+// ShareList открывает список пользователю с ролью редактора или читателя либо меняет его роль.
+// Управлять доступом может только владелец.
+func ShareList(actorID, listID, userID int, role models.Role) error {
+	if role != models.RoleEditor && role != models.RoleViewer {
+		return fmt.Errorf("недопустимая роль участника: %q", role)
+	}
+	return withTx(func(tx *sql.Tx) error {
+		if err := requireListRole(tx, actorID, listID, models.RoleOwner); err != nil {
+			return err
+		}
+
+		var ownerID int
+		if err := tx.QueryRow("SELECT user_id FROM todo_lists WHERE id = $1", listID).Scan(&ownerID); err != nil {
+			return err
+		}
+		if ownerID == userID {
+			return fmt.Errorf("владелец уже имеет полный доступ к списку")
+		}
+		wrapped, err := wrappedKey(tx, ownerID)
+		if err != nil {
+			return err
+		}
+		if wrapped != "" {
+			return ErrSharedEncrypted
+		}
+
+		_, err = tx.Exec(
+			`INSERT INTO list_members (list_id, user_id, role, created_at) VALUES ($1, $2, $3, NOW())
+			ON CONFLICT (list_id, user_id) DO UPDATE SET role = EXCLUDED.role`,
+			listID, userID, string(role),
+		)
+		return err
+	})
+}
+
+// UnshareList закрывает доступ к списку. Владелец может убрать любого участника,
+// участник - только себя (выйти из списка).
+func UnshareList(actorID, listID, userID int) error {
+	return withTx(func(tx *sql.Tx) error {
+		if actorID != userID {
+			if err := requireListRole(tx, actorID, listID, models.RoleOwner); err != nil {
+				return err
+			}
+		}
+		if _, err := tx.Exec("DELETE FROM list_members WHERE list_id = $1 AND user_id = $2", listID, userID); err != nil {
+			return err
+		}
+		// Задачи списка больше не могут быть на пользователе без доступа
+		_, err := tx.Exec("UPDATE tasks SET assignee_id = NULL WHERE list_id = $1 AND assignee_id = $2", listID, userID)
+		return err
+	})
+}
+
+// GetListMembers возвращает участников списка (без владельца)
+func GetListMembers(listID int) ([]models.ListMember, error) {
+	rows, err := DB.Query(
+		"SELECT list_id, user_id, role, created_at FROM list_members WHERE list_id = $1 ORDER BY created_at",
+		listID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var members []models.ListMember
+	for rows.Next() {
+		var (
+			member models.ListMember
+			role   string
+		)
+		if err := rows.Scan(&member.ListID, &member.UserID, &role, &member.CreatedAt); err != nil {
+			return nil, err
+		}
+		member.Role = models.Role(role)
+		members = append(members, member)
+	}
+	return members, rows.Err()
+}
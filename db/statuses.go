@@ -0,0 +1,387 @@
+// statuses.go
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"todolist/models"
+)
+
+// ErrWIPLimit возвращается, когда задача попадает в колонку, где уже набрано предельное число задач
+var ErrWIPLimit = errors.New("в колонке достигнут предел незавершённых задач")
+
+var errStatusKinds = errors.New("на доске должна остаться хотя бы одна колонка для выполненных и одна для невыполненных задач")
+
+// Колонки, которые получает список при первом обращении к доске
+var defaultStatuses = []models.Status{
+	{Title: "К выполнению"},
+	{Title: "В работе"},
+	{Title: "На проверке"},
+	{Title: "Готово", IsFinal: true},
+}
+
+const statusColumns = "id, list_id, title, position, wip_limit, is_final"
+
+// ensureListStatuses создаёт колонки по умолчанию для списка, у которого их ещё нет,
+// и раскладывает по колонкам задачи без статуса (созданные до появления доски или
+// восстановленные из старого снимка): выполненные - в итоговые, остальные - в рабочие.
+// Если делать ничего не нужно, обходится одним чтением; иначе блокирует строку списка,
+// чтобы параллельные транзакции не создали колонки дважды.
+func ensureListStatuses(q querier, listID int) error {
+	var hasStatuses, unsorted bool
+	if err := q.QueryRow(
+		`SELECT EXISTS (SELECT 1 FROM list_statuses WHERE list_id = $1),
+			EXISTS (SELECT 1 FROM tasks WHERE list_id = $1 AND status_id IS NULL)`,
+		listID,
+	).Scan(&hasStatuses, &unsorted); err != nil {
+		return err
+	}
+	if hasStatuses && !unsorted {
+		return nil
+	}
+
+	if _, err := q.Exec("SELECT id FROM todo_lists WHERE id = $1 FOR UPDATE", listID); err != nil {
+		return err
+	}
+	if !hasStatuses {
+		// Пока ждали блокировку, колонки могла создать другая транзакция
+		if err := q.QueryRow("SELECT EXISTS (SELECT 1 FROM list_statuses WHERE list_id = $1)", listID).Scan(&hasStatuses); err != nil {
+			return err
+		}
+	}
+	if !hasStatuses {
+		for i, s := range defaultStatuses {
+			if _, err := q.Exec(
+				"INSERT INTO list_statuses (list_id, title, position, is_final) VALUES ($1, $2, $3, $4)",
+				listID, s.Title, i, s.IsFinal,
+			); err != nil {
+				return err
+			}
+		}
+	}
+	return placeUnsorted(q, listID)
+}
+
+// placeUnsorted раскладывает задачи списка без статуса по колонкам с учётом предела.
+// Задачи, которым места не нашлось, попадают в первую колонку своего вида сверх предела:
+// это старые данные, и прятать их с доски хуже, чем превысить предел.
+func placeUnsorted(q querier, listID int) error {
+	rows, err := q.Query("SELECT id, is_done FROM tasks WHERE list_id = $1 AND status_id IS NULL ORDER BY position NULLS LAST, id", listID)
+	if err != nil {
+		return err
+	}
+	type unsorted struct {
+		id   int
+		done bool
+	}
+	var tasks []unsorted
+	for rows.Next() {
+		var t unsorted
+		if err := rows.Scan(&t.id, &t.done); err != nil {
+			rows.Close()
+			return err
+		}
+		tasks = append(tasks, t)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, t := range tasks {
+		statusID, err := freeStatus(q, listID, t.done, t.id)
+		if errors.Is(err, ErrWIPLimit) {
+			err = q.QueryRow(
+				"SELECT id FROM list_statuses WHERE list_id = $1 AND is_final = $2 ORDER BY position, id LIMIT 1",
+				listID, t.done,
+			).Scan(&statusID)
+		}
+		if err != nil {
+			return err
+		}
+		if _, err := q.Exec("UPDATE tasks SET status_id = $1 WHERE id = $2", statusID, t.id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// freeStatus возвращает первую колонку списка нужного вида, где есть место для задачи taskID
+// (0 - новая задача), или ErrWIPLimit, если все такие колонки заполнены
+func freeStatus(q querier, listID int, final bool, taskID int) (int, error) {
+	var statusID int
+	err := q.QueryRow(
+		`SELECT s.id FROM list_statuses s
+		WHERE s.list_id = $1 AND s.is_final = $2
+			AND (s.wip_limit = 0 OR (SELECT COUNT(*) FROM tasks t WHERE t.status_id = s.id AND t.id <> $3) < s.wip_limit)
+		ORDER BY s.position, s.id LIMIT 1`,
+		listID, final, taskID,
+	).Scan(&statusID)
+	if errors.Is(err, sql.ErrNoRows) {
+		kind := "невыполненных"
+		if final {
+			kind = "выполненных"
+		}
+		return 0, fmt.Errorf("%w: во всех колонках для %s задач", ErrWIPLimit, kind)
+	}
+	return statusID, err
+}
+
+// checkWIPLimit проверяет, что в колонке есть место для задачи taskID (0 - новая задача)
+func checkWIPLimit(q querier, status *models.Status, taskID int) error {
+	if status.WIPLimit == 0 {
+		return nil
+	}
+	var count int
+	if err := q.QueryRow(
+		"SELECT COUNT(*) FROM tasks WHERE status_id = $1 AND id <> $2", status.ID, taskID,
+	).Scan(&count); err != nil {
+		return err
+	}
+	if count >= status.WIPLimit {
+		return fmt.Errorf("%w: %q, не больше %d", ErrWIPLimit, status.Title, status.WIPLimit)
+	}
+	return nil
+}
+
+// syncStatus согласует статус задачи с отметкой о выполнении перед сохранением:
+// если статус не задан, относится к другому списку или не совпадает с IsDone,
+// задача попадает в первую подходящую колонку своего списка, где есть место.
+// Предел колонки проверяется, только когда задача в неё попадает, поэтому правка задачи
+// в колонке, предел которой уменьшили, не мешает.
+func syncStatus(q querier, task *models.Task) error {
+	if err := ensureListStatuses(q, task.ListID); err != nil {
+		return err
+	}
+	if task.StatusID != 0 {
+		var status models.Status
+		err := q.QueryRow("SELECT "+statusColumns+" FROM list_statuses WHERE id = $1 AND list_id = $2", task.StatusID, task.ListID).
+			Scan(&status.ID, &status.ListID, &status.Title, &status.Position, &status.WIPLimit, &status.IsFinal)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+		if err == nil && status.IsFinal == task.IsDone {
+			if task.ID != 0 {
+				var current sql.NullInt64
+				if err := q.QueryRow("SELECT status_id FROM tasks WHERE id = $1", task.ID).Scan(&current); err != nil {
+					return err
+				}
+				if int(current.Int64) == task.StatusID {
+					return nil
+				}
+			}
+			return checkWIPLimit(q, &status, task.ID)
+		}
+	}
+	statusID, err := freeStatus(q, task.ListID, task.IsDone, task.ID)
+	if err != nil {
+		return err
+	}
+	task.StatusID = statusID
+	return nil
+}
+
+// GetListStatuses возвращает колонки доски списка по порядку
+func GetListStatuses(listID int) ([]models.Status, error) {
+	var statuses []models.Status
+	err := withTx(func(tx *sql.Tx) error {
+		if err := ensureListStatuses(tx, listID); err != nil {
+			return err
+		}
+		var err error
+		statuses, err = queryStatuses(tx, listID)
+		return err
+	})
+	return statuses, err
+}
+
+func queryStatuses(q querier, listID int) ([]models.Status, error) {
+	rows, err := q.Query("SELECT "+statusColumns+" FROM list_statuses WHERE list_id = $1 ORDER BY position, id", listID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var statuses []models.Status
+	for rows.Next() {
+		var s models.Status
+		if err := rows.Scan(&s.ID, &s.ListID, &s.Title, &s.Position, &s.WIPLimit, &s.IsFinal); err != nil {
+			return nil, err
+		}
+		statuses = append(statuses, s)
+	}
+	return statuses, rows.Err()
+}
+
+func queryStatus(q querier, statusID int) (*models.Status, error) {
+	var s models.Status
+	err := q.QueryRow("SELECT "+statusColumns+" FROM list_statuses WHERE id = $1", statusID).
+		Scan(&s.ID, &s.ListID, &s.Title, &s.Position, &s.WIPLimit, &s.IsFinal)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("колонка %d не найдена", statusID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// SetTaskStatus переносит задачу в колонку statusID того же списка; нужна роль редактора.
+// Отметка о выполнении следует за колонкой, предел незавершённых задач проверяется здесь же.
+func SetTaskStatus(userID, taskID, statusID int) error {
+	return withTx(func(tx *sql.Tx) error {
+		if err := requireTaskRole(tx, userID, taskID, models.RoleEditor); err != nil {
+			return err
+		}
+		status, err := queryStatus(tx, statusID)
+		if err != nil {
+			return err
+		}
+		var listID int
+		if err := tx.QueryRow("SELECT list_id FROM tasks WHERE id = $1", taskID).Scan(&listID); err != nil {
+			return err
+		}
+		if status.ListID != listID {
+			return fmt.Errorf("колонка %q относится к другому списку", status.Title)
+		}
+		if err := checkWIPLimit(tx, status, taskID); err != nil {
+			return err
+		}
+		// Название и описание не трогаем, чтобы не перешифровывать их
+		_, err = tx.Exec(
+			`UPDATE tasks SET status_id = $1, is_done = $2,
+				completed_at = CASE WHEN $2 THEN COALESCE(completed_at, $3) END
+			WHERE id = $4`,
+			statusID, status.IsFinal, time.Now(), taskID,
+		)
+		if err != nil {
+			return err
+		}
+		return autoArchive(tx, listID)
+	})
+}
+
+func checkStatus(status *models.Status) error {
+	status.Title = strings.TrimSpace(status.Title)
+	if status.Title == "" {
+		return fmt.Errorf("название колонки не может быть пустым")
+	}
+	if status.WIPLimit < 0 {
+		return fmt.Errorf("предел задач не может быть отрицательным")
+	}
+	return nil
+}
+
+// CreateStatus добавляет колонку в конец доски; настраивать доску может только владелец списка
+func CreateStatus(userID int, status *models.Status) error {
+	if err := checkStatus(status); err != nil {
+		return err
+	}
+	return withTx(func(tx *sql.Tx) error {
+		if err := requireListRole(tx, userID, status.ListID, models.RoleOwner); err != nil {
+			return err
+		}
+		if err := ensureListStatuses(tx, status.ListID); err != nil {
+			return err
+		}
+		return tx.QueryRow(
+			`INSERT INTO list_statuses (list_id, title, position, wip_limit, is_final)
+			VALUES ($1, $2, (SELECT COALESCE(MAX(position) + 1, 0) FROM list_statuses WHERE list_id = $1), $3, $4)
+			RETURNING id, position`,
+			status.ListID, status.Title, status.WIPLimit, status.IsFinal,
+		).Scan(&status.ID, &status.Position)
+	})
+}
+
+// UpdateStatus меняет название, предел и признак итоговой колонки.
+// При смене признака задачи колонки отмечаются выполненными или снова открываются.
+func UpdateStatus(userID int, status *models.Status) error {
+	if err := checkStatus(status); err != nil {
+		return err
+	}
+	return withTx(func(tx *sql.Tx) error {
+		current, err := queryStatus(tx, status.ID)
+		if err != nil {
+			return err
+		}
+		if err := requireListRole(tx, userID, current.ListID, models.RoleOwner); err != nil {
+			return err
+		}
+		status.ListID, status.Position = current.ListID, current.Position
+		if _, err := tx.Exec(
+			"UPDATE list_statuses SET title = $1, wip_limit = $2, is_final = $3 WHERE id = $4",
+			status.Title, status.WIPLimit, status.IsFinal, status.ID,
+		); err != nil {
+			return err
+		}
+		if status.IsFinal == current.IsFinal {
+			return nil
+		}
+		var hasFinal, hasOpen bool
+		if err := tx.QueryRow(
+			"SELECT COALESCE(bool_or(is_final), FALSE), COALESCE(bool_or(NOT is_final), FALSE) FROM list_statuses WHERE list_id = $1",
+			status.ListID,
+		).Scan(&hasFinal, &hasOpen); err != nil {
+			return err
+		}
+		if !hasFinal || !hasOpen {
+			return errStatusKinds
+		}
+		_, err = tx.Exec(
+			"UPDATE tasks SET is_done = $1, completed_at = CASE WHEN $1 THEN COALESCE(completed_at, $2) END WHERE status_id = $3",
+			status.IsFinal, time.Now(), status.ID,
+		)
+		if err != nil {
+			return err
+		}
+		return autoArchive(tx, status.ListID)
+	})
+}
+
+// DeleteStatus удаляет колонку, перенося её задачи в первую колонку того же вида
+func DeleteStatus(userID, statusID int) error {
+	return withTx(func(tx *sql.Tx) error {
+		status, err := queryStatus(tx, statusID)
+		if err != nil {
+			return err
+		}
+		if err := requireListRole(tx, userID, status.ListID, models.RoleOwner); err != nil {
+			return err
+		}
+		var replacement int
+		err = tx.QueryRow(
+			"SELECT id FROM list_statuses WHERE list_id = $1 AND is_final = $2 AND id <> $3 ORDER BY position, id LIMIT 1",
+			status.ListID, status.IsFinal, statusID,
+		).Scan(&replacement)
+		if errors.Is(err, sql.ErrNoRows) {
+			return errStatusKinds
+		}
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec("UPDATE tasks SET status_id = $1 WHERE status_id = $2", replacement, statusID); err != nil {
+			return err
+		}
+		_, err = tx.Exec("DELETE FROM list_statuses WHERE id = $1", statusID)
+		return err
+	})
+}
+
+// ReorderStatuses расставляет колонки списка в порядке ids
+func ReorderStatuses(userID, listID int, ids []int) error {
+	return withTx(func(tx *sql.Tx) error {
+		if err := requireListRole(tx, userID, listID, models.RoleOwner); err != nil {
+			return err
+		}
+		for i, id := range ids {
+			res, err := tx.Exec("UPDATE list_statuses SET position = $1 WHERE id = $2 AND list_id = $3", i, id, listID)
+			if err := requireAffected(res, err); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
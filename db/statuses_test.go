@@ -0,0 +1,67 @@
+package db
+
+import (
+	"errors"
+	"testing"
+	"todolist/models"
+)
+
+// Предел колонки действует при создании задачи и при возврате выполненной в работу,
+// а колонки по умолчанию создаются один раз
+func TestWIPLimitOnPlacement(t *testing.T) {
+	openTestDB(t)
+	t.Cleanup(func() { DiscardUndo(0) })
+	userID := mustCreateUser(t)
+	list := mustCreateList(t, userID, "Доска")
+
+	statuses, err := GetListStatuses(list.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again, err := GetListStatuses(list.ID); err != nil || len(again) != len(statuses) {
+		t.Fatalf("повторное чтение доски: %d колонок, %v; want %d", len(again), err, len(statuses))
+	}
+	for _, s := range statuses {
+		if !s.IsFinal {
+			s.WIPLimit = 1
+			if err := UpdateStatus(userID, &s); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	// Каждая новая задача занимает следующую колонку, где есть место
+	open := 0
+	for _, s := range statuses {
+		if !s.IsFinal {
+			mustCreateTask(t, userID, models.Task{ListID: list.ID, Title: "В колонку " + s.Title})
+			open++
+		}
+	}
+	extra := models.Task{ListID: list.ID, Title: "Лишняя"}
+	if err := CreateTask(userID, &extra); !errors.Is(err, ErrWIPLimit) {
+		t.Errorf("CreateTask при заполненных колонках: %v, want ErrWIPLimit", err)
+	}
+
+	done := mustCreateTask(t, userID, models.Task{ListID: list.ID, Title: "Сделана", IsDone: true})
+	if err := CompleteTasks(userID, []int{done.ID}, false); !errors.Is(err, ErrWIPLimit) {
+		t.Errorf("CompleteTasks(false) при заполненных колонках: %v, want ErrWIPLimit", err)
+	}
+
+	tasks, err := GetTasksByList(list.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	counts := make(map[int]int)
+	for _, task := range tasks {
+		counts[task.StatusID]++
+	}
+	for _, s := range statuses {
+		if !s.IsFinal && counts[s.ID] != 1 {
+			t.Errorf("в колонке %q %d задач, want 1", s.Title, counts[s.ID])
+		}
+	}
+	if len(tasks) != open+1 {
+		t.Errorf("задач в списке %d, want %d", len(tasks), open+1)
+	}
+}
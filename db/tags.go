@@ -0,0 +1,73 @@
+// tags.go
+package db
+
+import (
+	"sort"
+	"strings"
+	"todolist/models"
+
+	"github.com/lib/pq"
+)
+
+// loadTags заполняет теги у переданных задач одним запросом
+func loadTags(q querier, tasks []models.Task) error {
+	if len(tasks) == 0 {
+		return nil
+	}
+
+	ids := make([]int64, len(tasks))
+	index := make(map[int]int, len(tasks))
+	for i, task := range tasks {
+		ids[i] = int64(task.ID)
+		index[task.ID] = i
+	}
+
+	rows, err := q.Query("SELECT task_id, tag FROM task_tags WHERE task_id = ANY($1) ORDER BY tag", pq.Array(ids))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			taskID int
+			tag    string
+		)
+		if err := rows.Scan(&taskID, &tag); err != nil {
+			return err
+		}
+		if i, ok := index[taskID]; ok {
+			tasks[i].Tags = append(tasks[i].Tags, tag)
+		}
+	}
+	return rows.Err()
+}
+
+// saveTags заменяет теги задачи
+func saveTags(q querier, taskID int, tags []string) error {
+	if _, err := q.Exec("DELETE FROM task_tags WHERE task_id = $1", taskID); err != nil {
+		return err
+	}
+	for _, tag := range NormalizeTags(tags) {
+		if _, err := q.Exec("INSERT INTO task_tags (task_id, tag) VALUES ($1, $2)", taskID, tag); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// NormalizeTags убирает пустые теги и повторы, приводит к нижнему регистру и сортирует
+func NormalizeTags(tags []string) []string {
+	seen := make(map[string]bool, len(tags))
+	var result []string
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(tag), "#")))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		result = append(result, tag)
+	}
+	sort.Strings(result)
+	return result
+}
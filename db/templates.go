@@ -0,0 +1,277 @@
+// templates.go
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"todolist/models"
+
+	"github.com/lib/pq"
+)
+
+// Шаблоны личные и шифруются ключом своего автора, как и его списки.
+// Сроки задач хранятся в днях от даты начала, которую указывают при сохранении
+// шаблона и при создании списка из него.
+
+// templateTask - задача шаблона в порядке вставки: родитель всегда раньше подзадач
+type templateTask struct {
+	id, parentID       int
+	title, description string
+	priority           int
+	dueOffset          sql.NullInt64
+	tags               []string
+}
+
+// dayOffset - число календарных дней от start до due
+func dayOffset(start, due time.Time) int {
+	from := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, time.UTC)
+	to := time.Date(due.Year(), due.Month(), due.Day(), 0, 0, 0, 0, time.UTC)
+	return int(to.Sub(from).Hours() / 24)
+}
+
+// CreateTemplate сохраняет список listID с задачами как шаблон пользователя userID.
+// Сроки задач отсчитываются от start; отметки о выполнении, исполнители, комментарии
+// и вложения в шаблон не попадают. Для сохранения достаточно роли читателя.
+func CreateTemplate(userID, listID int, tmpl *models.ListTemplate, start time.Time) error {
+	tmpl.Title = strings.TrimSpace(tmpl.Title)
+	if tmpl.Title == "" {
+		return fmt.Errorf("название шаблона не может быть пустым")
+	}
+	return withTx(func(tx *sql.Tx) error {
+		if err := requireListRole(tx, userID, listID, models.RoleViewer); err != nil {
+			return err
+		}
+		// Текст задач списка с закрытым ключом прочитать нельзя
+		if _, err := listSealer(tx, listID); err != nil {
+			return err
+		}
+		seal, err := userSealer(tx, userID)
+		if err != nil {
+			return err
+		}
+
+		rows, err := tx.Query(
+			"SELECT "+taskColumns+" FROM tasks WHERE list_id = $1 ORDER BY position NULLS LAST, due_date NULLS LAST, created_at DESC",
+			listID,
+		)
+		if err != nil {
+			return err
+		}
+		tasks, err := scanTasks(tx, rows)
+		if err != nil {
+			return err
+		}
+
+		title, description, err := sealPair(seal, tmpl.Title, tmpl.Description)
+		if err != nil {
+			return err
+		}
+		tmpl.UserID = userID
+		if tmpl.CreatedAt.IsZero() {
+			tmpl.CreatedAt = time.Now()
+		}
+		if err := tx.QueryRow(
+			"INSERT INTO list_templates (user_id, title, description, created_at) VALUES ($1, $2, $3, $4) RETURNING id",
+			userID, title, description, tmpl.CreatedAt,
+		).Scan(&tmpl.ID); err != nil {
+			return err
+		}
+		tmpl.TaskCount = 0
+		position := 0
+		var save func(tasks []models.Task, parentID int) error
+		save = func(tasks []models.Task, parentID int) error {
+			for _, task := range tasks {
+				title, description, err := sealPair(seal, task.Title, task.Description)
+				if err != nil {
+					return err
+				}
+				var offset sql.NullInt64
+				if task.HasDue() {
+					offset = sql.NullInt64{Int64: int64(dayOffset(start, task.DueDate)), Valid: true}
+				}
+				var id int
+				if err := tx.QueryRow(
+					`INSERT INTO template_tasks (template_id, parent_id, title, description, priority, due_offset, tags, position)
+					VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id`,
+					tmpl.ID, nullInt(parentID), title, description, task.Priority, offset, pq.Array(NormalizeTags(task.Tags)), position,
+				).Scan(&id); err != nil {
+					return err
+				}
+				position++
+				tmpl.TaskCount++
+				if err := save(task.Subtasks, id); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		return save(models.NestTasks(tasks), 0)
+	})
+}
+
+// GetTemplates возвращает шаблоны пользователя, новые первыми
+func GetTemplates(userID int) ([]models.ListTemplate, error) {
+	rows, err := DB.Query(
+		`SELECT p.id, p.user_id, p.title, COALESCE(p.description, ''), p.created_at,
+			(SELECT COUNT(*) FROM template_tasks WHERE template_id = p.id)
+		FROM list_templates p WHERE p.user_id = $1 ORDER BY p.created_at DESC`,
+		userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var templates []models.ListTemplate
+	for rows.Next() {
+		var t models.ListTemplate
+		if err := rows.Scan(&t.ID, &t.UserID, &t.Title, &t.Description, &t.CreatedAt, &t.TaskCount); err != nil {
+			return nil, err
+		}
+		t.Title = openField(t.Title)
+		t.Description = openField(t.Description)
+		templates = append(templates, t)
+	}
+	return templates, rows.Err()
+}
+
+func DeleteTemplate(userID, templateID int) error {
+	res, err := DB.Exec("DELETE FROM list_templates WHERE id = $1 AND user_id = $2", templateID, userID)
+	return requireAffected(res, err)
+}
+
+// CreateListFromTemplate создаёт список list.UserID из шаблона: задачи получают сроки
+// от даты начала start и добавляются невыполненными
+func CreateListFromTemplate(templateID int, list *models.TodoList, start time.Time) error {
+	return withTx(func(tx *sql.Tx) error {
+		var owner int
+		err := tx.QueryRow("SELECT user_id FROM list_templates WHERE id = $1", templateID).Scan(&owner)
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("шаблон %d не найден", templateID)
+		}
+		if err != nil {
+			return err
+		}
+		if owner != list.UserID {
+			return ErrAccessDenied
+		}
+
+		rows, err := tx.Query(
+			`SELECT id, COALESCE(parent_id, 0), title, COALESCE(description, ''), priority, due_offset, tags
+			FROM template_tasks WHERE template_id = $1 ORDER BY position, id`,
+			templateID,
+		)
+		if err != nil {
+			return err
+		}
+		var tasks []templateTask
+		for rows.Next() {
+			var t templateTask
+			if err := rows.Scan(&t.id, &t.parentID, &t.title, &t.description, &t.priority, &t.dueOffset, pq.Array(&t.tags)); err != nil {
+				rows.Close()
+				return err
+			}
+			t.title, t.description = openField(t.title), openField(t.description)
+			if t.title == LockedText {
+				rows.Close()
+				return ErrKeyLocked
+			}
+			tasks = append(tasks, t)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		if err := createTodoList(tx, list); err != nil {
+			return err
+		}
+		now := time.Now()
+		day := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, start.Location())
+		created := make(map[int]int, len(tasks))
+		for _, t := range tasks {
+			task := models.Task{
+				ListID:      list.ID,
+				Title:       t.title,
+				Description: t.description,
+				Priority:    t.priority,
+				Tags:        t.tags,
+				ParentID:    created[t.parentID],
+				CreatedAt:   now,
+			}
+			if t.dueOffset.Valid {
+				task.DueDate = day.AddDate(0, 0, int(t.dueOffset.Int64))
+			}
+			if err := insertTask(tx, &task); err != nil {
+				return err
+			}
+			created[t.id] = task.ID
+		}
+		return nil
+	})
+}
+
+// DuplicateList создаёт копию списка listID со всеми задачами, подзадачами, тегами,
+// вложениями и колонками доски; владельцем копии становится list.UserID, которому
+// достаточно роли читателя. При reopen задачи копии снова открываются.
+func DuplicateList(listID int, list *models.TodoList, reopen bool) error {
+	return withTx(func(tx *sql.Tx) error {
+		if err := requireListRole(tx, list.UserID, listID, models.RoleViewer); err != nil {
+			return err
+		}
+		if _, err := listSealer(tx, listID); err != nil {
+			return err
+		}
+		if err := createTodoList(tx, list); err != nil {
+			return err
+		}
+
+		source, err := queryStatuses(tx, listID)
+		if err != nil {
+			return err
+		}
+		statuses := make(map[int]int, len(source))
+		for _, s := range source {
+			var id int
+			if err := tx.QueryRow(
+				"INSERT INTO list_statuses (list_id, title, position, wip_limit, is_final) VALUES ($1, $2, $3, $4, $5) RETURNING id",
+				list.ID, s.Title, s.Position, s.WIPLimit, s.IsFinal,
+			).Scan(&id); err != nil {
+				return err
+			}
+			statuses[s.ID] = id
+		}
+
+		var ids []int64
+		if err := tx.QueryRow(
+			"SELECT COALESCE(array_agg(id), '{}') FROM tasks WHERE list_id = $1", listID,
+		).Scan(pq.Array(&ids)); err != nil {
+			return err
+		}
+		taskIDs := make([]int, len(ids))
+		for i, id := range ids {
+			taskIDs[i] = int(id)
+		}
+		roots, err := loadSubtrees(tx, taskIDs)
+		if err != nil {
+			return err
+		}
+		copies, err := copyTaskTrees(tx, roots, list.ID, statuses, reopen)
+		if err != nil {
+			return err
+		}
+		// Ручной порядок задач переносится как есть
+		for sourceID, copyID := range copies {
+			if _, err := tx.Exec(
+				"UPDATE tasks SET position = (SELECT position FROM tasks WHERE id = $1) WHERE id = $2",
+				sourceID, copyID,
+			); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,203 @@
+// feed.go
+package feed
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+	"todolist/db"
+	"todolist/ical"
+	"todolist/models"
+)
+
+const (
+	defaultAddr = "127.0.0.1:8765"
+	pathPrefix  = "/feed/"
+	pathSuffix  = ".ics"
+)
+
+// Addr - адрес, на котором слушает сервер подписок (переменная окружения TODO_FEED_ADDR)
+func Addr() string {
+	if addr := os.Getenv("TODO_FEED_ADDR"); addr != "" {
+		return addr
+	}
+	return defaultAddr
+}
+
+// BaseURL - внешний адрес сервера для ссылок подписки (переменная окружения TODO_FEED_URL)
+func BaseURL() string {
+	if url := os.Getenv("TODO_FEED_URL"); url != "" {
+		return strings.TrimRight(url, "/")
+	}
+	return "http://" + Addr()
+}
+
+// URL возвращает ссылку, которую нужно добавить в календарь
+func URL(feed models.FeedToken) string {
+	return BaseURL() + pathPrefix + feed.Token + pathSuffix
+}
+
+// Create выпускает новую секретную ссылку на все списки пользователя (listID = 0) или на один список
+func Create(userID, listID int) (*models.FeedToken, error) {
+	secret := make([]byte, 24)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, fmt.Errorf("ошибка генерации токена: %v", err)
+	}
+
+	feed := models.FeedToken{
+		Token:     hex.EncodeToString(secret),
+		UserID:    userID,
+		ListID:    listID,
+		CreatedAt: time.Now(),
+	}
+	if err := db.CreateFeedToken(&feed); err != nil {
+		return nil, err
+	}
+	return &feed, nil
+}
+
+// version - последняя отданная версия календаря
+type version struct {
+	etag     string
+	modified time.Time
+}
+
+// Server отдаёт календари по секретным ссылкам.
+// Last-Modified - момент, когда содержимое календаря изменилось в последний раз
+// с точки зрения сервера (хранится вместе с подпиской); ETag - хеш содержимого.
+type Server struct{}
+
+func NewServer() *Server {
+	return &Server{}
+}
+
+// ListenAndServe запускает сервер подписок на адресе Addr()
+func ListenAndServe() error {
+	log.Printf("Сервер календарных подписок: %s", BaseURL())
+	return http.ListenAndServe(Addr(), NewServer())
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	token, ok := strings.CutPrefix(r.URL.Path, pathPrefix)
+	token, hasSuffix := strings.CutSuffix(token, pathSuffix)
+	if !ok || !hasSuffix || token == "" {
+		http.NotFound(w, r)
+		return
+	}
+
+	feed, err := db.GetFeedToken(token)
+	if err != nil {
+		log.Printf("Ошибка загрузки подписки: %v", err)
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	if feed == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	body, err := render(*feed)
+	if err != nil {
+		log.Printf("Ошибка формирования календаря: %v", err)
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+
+	v, err := s.version(token, body)
+	if err != nil {
+		log.Printf("Ошибка сохранения версии календаря: %v", err)
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("ETag", v.etag)
+	w.Header().Set("Last-Modified", v.modified.UTC().Format(http.TimeFormat))
+	w.Header().Set("Cache-Control", "private, no-cache")
+
+	if notModified(r, v) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Length", fmt.Sprint(len(body)))
+	if r.Method == http.MethodHead {
+		return
+	}
+	w.Write(body)
+}
+
+func (s *Server) version(token string, body []byte) (version, error) {
+	sum := sha256.Sum256(body)
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+
+	modified, err := db.TouchFeedVersion(token, etag)
+	if err != nil {
+		return version{}, err
+	}
+	return version{etag: etag, modified: modified}, nil
+}
+
+func notModified(r *http.Request, v version) bool {
+	if match := r.Header.Get("If-None-Match"); match != "" {
+		for _, tag := range strings.Split(match, ",") {
+			tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+			if tag == v.etag || tag == "*" {
+				return true
+			}
+		}
+		return false
+	}
+
+	if since := r.Header.Get("If-Modified-Since"); since != "" {
+		t, err := http.ParseTime(since)
+		return err == nil && !v.modified.After(t)
+	}
+	return false
+}
+
+func render(feed models.FeedToken) ([]byte, error) {
+	todoLists, err := db.GetTodoLists(feed.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	var lists []models.ListWithTasks
+	name := "My Tasks"
+	for _, list := range todoLists {
+		if feed.ListID != 0 && list.ID != feed.ListID {
+			continue
+		}
+		// В общую ленту архивные списки не попадают; ленту самого списка не трогаем
+		if feed.ListID == 0 && !list.ArchivedAt.IsZero() {
+			continue
+		}
+		tasks, err := db.GetTasksByList(list.ID)
+		if err != nil {
+			return nil, err
+		}
+		lists = append(lists, models.ListWithTasks{TodoList: list, Tasks: tasks})
+		if feed.ListID != 0 {
+			name = list.Title
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := ical.Encode(&buf, name, lists); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,482 @@
+// filter.go
+package filter
+
+// Язык фильтров задач для умных списков.
+//
+//	is:done, is:open, is:overdue       выполнена / не выполнена / просрочена
+//	is:archived                        задача из архивного списка
+//	due:today, due:tomorrow            срок сегодня / завтра
+//	due:week, due:month                срок на этой неделе (пн-вс) / в этом месяце
+//	due:none, due:any                  без срока / со сроком
+//	due:2024-05-01, due:01.05.2024     срок в указанный день
+//	due<D, due<=D, due>D, due>=D       сравнение срока с днём D: дата, today, tomorrow, +7d, -2w
+//	list:Работа, list:"Дом и сад"      задача из списка с таким названием (без учёта регистра)
+//	tag:urgent, #urgent                задача с тегом
+//	pri:A, pri<=B, pri:none            приоритет (A - наивысший)
+//	слово, "фраза", text:слово         текст в названии или описании
+//
+// Условия через пробел (или and) должны выполняться все; or объединяет альтернативы;
+// not или "-" перед условием его отрицает; скобки группируют. Например:
+//
+//	is:overdue (list:Работа or list:Дом)
+//	due:week #urgent -is:done
+//
+// Задачи архивных списков попадают в выборку, только если фильтр упоминает is:archived.
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"todolist/models"
+)
+
+// Expr - разобранное выражение фильтра
+type Expr interface {
+	// Match проверяет задачу; list - список, в котором она лежит
+	Match(task *models.Task, list *models.TodoList) bool
+}
+
+type (
+	And struct{ Left, Right Expr }
+	Or  struct{ Left, Right Expr }
+	Not struct{ X Expr }
+
+	// Done - задача выполнена
+	Done struct{}
+	// Archived - список задачи в архиве
+	Archived struct{}
+	// DueNone - у задачи нет срока
+	DueNone struct{}
+	// DueRange - срок в полуинтервале [From, To); нулевая граница не ограничивает
+	DueRange struct{ From, To time.Time }
+	// List - название списка задачи без учёта регистра
+	List struct{ Name string }
+	// Tag - у задачи есть тег без учёта регистра
+	Tag struct{ Name string }
+	// Priority - приоритет в диапазоне [Min, Max]; 0 - без приоритета
+	Priority struct{ Min, Max int }
+	// Text - подстрока названия или описания без учёта регистра
+	Text struct{ Value string }
+)
+
+func (e And) Match(t *models.Task, l *models.TodoList) bool {
+	return e.Left.Match(t, l) && e.Right.Match(t, l)
+}
+func (e Or) Match(t *models.Task, l *models.TodoList) bool {
+	return e.Left.Match(t, l) || e.Right.Match(t, l)
+}
+func (e Not) Match(t *models.Task, l *models.TodoList) bool { return !e.X.Match(t, l) }
+
+func (Done) Match(t *models.Task, _ *models.TodoList) bool     { return t.IsDone }
+func (DueNone) Match(t *models.Task, _ *models.TodoList) bool  { return !t.HasDue() }
+func (Archived) Match(_ *models.Task, l *models.TodoList) bool { return !l.ArchivedAt.IsZero() }
+
+func (e DueRange) Match(t *models.Task, _ *models.TodoList) bool {
+	if !t.HasDue() {
+		return false
+	}
+	// Сроки хранятся без часового пояса, поэтому сравниваем показания часов, как и база
+	loc := e.From.Location()
+	if e.From.IsZero() {
+		loc = e.To.Location()
+	}
+	due := time.Date(t.DueDate.Year(), t.DueDate.Month(), t.DueDate.Day(),
+		t.DueDate.Hour(), t.DueDate.Minute(), t.DueDate.Second(), t.DueDate.Nanosecond(), loc)
+	return (e.From.IsZero() || !due.Before(e.From)) && (e.To.IsZero() || due.Before(e.To))
+}
+
+func (e List) Match(_ *models.Task, l *models.TodoList) bool {
+	return strings.EqualFold(l.Title, e.Name)
+}
+
+func (e Tag) Match(t *models.Task, _ *models.TodoList) bool {
+	for _, tag := range t.Tags {
+		if strings.EqualFold(tag, e.Name) {
+			return true
+		}
+	}
+	return false
+}
+
+func (e Priority) Match(t *models.Task, _ *models.TodoList) bool {
+	return t.Priority >= e.Min && t.Priority <= e.Max
+}
+
+func (e Text) Match(t *models.Task, _ *models.TodoList) bool {
+	value := strings.ToLower(e.Value)
+	return strings.Contains(strings.ToLower(t.Title), value) || strings.Contains(strings.ToLower(t.Description), value)
+}
+
+// MentionsArchived сообщает, упоминает ли выражение is:archived (в том числе с отрицанием)
+func MentionsArchived(e Expr) bool {
+	switch e := e.(type) {
+	case And:
+		return MentionsArchived(e.Left) || MentionsArchived(e.Right)
+	case Or:
+		return MentionsArchived(e.Left) || MentionsArchived(e.Right)
+	case Not:
+		return MentionsArchived(e.X)
+	case Archived:
+		return true
+	}
+	return false
+}
+
+// Parse разбирает выражение фильтра. Относительные даты (today, due:week и т.п.)
+// вычисляются от now, поэтому сохранённый фильтр нужно разбирать заново при каждом показе.
+func Parse(query string, now time.Time) (Expr, error) {
+	tokens, err := tokenize(query)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("пустой фильтр")
+	}
+	p := parser{tokens: tokens, now: now}
+	expr, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("лишняя %q", p.tokens[p.pos].text)
+	}
+	return expr, nil
+}
+
+type tokenKind int
+
+const (
+	tokWord tokenKind = iota
+	tokPhrase
+	tokOpen
+	tokClose
+	tokMinus
+)
+
+type token struct {
+	kind tokenKind
+	text string
+}
+
+func tokenize(s string) ([]token, error) {
+	var tokens []token
+	runes := []rune(s)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case r == ' ' || r == '\t' || r == '\n':
+			i++
+		case r == '(':
+			tokens = append(tokens, token{kind: tokOpen, text: "("})
+			i++
+		case r == ')':
+			tokens = append(tokens, token{kind: tokClose, text: ")"})
+			i++
+		case r == '-' && i+1 < len(runes) && runes[i+1] != ' ':
+			tokens = append(tokens, token{kind: tokMinus, text: "-"})
+			i++
+		case r == '"':
+			end := indexRune(runes, i+1, '"')
+			if end < 0 {
+				return nil, fmt.Errorf("не закрыта кавычка")
+			}
+			tokens = append(tokens, token{kind: tokPhrase, text: string(runes[i+1 : end])})
+			i = end + 1
+		default:
+			// Слово до пробела или скобки; значение в кавычках (list:"Дом и сад") входит в слово
+			var b strings.Builder
+			for i < len(runes) && !strings.ContainsRune(" \t\n()", runes[i]) {
+				if runes[i] == '"' {
+					end := indexRune(runes, i+1, '"')
+					if end < 0 {
+						return nil, fmt.Errorf("не закрыта кавычка")
+					}
+					b.WriteString(string(runes[i+1 : end]))
+					i = end + 1
+					continue
+				}
+				b.WriteRune(runes[i])
+				i++
+			}
+			tokens = append(tokens, token{kind: tokWord, text: b.String()})
+		}
+	}
+	return tokens, nil
+}
+
+func indexRune(runes []rune, from int, r rune) int {
+	for i := from; i < len(runes); i++ {
+		if runes[i] == r {
+			return i
+		}
+	}
+	return -1
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+	now    time.Time
+}
+
+func (p *parser) peek() (token, bool) {
+	if p.pos >= len(p.tokens) {
+		return token{}, false
+	}
+	return p.tokens[p.pos], true
+}
+
+// isKeyword проверяет, что текущий токен - ключевое слово из words
+func (p *parser) isKeyword(words ...string) bool {
+	t, ok := p.peek()
+	if !ok || t.kind != tokWord {
+		return false
+	}
+	for _, w := range words {
+		if strings.EqualFold(t.text, w) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *parser) parseOr() (Expr, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.isKeyword("or", "или") {
+		p.pos++
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = Or{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (Expr, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		t, ok := p.peek()
+		if !ok || t.kind == tokClose || p.isKeyword("or", "или") {
+			return left, nil
+		}
+		if p.isKeyword("and", "и") {
+			p.pos++
+		}
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = And{left, right}
+	}
+}
+
+func (p *parser) parseUnary() (Expr, error) {
+	t, ok := p.peek()
+	if !ok {
+		return nil, fmt.Errorf("фильтр оборвался: ожидалось условие")
+	}
+	if t.kind == tokMinus || p.isKeyword("not", "не") {
+		p.pos++
+		x, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return Not{x}, nil
+	}
+
+	p.pos++
+	switch t.kind {
+	case tokOpen:
+		expr, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if next, ok := p.peek(); !ok || next.kind != tokClose {
+			return nil, fmt.Errorf("не закрыта скобка")
+		}
+		p.pos++
+		return expr, nil
+	case tokClose:
+		return nil, fmt.Errorf("лишняя закрывающая скобка")
+	case tokPhrase:
+		return Text{t.text}, nil
+	}
+	return p.parseTerm(t.text)
+}
+
+// parseTerm разбирает отдельное условие: поле:значение, поле<значение, #тег или слово
+func (p *parser) parseTerm(word string) (Expr, error) {
+	if strings.HasPrefix(word, "#") && len(word) > 1 {
+		return Tag{word[1:]}, nil
+	}
+
+	i := strings.IndexAny(word, ":<>=")
+	if i <= 0 {
+		return Text{word}, nil
+	}
+	key := strings.ToLower(word[:i])
+	op := word[i : i+1]
+	rest := word[i+1:]
+	if (op == "<" || op == ">") && strings.HasPrefix(rest, "=") {
+		op += "="
+		rest = rest[1:]
+	}
+	if op == "=" {
+		op = ":"
+	}
+	if rest == "" {
+		return nil, fmt.Errorf("не указано значение в %q", word)
+	}
+
+	switch key {
+	case "is":
+		if op != ":" {
+			return nil, fmt.Errorf("поле is поддерживает только ':'")
+		}
+		return p.parseIs(rest)
+	case "due":
+		return p.parseDue(op, rest)
+	case "list":
+		if op != ":" {
+			return nil, fmt.Errorf("поле list поддерживает только ':'")
+		}
+		return List{rest}, nil
+	case "tag":
+		if op != ":" {
+			return nil, fmt.Errorf("поле tag поддерживает только ':'")
+		}
+		return Tag{strings.TrimPrefix(rest, "#")}, nil
+	case "pri", "priority":
+		return parsePriority(op, rest)
+	case "text":
+		if op != ":" {
+			return nil, fmt.Errorf("поле text поддерживает только ':'")
+		}
+		return Text{rest}, nil
+	}
+	return nil, fmt.Errorf("неизвестное поле %q", key)
+}
+
+func (p *parser) parseIs(value string) (Expr, error) {
+	switch strings.ToLower(value) {
+	case "done":
+		return Done{}, nil
+	case "open":
+		return Not{Done{}}, nil
+	case "overdue":
+		// Как и в списке задач: срок уже прошёл, а задача не выполнена
+		return And{DueRange{To: p.now}, Not{Done{}}}, nil
+	case "archived":
+		return Archived{}, nil
+	}
+	return nil, fmt.Errorf("неизвестное значение is:%s", value)
+}
+
+func (p *parser) parseDue(op, value string) (Expr, error) {
+	today := startOfDay(p.now)
+	if op == ":" {
+		switch strings.ToLower(value) {
+		case "none":
+			return DueNone{}, nil
+		case "any":
+			return Not{DueNone{}}, nil
+		case "week":
+			// Неделя с понедельника
+			monday := today.AddDate(0, 0, -(int(today.Weekday())+6)%7)
+			return DueRange{From: monday, To: monday.AddDate(0, 0, 7)}, nil
+		case "month":
+			first := time.Date(today.Year(), today.Month(), 1, 0, 0, 0, 0, today.Location())
+			return DueRange{From: first, To: first.AddDate(0, 1, 0)}, nil
+		}
+	}
+
+	day, err := parseDay(value, today)
+	if err != nil {
+		return nil, err
+	}
+	next := day.AddDate(0, 0, 1)
+	switch op {
+	case "<":
+		return DueRange{To: day}, nil
+	case "<=":
+		return DueRange{To: next}, nil
+	case ">":
+		return DueRange{From: next}, nil
+	case ">=":
+		return DueRange{From: day}, nil
+	}
+	return DueRange{From: day, To: next}, nil
+}
+
+// parseDay разбирает день: today, tomorrow, yesterday, +3d, -2w, 2024-05-01 или 01.05.2024
+func parseDay(value string, today time.Time) (time.Time, error) {
+	switch strings.ToLower(value) {
+	case "today":
+		return today, nil
+	case "tomorrow":
+		return today.AddDate(0, 0, 1), nil
+	case "yesterday":
+		return today.AddDate(0, 0, -1), nil
+	}
+
+	if (value[0] == '+' || value[0] == '-') && len(value) > 2 {
+		n, err := strconv.Atoi(value[1 : len(value)-1])
+		if err == nil {
+			if value[0] == '-' {
+				n = -n
+			}
+			switch value[len(value)-1] {
+			case 'd':
+				return today.AddDate(0, 0, n), nil
+			case 'w':
+				return today.AddDate(0, 0, 7*n), nil
+			case 'm':
+				return today.AddDate(0, n, 0), nil
+			}
+		}
+	}
+
+	for _, layout := range []string{"2006-01-02", "02.01.2006"} {
+		if day, err := time.ParseInLocation(layout, value, today.Location()); err == nil {
+			return day, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("не удалось разобрать дату %q", value)
+}
+
+func startOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// parsePriority разбирает приоритет: A-Z или none; сравнения идут по букве, A < B
+func parsePriority(op, value string) (Expr, error) {
+	if strings.EqualFold(value, "none") {
+		if op != ":" {
+			return nil, fmt.Errorf("pri:none нельзя сравнивать")
+		}
+		return Priority{0, 0}, nil
+	}
+	if len(value) != 1 || strings.ToUpper(value)[0] < 'A' || strings.ToUpper(value)[0] > 'Z' {
+		return nil, fmt.Errorf("приоритет должен быть буквой A-Z или none, а не %q", value)
+	}
+	n := int(strings.ToUpper(value)[0]-'A') + 1
+	switch op {
+	case "<":
+		return Priority{1, n - 1}, nil
+	case "<=":
+		return Priority{1, n}, nil
+	case ">":
+		return Priority{n + 1, 26}, nil
+	case ">=":
+		return Priority{n, 26}, nil
+	}
+	return Priority{n, n}, nil
+}
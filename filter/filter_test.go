@@ -0,0 +1,162 @@
+package filter
+
+import (
+	"reflect"
+	"testing"
+	"time"
+	"todolist/models"
+)
+
+// Среда, 14.10.2026, 15:00
+var now = time.Date(2026, 10, 14, 15, 0, 0, 0, time.UTC)
+
+func day(month time.Month, d, hour int) time.Time {
+	return time.Date(2026, month, d, hour, 0, 0, 0, time.UTC)
+}
+
+// Задачи для проверки фильтров; срок без времени хранится как полночь
+var fixtures = []models.Task{
+	{Title: "Купить молоко", Description: "и хлеб"},
+	{Title: "Сдать отчёт", DueDate: day(10, 10, 0), Priority: 1, Tags: []string{"urgent"}},
+	{Title: "Позвонить врачу", DueDate: day(10, 14, 18), Priority: 2},
+	{Title: "Оплатить счёт", DueDate: day(10, 15, 0), IsDone: true},
+	{Title: "Встреча", DueDate: day(10, 20, 0), Tags: []string{"Работа"}},
+	{Title: "Отпуск", DueDate: day(11, 3, 0)},
+}
+
+func TestMatch(t *testing.T) {
+	list := &models.TodoList{Title: "Работа"}
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"due:none", []string{"Купить молоко"}},
+		{"due:any", []string{"Сдать отчёт", "Позвонить врачу", "Оплатить счёт", "Встреча", "Отпуск"}},
+		{"due:today", []string{"Позвонить врачу"}},
+		{"due:tomorrow", []string{"Оплатить счёт"}},
+		{"due:week", []string{"Позвонить врачу", "Оплатить счёт"}},
+		{"due:month", []string{"Сдать отчёт", "Позвонить врачу", "Оплатить счёт", "Встреча"}},
+		{"due:2026-10-20", []string{"Встреча"}},
+		{"due:03.11.2026", []string{"Отпуск"}},
+		{"due<today", []string{"Сдать отчёт"}},
+		{"due<=tomorrow", []string{"Сдать отчёт", "Позвонить врачу", "Оплатить счёт"}},
+		{"due>tomorrow", []string{"Встреча", "Отпуск"}},
+		{"due>=+6d", []string{"Встреча", "Отпуск"}},
+		{"due<-1w", nil},
+		// Отрицание сравнения сроков оставляет задачи без срока
+		{"-due<today", []string{"Купить молоко", "Позвонить врачу", "Оплатить счёт", "Встреча", "Отпуск"}},
+		{"due:none -is:done", []string{"Купить молоко"}},
+		{"is:overdue", []string{"Сдать отчёт"}},
+		{"is:done", []string{"Оплатить счёт"}},
+		{"is:open due<+7d", []string{"Сдать отчёт", "Позвонить врачу", "Встреча"}},
+		{"not (is:done or due:none) and due<+1w", []string{"Сдать отчёт", "Позвонить врачу", "Встреча"}},
+		{"#urgent or pri:B", []string{"Сдать отчёт", "Позвонить врачу"}},
+		{"pri<=b", []string{"Сдать отчёт", "Позвонить врачу"}},
+		{"pri>A", []string{"Позвонить врачу"}},
+		{"pri:none -due:none", []string{"Оплатить счёт", "Встреча", "Отпуск"}},
+		{"tag:работа", []string{"Встреча"}},
+		{"ХЛЕБ", []string{"Купить молоко"}},
+		{`"купить молоко"`, []string{"Купить молоко"}},
+		{"text:счёт или молоко", []string{"Купить молоко", "Оплатить счёт"}},
+		{`list:"работа" встреча`, []string{"Встреча"}},
+		{"list:Дом", nil},
+	}
+
+	for _, tt := range tests {
+		expr, err := Parse(tt.query, now)
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", tt.query, err)
+		}
+		var got []string
+		for i := range fixtures {
+			if expr.Match(&fixtures[i], list) {
+				got = append(got, fixtures[i].Title)
+			}
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%q: got %q, want %q", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestArchived(t *testing.T) {
+	task := &fixtures[0]
+	active := &models.TodoList{Title: "Работа"}
+	archived := &models.TodoList{Title: "Работа", ArchivedAt: now}
+
+	tests := []struct {
+		query            string
+		mentions         bool
+		active, archived bool
+	}{
+		{"молоко", false, true, true},
+		{"is:archived", true, false, true},
+		{"молоко -is:archived", true, true, false},
+		{"is:done or (молоко is:archived)", true, false, true},
+	}
+	for _, tt := range tests {
+		expr, err := Parse(tt.query, now)
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", tt.query, err)
+		}
+		if got := MentionsArchived(expr); got != tt.mentions {
+			t.Errorf("MentionsArchived(%q) = %v, want %v", tt.query, got, tt.mentions)
+		}
+		if got := expr.Match(task, active); got != tt.active {
+			t.Errorf("%q в активном списке: got %v, want %v", tt.query, got, tt.active)
+		}
+		if got := expr.Match(task, archived); got != tt.archived {
+			t.Errorf("%q в архивном списке: got %v, want %v", tt.query, got, tt.archived)
+		}
+	}
+}
+
+func TestParseDay(t *testing.T) {
+	today := startOfDay(now)
+	tests := []struct {
+		value string
+		want  time.Time
+	}{
+		{"today", today},
+		{"Tomorrow", day(10, 15, 0)},
+		{"yesterday", day(10, 13, 0)},
+		{"+3d", day(10, 17, 0)},
+		{"-2w", day(9, 30, 0)},
+		{"+1m", day(11, 14, 0)},
+		{"2026-12-31", day(12, 31, 0)},
+		{"01.05.2026", day(5, 1, 0)},
+	}
+	for _, tt := range tests {
+		got, err := parseDay(tt.value, today)
+		if err != nil {
+			t.Fatalf("parseDay(%q): %v", tt.value, err)
+		}
+		if !got.Equal(tt.want) {
+			t.Errorf("parseDay(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, query := range []string{
+		"",
+		"   ",
+		"(is:done",
+		"is:done)",
+		`"без конца`,
+		`list:"без конца`,
+		"is:late",
+		"is<done",
+		"due:someday",
+		"due:",
+		"pri:AA",
+		"pri<none",
+		"color:red",
+		"is:done or",
+		"not",
+	} {
+		if _, err := Parse(query, now); err == nil {
+			t.Errorf("Parse(%q) returned no error", query)
+		}
+	}
+}
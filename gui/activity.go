@@ -0,0 +1,47 @@
+// activity.go
+package gui
+
+import (
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/driver/desktop"
+	"fyne.io/fyne/v2/widget"
+)
+
+// activityArea оборачивает содержимое окна и отмечает движения и нажатия мыши как активность
+// для автоблокировки. События получает только та часть окна, где нет своего обработчика мыши
+// (кнопки и строки списков забирают их себе), но курсор редко долго стоит над одной кнопкой.
+type activityArea struct {
+	widget.BaseWidget
+	content fyne.CanvasObject
+}
+
+func newActivityArea(content fyne.CanvasObject) *activityArea {
+	a := &activityArea{content: content}
+	a.ExtendBaseWidget(a)
+	return a
+}
+
+func (a *activityArea) CreateRenderer() fyne.WidgetRenderer {
+	return widget.NewSimpleRenderer(a.content)
+}
+
+func (a *activityArea) MouseIn(*desktop.MouseEvent)    { touchActivity() }
+func (a *activityArea) MouseMoved(*desktop.MouseEvent) { touchActivity() }
+func (a *activityArea) MouseOut()                      {}
+func (a *activityArea) MouseDown(*desktop.MouseEvent)  { touchActivity() }
+func (a *activityArea) MouseUp(*desktop.MouseEvent)    {}
+
+// setContent показывает экран в окне, обернув его в activityArea
+func setContent(w fyne.Window, content fyne.CanvasObject) {
+	w.SetContent(newActivityArea(content))
+}
+
+// runOnUI выполняет f в очереди событий окна, где fyne вызывает все обработчики ввода,
+// чтобы фоновые проверки не гонялись с ними за общее состояние (currentUserID, экраны)
+func runOnUI(w fyne.Window, f func()) {
+	if q, ok := w.(interface{ QueueEvent(func()) }); ok {
+		q.QueueEvent(f)
+		return
+	}
+	f()
+}
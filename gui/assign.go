@@ -0,0 +1,185 @@
+// assign.go
+package gui
+
+import (
+	"fmt"
+	"todolist/db"
+	"todolist/models"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/widget"
+)
+
+const noAssignee = "—"
+
+// announcedUnread - сколько непрочитанных уведомлений уже показано системным уведомлением
+var announcedUnread = make(map[int]int)
+
+// newAssigneeSelect - выбор исполнителя среди владельца и участников списка.
+// Возвращает виджет и функцию, отдающую ID выбранного пользователя (0 - не назначен).
+func newAssigneeSelect(list models.TodoList, assigneeID int) (*widget.Select, func() int) {
+	options := []string{noAssignee}
+	ids := map[string]int{noAssignee: 0}
+
+	userIDs, err := db.GetListUsers(list.ID)
+	if err != nil {
+		userIDs = []int{currentUserID}
+	}
+	selected := noAssignee
+	for _, id := range userIDs {
+		option := getUserName(id)
+		if id == currentUserID {
+			option += " (я)"
+		}
+		if _, taken := ids[option]; taken {
+			option = fmt.Sprintf("%s (ID %d)", option, id)
+		}
+		options = append(options, option)
+		ids[option] = id
+		if id == assigneeID {
+			selected = option
+		}
+	}
+
+	sel := widget.NewSelect(options, nil)
+	sel.SetSelected(selected)
+	return sel, func() int {
+		return ids[sel.Selected]
+	}
+}
+
+// ShowAssignedTasks - задачи, назначенные пользователю, из всех доступных ему списков
+func ShowAssignedTasks(w fyne.Window, userID int) {
+	tasks, err := db.GetAssignedTasks(userID)
+	if err != nil {
+		dialog.ShowError(fmt.Errorf("Ошибка загрузки задач: %v", err), w)
+		return
+	}
+	lists, err := db.GetTodoLists(userID)
+	if err != nil {
+		dialog.ShowError(fmt.Errorf("Ошибка загрузки списков: %v", err), w)
+		return
+	}
+	byID := make(map[int]models.TodoList, len(lists))
+	for _, l := range lists {
+		byID[l.ID] = l
+	}
+
+	tasksContainer := container.NewVBox()
+	for i := range tasks {
+		task := &tasks[i]
+		list := byID[task.ListID]
+		tasksContainer.Add(widget.NewLabelWithStyle(list.Title, fyne.TextAlignLeading, fyne.TextStyle{Italic: true}))
+		tasksContainer.Add(createTaskRow(w, task, list))
+	}
+	if len(tasks) == 0 {
+		tasksContainer.Add(widget.NewLabel("Вам пока не назначено ни одной задачи"))
+	}
+
+	backButton := widget.NewButton("← Назад", func() {
+		ShowTodoLists(w, userID)
+	})
+
+	w.SetOnDropped(nil)
+	setContent(w, container.NewBorder(
+		widget.NewLabelWithStyle("Назначенные мне", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+		container.NewHBox(backButton, layout.NewSpacer()),
+		nil, nil,
+		container.NewVScroll(tasksContainer),
+	))
+}
+
+func notificationText(n models.Notification) string {
+	switch n.Kind {
+	case models.NotifyAssigned:
+		return fmt.Sprintf("%s назначил(а) вам задачу «%s»", getUserName(n.ActorID), n.TaskTitle)
+	case models.NotifyCommented:
+		return fmt.Sprintf("%s прокомментировал(а) задачу «%s»", getUserName(n.ActorID), n.TaskTitle)
+	}
+	return fmt.Sprintf("Событие с задачей «%s»", n.TaskTitle)
+}
+
+// newNotificationsButton - кнопка с числом непрочитанных уведомлений.
+// О новых уведомлениях также сообщает системное уведомление.
+func newNotificationsButton(w fyne.Window, userID int) *widget.Button {
+	unread, err := db.CountUnreadNotifications(userID)
+	if err != nil {
+		unread = 0
+	}
+
+	label := "🔔"
+	if unread > 0 {
+		label = fmt.Sprintf("🔔 %d", unread)
+		// Системное уведомление - только когда появилось что-то новое с прошлого показа
+		if app := fyne.CurrentApp(); app != nil && unread > announcedUnread[userID] {
+			app.SendNotification(fyne.NewNotification("My Tasks",
+				fmt.Sprintf("%s: новых уведомлений - %d", getUserName(userID), unread)))
+		}
+	}
+	announcedUnread[userID] = unread
+
+	btn := widget.NewButton(label, func() {
+		showNotificationsDialog(w, userID)
+	})
+	if unread > 0 {
+		btn.Importance = widget.HighImportance
+	}
+	return btn
+}
+
+func showNotificationsDialog(w fyne.Window, userID int) {
+	notifications, err := db.GetNotifications(userID, false)
+	if err != nil {
+		dialog.ShowError(fmt.Errorf("Ошибка загрузки уведомлений: %v", err), w)
+		return
+	}
+	lists, err := db.GetTodoLists(userID)
+	if err != nil {
+		dialog.ShowError(fmt.Errorf("Ошибка загрузки списков: %v", err), w)
+		return
+	}
+	byID := make(map[int]models.TodoList, len(lists))
+	for _, l := range lists {
+		byID[l.ID] = l
+	}
+
+	var d dialog.Dialog
+	items := container.NewVBox()
+	for _, n := range notifications {
+		current := n
+		text := notificationText(current)
+		if current.ReadAt.IsZero() {
+			text = "● " + text
+		}
+		label := widget.NewLabel(current.CreatedAt.Format(backupTimeFormat) + "\n" + text)
+		label.Wrapping = fyne.TextWrapWord
+
+		var openBtn fyne.CanvasObject = layout.NewSpacer()
+		if list, ok := byID[current.ListID]; ok {
+			openBtn = widget.NewButton("Открыть", func() {
+				d.Hide()
+				ShowTodoItems(w, list)
+			})
+		}
+		items.Add(container.NewBorder(nil, nil, nil, openBtn, label))
+	}
+	if len(notifications) == 0 {
+		items.Add(widget.NewLabel("Уведомлений нет"))
+	}
+
+	scroll := container.NewVScroll(items)
+	scroll.SetMinSize(fyne.NewSize(360, 300))
+	d = dialog.NewCustom("Уведомления", "Закрыть", scroll, w)
+	d.SetOnClosed(func() {
+		if err := db.MarkNotificationsRead(userID); err != nil {
+			dialog.ShowError(err, w)
+			return
+		}
+		// Обновляем счётчик на кнопке
+		ShowTodoLists(w, userID)
+	})
+	d.Show()
+}
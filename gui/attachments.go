@@ -0,0 +1,159 @@
+// attachments.go
+package gui
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"todolist/db"
+	"todolist/models"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/storage"
+	"fyne.io/fyne/v2/widget"
+)
+
+const thumbnailSize = 48
+
+// newAttachmentsSection - вложения задачи с миниатюрами и кнопками открыть/сохранить/удалить.
+// Возвращает виджет и функцию для прикрепления файлов по URI (используется при перетаскивании).
+func newAttachmentsSection(w fyne.Window, task *models.Task, list models.TodoList) (fyne.CanvasObject, func([]fyne.URI)) {
+	items := container.NewVBox()
+
+	var refresh func()
+	refresh = func() {
+		attachments, err := db.GetAttachments(task.ID)
+		if err != nil {
+			dialog.ShowError(fmt.Errorf("Ошибка загрузки вложений: %v", err), w)
+			return
+		}
+		items.RemoveAll()
+		for _, a := range attachments {
+			items.Add(attachmentRow(w, a, list, refresh))
+		}
+	}
+	refresh()
+
+	attach := func(uris []fyne.URI) {
+		for _, uri := range uris {
+			in, err := storage.Reader(uri)
+			if err != nil {
+				dialog.ShowError(err, w)
+				continue
+			}
+			err = attachFile(task, uri.Name(), in)
+			in.Close()
+			if err != nil {
+				dialog.ShowError(err, w)
+			}
+		}
+		refresh()
+	}
+
+	attachBtn := widget.NewButton("📎 Прикрепить файл…", func() {
+		showOpenFileDialog(w, nil, func(in fyne.URIReadCloser) error {
+			if err := attachFile(task, in.URI().Name(), in); err != nil {
+				return err
+			}
+			refresh()
+			return nil
+		})
+	})
+	if !list.Role.CanEdit() {
+		attachBtn.Hide()
+	}
+
+	return container.NewVBox(items, attachBtn), attach
+}
+
+// attachFile читает файл не больше допустимого размера и прикрепляет его к задаче
+func attachFile(task *models.Task, name string, in io.Reader) error {
+	limit := db.AttachmentMaxSize()
+	data, err := io.ReadAll(io.LimitReader(in, limit+1))
+	if err != nil {
+		return err
+	}
+	if int64(len(data)) > limit {
+		return fmt.Errorf("файл %s больше допустимых %d МБ", name, limit>>20)
+	}
+	_, err = db.AddAttachment(currentUserID, task.ID, name, data)
+	return err
+}
+
+func attachmentRow(w fyne.Window, a models.Attachment, list models.TodoList, refresh func()) fyne.CanvasObject {
+	var icon fyne.CanvasObject = widget.NewLabel("📄")
+	if a.IsImage() {
+		if data, err := db.ReadAttachment(a); err == nil {
+			img := canvas.NewImageFromReader(bytes.NewReader(data), a.Name)
+			img.FillMode = canvas.ImageFillContain
+			img.SetMinSize(fyne.NewSize(thumbnailSize, thumbnailSize))
+			icon = img
+		}
+	}
+
+	label := widget.NewLabel(fmt.Sprintf("%s (%s)", a.Name, formatSize(a.Size)))
+	label.Truncation = fyne.TextTruncateEllipsis
+
+	openBtn := widget.NewButton("Открыть", func() {
+		if err := openAttachment(a); err != nil {
+			dialog.ShowError(err, w)
+		}
+	})
+	saveBtn := widget.NewButton("Сохранить…", func() {
+		showSaveFileDialog(w, a.Name, nil, func(out io.Writer) error {
+			data, err := db.ReadAttachment(a)
+			if err != nil {
+				return err
+			}
+			_, err = out.Write(data)
+			return err
+		})
+	})
+	actions := container.NewHBox(openBtn, saveBtn)
+	if list.Role.CanEdit() {
+		actions.Add(widget.NewButton("✕", func() {
+			showDeleteConfirmDialog(w, "Удаление вложения", "Удалить файл "+a.Name+"?", func() {
+				if err := db.DeleteAttachment(currentUserID, a.ID); err != nil {
+					dialog.ShowError(err, w)
+					return
+				}
+				refresh()
+			})
+		}))
+	}
+
+	return container.NewBorder(nil, nil, icon, actions, label)
+}
+
+// openAttachment выкладывает вложение во временный каталог и открывает его системной программой
+func openAttachment(a models.Attachment) error {
+	data, err := db.ReadAttachment(a)
+	if err != nil {
+		return err
+	}
+	dir := filepath.Join(os.TempDir(), "todolist-attachments", a.Hash[:12])
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	path := filepath.Join(dir, filepath.Base(a.Name))
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return err
+	}
+	return fyne.CurrentApp().OpenURL(&url.URL{Scheme: "file", Path: filepath.ToSlash(path)})
+}
+
+func formatSize(size int64) string {
+	switch {
+	case size >= 1<<20:
+		return fmt.Sprintf("%.1f МБ", float64(size)/(1<<20))
+	case size >= 1<<10:
+		return fmt.Sprintf("%d КБ", (size+1023)>>10)
+	}
+	return fmt.Sprintf("%d Б", size)
+}
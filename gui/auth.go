@@ -0,0 +1,227 @@
+// auth.go
+package gui
+
+import (
+	"fmt"
+	"sync"
+	"time"
+	"todolist/auth"
+	"todolist/db"
+	"todolist/models"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/driver/desktop"
+	"fyne.io/fyne/v2/widget"
+)
+
+const (
+	// После стольких неудачных попыток вход временно блокируется
+	maxLoginFailures = 5
+	loginLockout     = 30 * time.Second
+)
+
+var (
+	loginMu       sync.Mutex
+	loginFailures = make(map[int]int)
+	loginLocked   = make(map[int]time.Time)
+)
+
+// openUser открывает задачи пользователя на сегодня, при необходимости запросив пароль.
+// Тем же паролем открывается ключ шифрования данных пользователя.
+func openUser(w fyne.Window, user models.User) {
+	if !user.Protected {
+		ShowAgenda(w, user.ID, viewToday)
+		return
+	}
+	confirmUserPassword(w, user.ID, "Вход: "+getUserName(user.ID), func(secret string) {
+		// Ключ открываем до показа задач, чтобы они сразу были расшифрованы
+		err := db.UnlockUserKey(user.ID, secret)
+		ShowAgenda(w, user.ID, viewToday)
+		if err != nil {
+			dialog.ShowError(fmt.Errorf("Не удалось открыть ключ шифрования: %v", err), w)
+		}
+	})
+}
+
+// confirmUserPassword запрашивает пароль пользователя и передаёт его в onSuccess, если он верен.
+// Если пароль не задан, onSuccess вызывается сразу с пустой строкой.
+func confirmUserPassword(w fyne.Window, userID int, title string, onSuccess func(secret string)) {
+	hash, err := db.GetUserPasswordHash(userID)
+	if err != nil {
+		dialog.ShowError(err, w)
+		return
+	}
+	confirmSecret(w, userID, hash, title, onSuccess)
+}
+
+// confirmSecret запрашивает пароль, подходящий к хешу hash; пустой hash - пароль не нужен
+func confirmSecret(w fyne.Window, userID int, hash, title string, onSuccess func(secret string)) {
+	if hash == "" {
+		onSuccess("")
+		return
+	}
+
+	entry := widget.NewPasswordEntry()
+	entry.SetPlaceHolder("Пароль или PIN")
+
+	var d dialog.Dialog
+	check := func() {
+		if wait := loginWait(userID); wait > 0 {
+			dialog.ShowError(fmt.Errorf("слишком много неудачных попыток, подождите %d с", int(wait.Seconds())+1), w)
+			return
+		}
+		if !auth.VerifySecret(entry.Text, hash) {
+			loginFailed(userID)
+			entry.SetText("")
+			dialog.ShowError(fmt.Errorf("неверный пароль"), w)
+			return
+		}
+		loginSucceeded(userID)
+		d.Hide()
+		onSuccess(entry.Text)
+	}
+
+	d = dialog.NewCustomConfirm(title, "Продолжить", "Отмена", entry, func(ok bool) {
+		if ok {
+			check()
+		}
+	}, w)
+	addEnterHandler(entry, check)
+	d.Resize(fyne.NewSize(320, 150))
+	d.Show()
+	w.Canvas().Focus(entry)
+}
+
+func loginWait(userID int) time.Duration {
+	loginMu.Lock()
+	defer loginMu.Unlock()
+	return time.Until(loginLocked[userID])
+}
+
+func loginFailed(userID int) {
+	loginMu.Lock()
+	defer loginMu.Unlock()
+	loginFailures[userID]++
+	if loginFailures[userID] >= maxLoginFailures {
+		loginFailures[userID] = 0
+		loginLocked[userID] = time.Now().Add(loginLockout)
+	}
+}
+
+func loginSucceeded(userID int) {
+	loginMu.Lock()
+	defer loginMu.Unlock()
+	delete(loginFailures, userID)
+	delete(loginLocked, userID)
+}
+
+// showPasswordDialog задаёт, меняет или снимает пароль пользователя.
+// Текущий пароль проверяется заранее через confirmUserPassword.
+func showPasswordDialog(w fyne.Window, userID int) {
+	confirmUserPassword(w, userID, "Текущий пароль", func(oldSecret string) {
+		newEntry := widget.NewPasswordEntry()
+		newEntry.SetPlaceHolder("Пусто - снять защиту")
+		repeatEntry := widget.NewPasswordEntry()
+
+		dialog.ShowForm("Пароль или PIN", "Сохранить", "Отмена", []*widget.FormItem{
+			widget.NewFormItem("Новый:", newEntry),
+			widget.NewFormItem("Ещё раз:", repeatEntry),
+		}, func(ok bool) {
+			if !ok {
+				return
+			}
+			if newEntry.Text != repeatEntry.Text {
+				dialog.ShowError(fmt.Errorf("пароли не совпадают"), w)
+				return
+			}
+
+			var hash string
+			if newEntry.Text != "" {
+				if err := auth.CheckSecret(newEntry.Text); err != nil {
+					dialog.ShowError(err, w)
+					return
+				}
+				var err error
+				if hash, err = auth.HashSecret(newEntry.Text); err != nil {
+					dialog.ShowError(err, w)
+					return
+				}
+			}
+			if err := db.SetUserPassword(userID, hash, oldSecret, newEntry.Text); err != nil {
+				dialog.ShowError(err, w)
+				return
+			}
+
+			message := "Пароль сохранён"
+			if hash == "" {
+				message = "Защита паролем снята"
+			}
+			dialog.ShowInformation("Пароль", message, w)
+		}, w)
+	})
+}
+
+// Учёт активности для автоблокировки
+var (
+	activityMu   sync.Mutex
+	lastActivity = time.Now()
+)
+
+func touchActivity() {
+	activityMu.Lock()
+	lastActivity = time.Now()
+	activityMu.Unlock()
+}
+
+func idleFor() time.Duration {
+	activityMu.Lock()
+	defer activityMu.Unlock()
+	return time.Since(lastActivity)
+}
+
+// StartAutoLock возвращает к выбору пользователя после бездействия (см. auth.LockTimeout).
+// Активностью считаются нажатия клавиш, движения и щелчки мыши (см. activityArea),
+// смена экрана и открытие или закрытие диалогов.
+func StartAutoLock(w fyne.Window) {
+	timeout := auth.LockTimeout()
+	if timeout == 0 {
+		return
+	}
+
+	if dc, ok := w.Canvas().(desktop.Canvas); ok {
+		dc.SetOnKeyDown(func(*fyne.KeyEvent) { touchActivity() })
+	}
+
+	var (
+		content fyne.CanvasObject
+		overlay fyne.CanvasObject
+	)
+	check := func() {
+		if c, o := w.Content(), w.Canvas().Overlays().Top(); c != content || o != overlay {
+			content, overlay = c, o
+			touchActivity()
+			return
+		}
+		if currentUserID != 0 && idleFor() >= timeout {
+			lock(w)
+		}
+	}
+	go func() {
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+		for range ticker.C {
+			// Проверка идёт в очереди событий окна, вместе с обработчиками, которые меняют экран и currentUserID
+			runOnUI(w, check)
+		}
+	}()
+}
+
+// lock закрывает открытые диалоги и возвращает к выбору пользователя
+func lock(w fyne.Window) {
+	for _, o := range w.Canvas().Overlays().List() {
+		w.Canvas().Overlays().Remove(o)
+	}
+	ShowUserSelection(w)
+	touchActivity()
+}
@@ -0,0 +1,229 @@
+// backup.go
+package gui
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"todolist/backup"
+	"todolist/db"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+const backupTimeFormat = "02.01.2006 15:04"
+
+// UserNames возвращает копию отображаемых имён пользователей для резервных копий
+func UserNames() map[int]string {
+	userNamesMu.RLock()
+	defer userNamesMu.RUnlock()
+	names := make(map[int]string, len(userNames))
+	for id, name := range userNames {
+		names[id] = name
+	}
+	return names
+}
+
+// showBackupDialog показывает снимки и позволяет создать новый или восстановить данные.
+// userID = 0 - все пользователи (экран выбора пользователя), иначе только данные этого пользователя.
+func showBackupDialog(w fyne.Window, userID int) {
+	backupsContainer := container.NewVBox()
+	var d dialog.Dialog
+
+	var refresh func()
+	refresh = func() {
+		infos, err := backup.List()
+		if err != nil {
+			dialog.ShowError(fmt.Errorf("Ошибка чтения каталога резервных копий: %v", err), w)
+			return
+		}
+
+		backupsContainer.RemoveAll()
+		for _, info := range infos {
+			currentInfo := info
+			// Пользователю доступны полные снимки и его собственные
+			if userID != 0 && currentInfo.UserID != 0 && currentInfo.UserID != userID {
+				continue
+			}
+
+			scope := "Все пользователи"
+			if currentInfo.UserID != 0 {
+				scope = getUserName(currentInfo.UserID)
+			}
+			label := widget.NewLabel(fmt.Sprintf("%s — %s, %d КБ",
+				currentInfo.CreatedAt.Format(backupTimeFormat), scope, (currentInfo.Size+1023)/1024))
+
+			restoreBtn := widget.NewButton("Восстановить…", func() {
+				d.Hide()
+				showRestoreDialog(w, currentInfo, userID)
+			})
+			backupsContainer.Add(container.NewBorder(nil, nil, nil, restoreBtn, label))
+		}
+		if len(backupsContainer.Objects) == 0 {
+			backupsContainer.Add(widget.NewLabel("Резервных копий пока нет"))
+		}
+	}
+	refresh()
+
+	createBtn := widget.NewButton("Создать копию сейчас", func() {
+		if _, err := backup.Create(userID, UserNames()); err != nil {
+			dialog.ShowError(fmt.Errorf("Ошибка резервного копирования: %v", err), w)
+			return
+		}
+		refresh()
+	})
+
+	hint := widget.NewLabel(fmt.Sprintf("Каталог: %s. Хранятся %d последних копий каждого вида.", backup.Dir(), backup.Keep()))
+	hint.Wrapping = fyne.TextWrapWord
+
+	scroll := container.NewVScroll(backupsContainer)
+	scroll.SetMinSize(fyne.NewSize(360, 250))
+
+	content := container.NewBorder(hint, createBtn, nil, nil, scroll)
+	d = dialog.NewCustom("Резервные копии", "Закрыть", content, w)
+	d.Resize(fyne.NewSize(420, 420))
+	d.Show()
+}
+
+// snapshotRow - поля строк users и todo_lists, нужные для выбора, что восстанавливать
+type snapshotRow struct {
+	ID           int    `json:"id"`
+	UserID       int    `json:"user_id"`
+	Title        string `json:"title"`
+	PasswordHash string `json:"password_hash"`
+}
+
+func snapshotRows(snapshot *backup.Snapshot, table string) []snapshotRow {
+	var rows []snapshotRow
+	for _, raw := range snapshot.Tables[table] {
+		var row snapshotRow
+		if err := json.Unmarshal(raw, &row); err == nil {
+			rows = append(rows, row)
+		}
+	}
+	return rows
+}
+
+// showRestoreDialog восстанавливает из снимка данные пользователя целиком или один его список.
+// onlyUserID != 0 ограничивает выбор этим пользователем.
+func showRestoreDialog(w fyne.Window, info backup.Info, onlyUserID int) {
+	snapshot, err := backup.Load(info.Path)
+	if err != nil {
+		dialog.ShowError(err, w)
+		return
+	}
+
+	snapshotName := func(id int) string {
+		if name, ok := snapshot.Names[id]; ok {
+			return name
+		}
+		return getUserName(id)
+	}
+
+	var (
+		userOptions []string
+		userIDs     = make(map[string]int)
+		savedHashes = make(map[int]string)
+	)
+	for _, u := range snapshotRows(snapshot, "users") {
+		if onlyUserID != 0 && u.ID != onlyUserID {
+			continue
+		}
+		option := fmt.Sprintf("%s (ID %d)", snapshotName(u.ID), u.ID)
+		userOptions = append(userOptions, option)
+		userIDs[option] = u.ID
+		savedHashes[u.ID] = u.PasswordHash
+	}
+	if len(userOptions) == 0 {
+		dialog.ShowInformation("Восстановление", "В этой резервной копии нет подходящих пользователей", w)
+		return
+	}
+
+	const allLists = "Все списки пользователя"
+	lists := snapshotRows(snapshot, "todo_lists")
+	listIDs := make(map[string]int)
+	listSelect := widget.NewSelect(nil, nil)
+	userSelect := widget.NewSelect(userOptions, func(option string) {
+		options := []string{allLists}
+		for _, l := range lists {
+			if l.UserID == userIDs[option] {
+				listOption := fmt.Sprintf("%s (ID %d)", l.Title, l.ID)
+				options = append(options, listOption)
+				listIDs[listOption] = l.ID
+			}
+		}
+		listSelect.Options = options
+		listSelect.SetSelected(allLists)
+	})
+	userSelect.SetSelected(userOptions[0])
+
+	form := []*widget.FormItem{
+		widget.NewFormItem("Пользователь", userSelect),
+		widget.NewFormItem("Что восстановить", listSelect),
+	}
+	title := "Восстановление из копии от " + info.CreatedAt.Format(backupTimeFormat)
+	dialog.ShowForm(title, "Восстановить", "Отмена", form, func(ok bool) {
+		if !ok {
+			return
+		}
+		restoreUserID := userIDs[userSelect.Selected]
+		listID := listIDs[listSelect.Selected]
+
+		message := fmt.Sprintf("Текущие данные пользователя %s будут заменены данными из копии. Продолжить?", snapshotName(restoreUserID))
+		if listSelect.Selected != allLists {
+			message = fmt.Sprintf("Текущее содержимое списка %s будет заменено данными из копии. Продолжить?", listSelect.Selected)
+		}
+		// С экрана выбора пользователя данные защищённого пользователя восстанавливаются только по паролю
+		restore := func(string) {
+			if listSelect.Selected != allLists {
+				err = db.RestoreList(snapshot.Tables, listID)
+			} else {
+				err = db.RestoreUser(snapshot.Tables, restoreUserID)
+			}
+			if err != nil {
+				dialog.ShowError(fmt.Errorf("Ошибка восстановления: %v", err), w)
+				return
+			}
+
+			if name, ok := snapshot.Names[restoreUserID]; ok && listSelect.Selected == allLists {
+				userNamesMu.Lock()
+				userNames[restoreUserID] = name
+				userNamesMu.Unlock()
+				saveUserNames()
+			}
+
+			if enabled, _ := db.EncryptionEnabled(restoreUserID); enabled && listSelect.Selected == allLists {
+				// Вместе с данными восстановлен и ключ шифрования - его нужно открыть заново
+				ShowUserSelection(w)
+				dialog.ShowInformation("Восстановление", "Данные восстановлены. Войдите снова, чтобы открыть зашифрованные данные.", w)
+				return
+			}
+			if onlyUserID != 0 {
+				ShowTodoLists(w, onlyUserID)
+			} else {
+				ShowUserSelection(w)
+			}
+			dialog.ShowInformation("Восстановление", "Данные восстановлены", w)
+		}
+		showDeleteConfirmDialog(w, "Восстановление", message, func() {
+			if onlyUserID != 0 {
+				restore("")
+				return
+			}
+			// Пароль живого пользователя защищает его текущие данные; удалённого после
+			// снимка пользователя защищает пароль, сохранённый в самом снимке
+			hash, err := db.GetUserPasswordHash(restoreUserID)
+			if errors.Is(err, db.ErrUserNotFound) {
+				hash, err = savedHashes[restoreUserID], nil
+			}
+			if err != nil {
+				dialog.ShowError(err, w)
+				return
+			}
+			confirmSecret(w, restoreUserID, hash, "Подтвердите восстановление паролем", restore)
+		})
+	}, w)
+}
@@ -0,0 +1,302 @@
+// calendar.go
+package gui
+
+import (
+	"fmt"
+	"time"
+	"todolist/db"
+	"todolist/filter"
+	"todolist/models"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+type calendarMode int
+
+const (
+	calendarMonth calendarMode = iota
+	calendarWeek
+)
+
+// В клетке месяца показываются не все задачи, остальные - в недельном виде
+const monthCellTasks = 4
+
+var monthNames = [...]string{"Январь", "Февраль", "Март", "Апрель", "Май", "Июнь",
+	"Июль", "Август", "Сентябрь", "Октябрь", "Ноябрь", "Декабрь"}
+
+// taskChip - задача в клетке календаря: нажатие открывает её, перетаскивание на другой день переносит срок
+type taskChip struct {
+	widget.BaseWidget
+	background *canvas.Rectangle
+	label      *widget.Label
+	onTap      func()
+	onDrop     func(pos fyne.Position)
+	dropPos    fyne.Position
+	dragging   bool
+}
+
+func newTaskChip(text string, onTap func(), onDrop func(fyne.Position)) *taskChip {
+	label := widget.NewLabel(text)
+	label.Truncation = fyne.TextTruncateEllipsis
+	chip := &taskChip{
+		background: canvas.NewRectangle(theme.Color(theme.ColorNameInputBackground)),
+		label:      label,
+		onTap:      onTap,
+		onDrop:     onDrop,
+	}
+	chip.background.CornerRadius = theme.InputRadiusSize()
+	chip.ExtendBaseWidget(chip)
+	return chip
+}
+
+func (c *taskChip) CreateRenderer() fyne.WidgetRenderer {
+	return widget.NewSimpleRenderer(container.NewStack(c.background, c.label))
+}
+
+func (c *taskChip) Tapped(*fyne.PointEvent) {
+	c.onTap()
+}
+
+func (c *taskChip) Dragged(e *fyne.DragEvent) {
+	if !c.dragging {
+		c.dragging = true
+		c.background.FillColor = theme.Color(theme.ColorNameFocus)
+		c.background.Refresh()
+	}
+	c.dropPos = e.AbsolutePosition
+}
+
+func (c *taskChip) DragEnd() {
+	if !c.dragging {
+		return
+	}
+	c.dragging = false
+	c.background.FillColor = theme.Color(theme.ColorNameInputBackground)
+	c.background.Refresh()
+	c.onDrop(c.dropPos)
+}
+
+// calendarCell - клетка дня, нужна для поиска дня под отпущенной задачей
+type calendarCell struct {
+	day time.Time
+	obj fyne.CanvasObject
+}
+
+// calendarRange возвращает первый день и день после последнего для вида с опорной датой anchor
+func calendarRange(mode calendarMode, anchor time.Time) (time.Time, time.Time) {
+	day := time.Date(anchor.Year(), anchor.Month(), anchor.Day(), 0, 0, 0, 0, time.Local)
+	if mode == calendarWeek {
+		monday := day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+		return monday, monday.AddDate(0, 0, 7)
+	}
+	first := time.Date(day.Year(), day.Month(), 1, 0, 0, 0, 0, time.Local)
+	start := first.AddDate(0, 0, -(int(first.Weekday())+6)%7)
+	last := first.AddDate(0, 1, -1)
+	end := last.AddDate(0, 0, 7-(int(last.Weekday())+6)%7)
+	return start, end
+}
+
+// ShowCalendar показывает задачи всех списков пользователя на их сроках по месяцам или неделям
+func ShowCalendar(w fyne.Window, userID int, mode calendarMode, anchor time.Time) {
+	currentUserID = userID
+	start, end := calendarRange(mode, anchor)
+
+	tasks, err := db.FilterTasks(userID, filter.DueRange{From: start, To: end})
+	if err != nil {
+		dialog.ShowError(fmt.Errorf("Ошибка загрузки задач: %v", err), w)
+		return
+	}
+	lists, err := db.GetTodoLists(userID)
+	if err != nil {
+		dialog.ShowError(fmt.Errorf("Ошибка загрузки списков: %v", err), w)
+		return
+	}
+	byID := make(map[int]models.TodoList, len(lists))
+	for _, l := range lists {
+		byID[l.ID] = l
+	}
+
+	byDay := make(map[string][]*models.Task)
+	for i := range tasks {
+		key := tasks[i].DueDate.Format(dateFormat)
+		byDay[key] = append(byDay[key], &tasks[i])
+	}
+
+	refresh := func() {
+		ShowCalendar(w, userID, mode, anchor)
+	}
+
+	var cells []calendarCell
+	// reschedule переносит задачу на день под точкой pos, сохраняя время срока
+	reschedule := func(task *models.Task, pos fyne.Position) {
+		driver := fyne.CurrentApp().Driver()
+		for _, cell := range cells {
+			topLeft := driver.AbsolutePositionForObject(cell.obj)
+			size := cell.obj.Size()
+			if pos.X < topLeft.X || pos.Y < topLeft.Y || pos.X > topLeft.X+size.Width || pos.Y > topLeft.Y+size.Height {
+				continue
+			}
+			due := task.DueDate
+			if cell.day.Format(dateFormat) == due.Format(dateFormat) {
+				return
+			}
+			task.DueDate = time.Date(cell.day.Year(), cell.day.Month(), cell.day.Day(),
+				due.Hour(), due.Minute(), due.Second(), 0, due.Location())
+			if err := db.UpdateTask(currentUserID, task); err != nil {
+				dialog.ShowError(err, w)
+			}
+			refresh()
+			return
+		}
+	}
+
+	today := time.Now().Format(dateFormat)
+	grid := container.NewGridWithColumns(7)
+	for i := 1; i <= 7; i++ {
+		grid.Add(widget.NewLabelWithStyle(weekdayNames[i%7], fyne.TextAlignCenter, fyne.TextStyle{Bold: true}))
+	}
+	for day := start; day.Before(end); day = day.AddDate(0, 0, 1) {
+		day := day
+		key := day.Format(dateFormat)
+
+		dayBtn := widget.NewButton(fmt.Sprint(day.Day()), func() {
+			showQuickAddDialog(w, userID, lists, day, refresh)
+		})
+		dayBtn.Importance = widget.LowImportance
+		if key == today {
+			dayBtn.Importance = widget.HighImportance
+		} else if mode == calendarMonth && day.Month() != anchor.Month() {
+			// Дни соседних месяцев подписываем вместе с месяцем
+			dayBtn.SetText(day.Format("2.01"))
+		}
+
+		content := container.NewVBox(dayBtn)
+		dayTasks := byDay[key]
+		for i, task := range dayTasks {
+			if mode == calendarMonth && i == monthCellTasks {
+				more := widget.NewButton(fmt.Sprintf("+ ещё %d", len(dayTasks)-i), func() {
+					ShowCalendar(w, userID, calendarWeek, day)
+				})
+				more.Importance = widget.LowImportance
+				content.Add(more)
+				break
+			}
+			task := task
+			list := byID[task.ListID]
+			text := task.Title
+			if task.IsDone {
+				text = "✓ " + text
+			}
+			content.Add(newTaskChip(text, func() {
+				showTaskDetails(w, task, list, refresh)
+			}, func(pos fyne.Position) {
+				reschedule(task, pos)
+			}))
+		}
+
+		border := canvas.NewRectangle(theme.Color(theme.ColorNameBackground))
+		border.StrokeColor = theme.Color(theme.ColorNameSeparator)
+		border.StrokeWidth = 1
+		cell := container.NewStack(border, content)
+		cells = append(cells, calendarCell{day: day, obj: cell})
+		grid.Add(cell)
+	}
+
+	title := monthNames[anchor.Month()-1] + " " + fmt.Sprint(anchor.Year())
+	step := func(n int) time.Time {
+		// От первого числа, чтобы с 31-го не перескочить через короткий месяц
+		return time.Date(anchor.Year(), anchor.Month(), 1, 0, 0, 0, 0, time.Local).AddDate(0, n, 0)
+	}
+	modeBtn := widget.NewButton("Неделя", func() {
+		ShowCalendar(w, userID, calendarWeek, anchor)
+	})
+	if mode == calendarWeek {
+		title = start.Format("02.01") + " – " + end.AddDate(0, 0, -1).Format(dateFormat)
+		step = func(n int) time.Time { return anchor.AddDate(0, 0, 7*n) }
+		modeBtn.SetText("Месяц")
+		modeBtn.OnTapped = func() {
+			ShowCalendar(w, userID, calendarMonth, anchor)
+		}
+	}
+
+	navigation := container.NewHBox(
+		widget.NewButton("◀", func() { ShowCalendar(w, userID, mode, step(-1)) }),
+		widget.NewLabelWithStyle(title, fyne.TextAlignCenter, fyne.TextStyle{Bold: true}),
+		widget.NewButton("▶", func() { ShowCalendar(w, userID, mode, step(1)) }),
+		layout.NewSpacer(),
+		widget.NewButton("Сегодня", func() { ShowCalendar(w, userID, mode, time.Now()) }),
+		modeBtn,
+	)
+
+	backButton := widget.NewButton("← Назад", func() {
+		ShowAgenda(w, userID, viewToday)
+	})
+	hint := widget.NewLabel("Нажмите на число, чтобы добавить задачу; перетащите задачу на другой день, чтобы перенести срок")
+	hint.Wrapping = fyne.TextWrapWord
+
+	var body fyne.CanvasObject = grid
+	if mode == calendarWeek {
+		body = container.NewVScroll(grid)
+	}
+
+	w.SetOnDropped(nil)
+	setContent(w, container.NewBorder(
+		navigation,
+		container.NewVBox(hint, container.NewHBox(backButton, layout.NewSpacer())),
+		nil, nil,
+		body,
+	))
+}
+
+// showQuickAddDialog добавляет задачу со сроком day в один из списков, доступных для изменения
+func showQuickAddDialog(w fyne.Window, userID int, lists []models.TodoList, day time.Time, onAdded func()) {
+	listSelect, selected := newListSelect(lists, 0)
+	if listSelect == nil {
+		dialog.ShowInformation("Новая задача", "Нет списков, в которые можно добавить задачу", w)
+		return
+	}
+
+	titleEntry := widget.NewEntry()
+	titleEntry.SetPlaceHolder("Название задачи")
+
+	create := func() bool {
+		if titleEntry.Text == "" {
+			return false
+		}
+		task := models.Task{
+			ListID:    selected().ID,
+			Title:     titleEntry.Text,
+			DueDate:   time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, time.UTC),
+			CreatedAt: time.Now(),
+		}
+		if err := db.CreateTask(currentUserID, &task); err != nil {
+			dialog.ShowError(err, w)
+			return false
+		}
+		onAdded()
+		return true
+	}
+
+	d := dialog.NewForm("Новая задача на "+day.Format(dateFormat), "Добавить", "Отмена", []*widget.FormItem{
+		widget.NewFormItem("Список:", listSelect),
+		widget.NewFormItem("Название:", titleEntry),
+	}, func(ok bool) {
+		if ok {
+			create()
+		}
+	}, w)
+	addEnterHandler(titleEntry, func() {
+		if create() {
+			d.Hide()
+		}
+	})
+	d.Resize(fyne.NewSize(360, 200))
+	d.Show()
+	w.Canvas().Focus(titleEntry)
+}
@@ -0,0 +1,138 @@
+// comments.go
+package gui
+
+import (
+	"fmt"
+	"todolist/db"
+	"todolist/models"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/widget"
+)
+
+const commentTimeFormat = "02.01.2006 15:04"
+
+// commentCountText - пометка с числом комментариев для строки задачи
+func commentCountText(task *models.Task) string {
+	if task.CommentCount == 0 {
+		return ""
+	}
+	return fmt.Sprintf("💬 %d", task.CommentCount)
+}
+
+// newCommentThread - обсуждение задачи: лента комментариев и поле ввода.
+// onChange вызывается при изменении числа комментариев.
+func newCommentThread(w fyne.Window, task *models.Task, onChange func()) fyne.CanvasObject {
+	thread := container.NewVBox()
+	scroll := container.NewVScroll(thread)
+	scroll.SetMinSize(fyne.NewSize(360, 180))
+
+	var refresh func()
+	refresh = func() {
+		comments, err := db.GetComments(task.ID)
+		if err != nil {
+			dialog.ShowError(fmt.Errorf("Ошибка загрузки комментариев: %v", err), w)
+			return
+		}
+
+		thread.RemoveAll()
+		if len(comments) == 0 {
+			thread.Add(widget.NewLabel("Комментариев пока нет"))
+		}
+		for _, c := range comments {
+			thread.Add(commentRow(w, c, refresh))
+		}
+		if task.CommentCount != len(comments) {
+			task.CommentCount = len(comments)
+			onChange()
+		}
+		scroll.ScrollToBottom()
+	}
+	refresh()
+
+	input := widget.NewMultiLineEntry()
+	input.SetPlaceHolder("Написать комментарий…")
+	input.Wrapping = fyne.TextWrapWord
+	input.SetMinRowsVisible(2)
+
+	sendBtn := widget.NewButton("Отправить", func() {
+		comment := models.Comment{TaskID: task.ID, UserID: currentUserID, Body: input.Text}
+		if err := db.AddComment(&comment); err != nil {
+			dialog.ShowError(err, w)
+			return
+		}
+		input.SetText("")
+		refresh()
+	})
+
+	return container.NewBorder(
+		widget.NewLabelWithStyle("Обсуждение", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+		container.NewBorder(nil, nil, nil, sendBtn, input),
+		nil, nil,
+		scroll,
+	)
+}
+
+func commentRow(w fyne.Window, c models.Comment, refresh func()) fyne.CanvasObject {
+	author := "Удалённый пользователь"
+	if c.UserID != 0 {
+		author = getUserName(c.UserID)
+	}
+	header := author + ", " + c.CreatedAt.Format(commentTimeFormat)
+	if !c.UpdatedAt.IsZero() {
+		header += " (изменён)"
+	}
+
+	body := widget.NewLabel(c.Body)
+	body.Wrapping = fyne.TextWrapWord
+
+	actions := container.NewHBox(layout.NewSpacer())
+	if c.UserID == currentUserID {
+		actions.Add(widget.NewButton("Изменить", func() {
+			editCommentDialog(w, c, refresh)
+		}))
+	}
+	// Удалить может автор или владелец списка; права проверяет база
+	actions.Add(widget.NewButton("✕", func() {
+		showDeleteConfirmDialog(w, "Удаление комментария", "Удалить комментарий?", func() {
+			if err := db.DeleteComment(currentUserID, c.ID); err != nil {
+				dialog.ShowError(err, w)
+				return
+			}
+			refresh()
+		})
+	}))
+
+	return container.NewVBox(
+		container.NewBorder(nil, nil, nil, actions,
+			widget.NewLabelWithStyle(header, fyne.TextAlignLeading, fyne.TextStyle{Italic: true})),
+		body,
+		widget.NewSeparator(),
+	)
+}
+
+func editCommentDialog(w fyne.Window, c models.Comment, onSave func()) {
+	entry := widget.NewMultiLineEntry()
+	entry.Wrapping = fyne.TextWrapWord
+	entry.SetText(c.Body)
+	entry.SetMinRowsVisible(4)
+
+	d := dialog.NewForm("Изменить комментарий", "Сохранить", "Отмена",
+		[]*widget.FormItem{widget.NewFormItem("", entry)},
+		func(ok bool) {
+			if !ok {
+				return
+			}
+			c.Body = entry.Text
+			if err := db.UpdateComment(currentUserID, &c); err != nil {
+				dialog.ShowError(err, w)
+				return
+			}
+			onSave()
+		}, w)
+	d.Resize(fyne.NewSize(400, 250))
+	d.Show()
+}
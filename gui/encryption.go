@@ -0,0 +1,78 @@
+// encryption.go
+package gui
+
+import (
+	"fmt"
+	"todolist/db"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+const encryptionHelp = "Названия и описания ваших списков и задач хранятся в базе зашифрованными. " +
+	"Ключ открывается паролем при входе и забывается при блокировке, " +
+	"поэтому нужен пароль не короче 10 символов, а не PIN.\n\n" +
+	"Теги, комментарии, вложения и сроки не шифруются. " +
+	"Зашифрованные списки нельзя открывать другим пользователям. " +
+	"Поиск и фильтры по названию и описанию работают только после входа, " +
+	"внешние программы и запросы к базе видят лишь шифротекст."
+
+// showEncryptionDialog включает, отключает шифрование данных пользователя и меняет его ключ
+func showEncryptionDialog(w fyne.Window, userID int) {
+	hash, err := db.GetUserPasswordHash(userID)
+	if err != nil {
+		dialog.ShowError(err, w)
+		return
+	}
+	if hash == "" {
+		dialog.ShowInformation("Шифрование", "Сначала задайте пароль: ключ шифрования открывается им.", w)
+		return
+	}
+	enabled, err := db.EncryptionEnabled(userID)
+	if err != nil {
+		dialog.ShowError(err, w)
+		return
+	}
+
+	status := widget.NewLabel("Шифрование выключено")
+	if enabled {
+		status.SetText("Шифрование включено")
+	}
+	status.TextStyle = fyne.TextStyle{Bold: true}
+	help := widget.NewLabel(encryptionHelp)
+	help.Wrapping = fyne.TextWrapWord
+
+	var d dialog.Dialog
+	// run запрашивает пароль и выполняет действие с ключом
+	run := func(title, done string, action func(userID int, passphrase string) error) {
+		d.Hide()
+		confirmUserPassword(w, userID, title, func(secret string) {
+			if err := action(userID, secret); err != nil {
+				dialog.ShowError(fmt.Errorf("Ошибка шифрования: %v", err), w)
+				return
+			}
+			ShowTodoLists(w, userID)
+			dialog.ShowInformation("Шифрование", done, w)
+		})
+	}
+
+	buttons := container.NewVBox()
+	if enabled {
+		buttons.Add(widget.NewButton("Сменить ключ", func() {
+			run("Смена ключа", "Данные перешифрованы новым ключом", db.RotateEncryptionKey)
+		}))
+		buttons.Add(widget.NewButton("Отключить шифрование", func() {
+			run("Отключение шифрования", "Данные расшифрованы", db.DisableEncryption)
+		}))
+	} else {
+		buttons.Add(widget.NewButton("Включить шифрование", func() {
+			run("Включение шифрования", "Данные зашифрованы", db.EnableEncryption)
+		}))
+	}
+
+	d = dialog.NewCustom("Шифрование", "Закрыть", container.NewVBox(status, help, buttons), w)
+	d.Resize(fyne.NewSize(420, 360))
+	d.Show()
+}
@@ -0,0 +1,81 @@
+// feed.go
+package gui
+
+import (
+	"fmt"
+	"todolist/db"
+	"todolist/feed"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/widget"
+)
+
+// showFeedDialog управляет секретными ссылками подписки; listID = 0 - все списки пользователя
+func showFeedDialog(w fyne.Window, userID, listID int) {
+	feedsContainer := container.NewVBox()
+
+	var refresh func()
+	refresh = func() {
+		feeds, err := db.GetFeedTokens(userID, listID)
+		if err != nil {
+			dialog.ShowError(fmt.Errorf("Ошибка загрузки подписок: %v", err), w)
+			return
+		}
+
+		feedsContainer.RemoveAll()
+		if len(feeds) == 0 {
+			feedsContainer.Add(widget.NewLabel("Ссылок подписки пока нет"))
+		}
+		for _, f := range feeds {
+			currentFeed := f
+			url := feed.URL(currentFeed)
+
+			urlEntry := widget.NewEntry()
+			urlEntry.SetText(url)
+			urlEntry.OnChanged = func(string) {
+				urlEntry.SetText(url)
+			}
+
+			copyBtn := widget.NewButton("Копировать", func() {
+				w.Clipboard().SetContent(url)
+			})
+			revokeBtn := widget.NewButton("Отозвать", func() {
+				showDeleteConfirmDialog(w, "Отзыв ссылки",
+					"Календари, подписанные по этой ссылке, перестанут обновляться. Отозвать?",
+					func() {
+						if err := db.RevokeFeedToken(userID, currentFeed.Token); err != nil {
+							dialog.ShowError(err, w)
+							return
+						}
+						refresh()
+					})
+			})
+
+			feedsContainer.Add(widget.NewLabel("Создана " + currentFeed.CreatedAt.Format(dateFormat)))
+			feedsContainer.Add(container.NewBorder(nil, nil, nil, container.NewHBox(copyBtn, revokeBtn), urlEntry))
+		}
+	}
+	refresh()
+
+	createBtn := widget.NewButton("+ Новая ссылка", func() {
+		if _, err := feed.Create(userID, listID); err != nil {
+			dialog.ShowError(err, w)
+			return
+		}
+		refresh()
+	})
+
+	content := container.NewBorder(
+		widget.NewLabel("Добавьте ссылку в календарь как подписку.\nНе передавайте её посторонним."),
+		container.NewHBox(layout.NewSpacer(), createBtn),
+		nil, nil,
+		container.NewVScroll(feedsContainer),
+	)
+
+	d := dialog.NewCustom("Подписка на календарь", "Закрыть", content, w)
+	d.Resize(fyne.NewSize(560, 360))
+	d.Show()
+}
@@ -0,0 +1,69 @@
+// files.go
+package gui
+
+import (
+	"io"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/storage"
+	"fyne.io/fyne/v2/widget"
+)
+
+// newMenuButton создаёт кнопку, открывающую всплывающее меню под собой
+func newMenuButton(w fyne.Window, label string, items ...*fyne.MenuItem) *widget.Button {
+	var btn *widget.Button
+	btn = widget.NewButton(label, func() {
+		pos := fyne.CurrentApp().Driver().AbsolutePositionForObject(btn)
+		pos = pos.Add(fyne.NewPos(0, btn.Size().Height))
+		widget.ShowPopUpMenuAtPosition(fyne.NewMenu("", items...), w.Canvas(), pos)
+	})
+	return btn
+}
+
+// showSaveFileDialog предлагает выбрать файл и передаёт его в write
+func showSaveFileDialog(w fyne.Window, fileName string, extensions []string, write func(io.Writer) error) {
+	d := dialog.NewFileSave(func(writer fyne.URIWriteCloser, err error) {
+		if err != nil {
+			dialog.ShowError(err, w)
+			return
+		}
+		if writer == nil {
+			return
+		}
+		defer writer.Close()
+
+		if err := write(writer); err != nil {
+			dialog.ShowError(err, w)
+			return
+		}
+		dialog.ShowInformation("Сохранение", "Файл сохранён: "+writer.URI().Name(), w)
+	}, w)
+	d.SetFileName(fileName)
+	if len(extensions) > 0 {
+		d.SetFilter(storage.NewExtensionFileFilter(extensions))
+	}
+	d.Show()
+}
+
+// showOpenFileDialog предлагает выбрать файл и передаёт его в read
+func showOpenFileDialog(w fyne.Window, extensions []string, read func(fyne.URIReadCloser) error) {
+	d := dialog.NewFileOpen(func(reader fyne.URIReadCloser, err error) {
+		if err != nil {
+			dialog.ShowError(err, w)
+			return
+		}
+		if reader == nil {
+			return
+		}
+		defer reader.Close()
+
+		if err := read(reader); err != nil {
+			dialog.ShowError(err, w)
+		}
+	}, w)
+	if len(extensions) > 0 {
+		d.SetFilter(storage.NewExtensionFileFilter(extensions))
+	}
+	d.Show()
+}
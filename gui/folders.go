@@ -0,0 +1,218 @@
+// folders.go
+package gui
+
+import (
+	"fmt"
+	"todolist/db"
+	"todolist/models"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/widget"
+)
+
+// pinnedGroup - группа перестановки закреплённых списков; остальные списки
+// переставляются внутри своей папки (группа - ID папки)
+const pinnedGroup = -1
+
+// listTree раскладывает активные списки пользователя по папкам
+type listTree struct {
+	folders    map[int]models.Folder
+	subfolders map[int][]models.Folder   // по ID родителя, 0 - верхний уровень
+	lists      map[int][]models.TodoList // незакреплённые списки по ID папки
+	all        map[int][]models.TodoList // все списки папки, для сводки
+	pinned     []models.TodoList
+	counts     map[int]models.ListCounts
+}
+
+func newListTree(folders []models.Folder, lists []models.TodoList, counts map[int]models.ListCounts) *listTree {
+	t := &listTree{
+		folders:    make(map[int]models.Folder, len(folders)),
+		subfolders: make(map[int][]models.Folder),
+		lists:      make(map[int][]models.TodoList),
+		all:        make(map[int][]models.TodoList),
+		counts:     counts,
+	}
+	for _, f := range folders {
+		t.folders[f.ID] = f
+	}
+	for _, f := range folders {
+		parentID := f.ParentID
+		if _, ok := t.folders[parentID]; !ok {
+			parentID = 0
+		}
+		t.subfolders[parentID] = append(t.subfolders[parentID], f)
+	}
+	for _, l := range lists {
+		folderID := l.FolderID
+		if _, ok := t.folders[folderID]; !ok {
+			folderID = 0
+		}
+		t.all[folderID] = append(t.all[folderID], l)
+		if l.Pinned {
+			t.pinned = append(t.pinned, l)
+		} else {
+			t.lists[folderID] = append(t.lists[folderID], l)
+		}
+	}
+	return t
+}
+
+// total - сводка по всем спискам папки, включая вложенные папки
+func (t *listTree) total(folderID int) models.ListCounts {
+	var c models.ListCounts
+	for _, l := range t.all[folderID] {
+		c = c.Add(t.counts[l.ID])
+	}
+	for _, f := range t.subfolders[folderID] {
+		c = c.Add(t.total(f.ID))
+	}
+	return c
+}
+
+// folderTarget - строка папки, на которую можно перетащить список
+type folderTarget struct {
+	id  int
+	obj fyne.CanvasObject
+}
+
+// addRows добавляет в box папки и списки папки parentID с отступом depth;
+// строки папок собираются в targets для переноса списков перетаскиванием
+func (t *listTree) addRows(w fyne.Window, userID int, box *fyne.Container, parentID, depth int,
+	newListRow func(list models.TodoList, group int) fyne.CanvasObject, targets *[]folderTarget) {
+	for _, f := range t.subfolders[parentID] {
+		row := t.newFolderRow(w, userID, f)
+		*targets = append(*targets, folderTarget{id: f.ID, obj: row})
+		box.Add(indented(row, depth))
+		if !f.Collapsed {
+			t.addRows(w, userID, box, f.ID, depth+1, newListRow, targets)
+		}
+	}
+	for _, l := range t.lists[parentID] {
+		box.Add(indented(newListRow(l, parentID), depth))
+	}
+}
+
+func (t *listTree) newFolderRow(w fyne.Window, userID int, f models.Folder) fyne.CanvasObject {
+	arrow := "▾ "
+	if f.Collapsed {
+		arrow = "▸ "
+	}
+	toggle := widget.NewButton(arrow+"📁 "+f.Title, func() {
+		if err := db.SetFolderCollapsed(userID, f.ID, !f.Collapsed); err != nil {
+			dialog.ShowError(err, w)
+			return
+		}
+		ShowTodoLists(w, userID)
+	})
+	toggle.Alignment = widget.ButtonAlignLeading
+	toggle.Importance = widget.LowImportance
+
+	row := container.NewHBox(toggle)
+	c := t.total(f.ID)
+	if c.Open > 0 {
+		row.Add(widget.NewLabel(fmt.Sprintf("открыто: %d", c.Open)))
+	}
+	if c.Overdue > 0 {
+		row.Add(overdueLabel(c.Overdue))
+	}
+	row.Add(layout.NewSpacer())
+
+	row.Add(newMenuButton(w, "…",
+		fyne.NewMenuItem("Новая папка внутри…", func() {
+			showNewFolderDialog(w, userID, f.ID)
+		}),
+		fyne.NewMenuItem("Переименовать…", func() {
+			askValue(w, "Название папки", "", f.Title, func(title string) {
+				f.Title = title
+				if err := db.UpdateFolder(&f); err != nil {
+					dialog.ShowError(err, w)
+					return
+				}
+				ShowTodoLists(w, userID)
+			})
+		}),
+		fyne.NewMenuItem("Переместить в…", func() {
+			showFolderPicker(w, "Переместить папку", t, f.ParentID, f.ID, func(parentID int) {
+				f.ParentID = parentID
+				if err := db.UpdateFolder(&f); err != nil {
+					dialog.ShowError(err, w)
+					return
+				}
+				ShowTodoLists(w, userID)
+			})
+		}),
+		fyne.NewMenuItem("Удалить папку", func() {
+			showDeleteConfirmDialog(w, "Удаление папки",
+				"Удалить папку? Списки и вложенные папки из неё перейдут на уровень выше.",
+				func() {
+					if err := db.DeleteFolder(userID, f.ID); err != nil {
+						dialog.ShowError(err, w)
+						return
+					}
+					ShowTodoLists(w, userID)
+				})
+		}),
+	))
+	return row
+}
+
+// folderPath - название папки со всеми родителями, например "Работа / Проекты"
+func (t *listTree) folderPath(id int) string {
+	f := t.folders[id]
+	if _, ok := t.folders[f.ParentID]; ok {
+		return t.folderPath(f.ParentID) + " / " + f.Title
+	}
+	return f.Title
+}
+
+// showFolderPicker предлагает выбрать папку; skip вместе с вложенными папками не предлагается
+func showFolderPicker(w fyne.Window, title string, t *listTree, current, skip int, onPick func(folderID int)) {
+	const root = "Без папки"
+	options := []string{root}
+	ids := map[string]int{root: 0}
+	selected := root
+	var walk func(parentID int)
+	walk = func(parentID int) {
+		for _, f := range t.subfolders[parentID] {
+			if f.ID == skip {
+				continue
+			}
+			path := t.folderPath(f.ID)
+			if _, exists := ids[path]; exists {
+				path = fmt.Sprintf("%s (ID %d)", path, f.ID)
+			}
+			options = append(options, path)
+			ids[path] = f.ID
+			if f.ID == current {
+				selected = path
+			}
+			walk(f.ID)
+		}
+	}
+	walk(0)
+
+	folderSelect := widget.NewSelect(options, nil)
+	folderSelect.SetSelected(selected)
+	dialog.ShowForm(title, "OK", "Отмена", []*widget.FormItem{
+		widget.NewFormItem("В папку:", folderSelect),
+	}, func(ok bool) {
+		if ok {
+			onPick(ids[folderSelect.Selected])
+		}
+	}, w)
+}
+
+// showNewFolderDialog создаёт папку внутри parentID (0 - на верхнем уровне)
+func showNewFolderDialog(w fyne.Window, userID, parentID int) {
+	askValue(w, "Новая папка", "Название папки", "", func(title string) {
+		folder := models.Folder{UserID: userID, ParentID: parentID, Title: title}
+		if err := db.CreateFolder(&folder); err != nil {
+			dialog.ShowError(err, w)
+			return
+		}
+		ShowTodoLists(w, userID)
+	})
+}
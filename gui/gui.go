@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"hash/crc32"
+	"image/color"
 	"os"
 	"strings"
 	"sync"
@@ -13,6 +14,7 @@ import (
 	"todolist/models"
 
 	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
 	"fyne.io/fyne/v2/container"
 	"fyne.io/fyne/v2/dialog"
 	"fyne.io/fyne/v2/layout"
@@ -22,11 +24,15 @@ import (
 const (
 	dateFormat    = "02.01.2006"
 	userNamesFile = "user_names.json"
+	subtaskIndent = 24
 )
 
 var (
 	userNames   = make(map[int]string)
 	userNamesMu sync.RWMutex
+
+	// currentUserID - пользователь, выбранный на первом экране; от его имени выполняются изменения
+	currentUserID int
 )
 
 func addEnterHandler(entry *widget.Entry, callback func()) {
@@ -62,6 +68,8 @@ func saveUserNames() {
 }
 
 func ShowUserSelection(w fyne.Window) {
+	currentUserID = 0
+	db.LockUserKeys()
 	users, err := db.GetAllUsers()
 	if err != nil {
 		dialog.ShowError(fmt.Errorf("Ошибка загрузки пользователей: %v", err), w)
@@ -77,14 +85,20 @@ func ShowUserSelection(w fyne.Window) {
 	for _, user := range users {
 		u := user
 		userName := getUserName(u.ID)
+		if u.Protected {
+			userName = "🔒 " + userName
+		}
 
 		userRow := container.NewHBox(
 			widget.NewButton(userName, func() {
-				ShowTodoLists(w, u.ID)
+				openUser(w, u)
 			}),
 			layout.NewSpacer(),
 			widget.NewButton("✕", func() {
-				showDeleteUserDialog(w, u)
+				// Удалить защищённого пользователя можно только зная его пароль
+				confirmUserPassword(w, u.ID, "Подтвердите удаление паролем", func(string) {
+					showDeleteUserDialog(w, u)
+				})
 			}),
 		)
 		usersContainer.Add(userRow)
@@ -102,10 +116,16 @@ func ShowUserSelection(w fyne.Window) {
 
 	mainContainer.Add(usersContainer)
 	mainContainer.Add(layout.NewSpacer())
+	backupButton := widget.NewButton("Резервные копии…", func() {
+		showBackupDialog(w, 0)
+	})
+
 	mainContainer.Add(addButtonContainer)
 	mainContainer.Add(layout.NewSpacer())
+	mainContainer.Add(container.NewHBox(layout.NewSpacer(), backupButton))
 
-	w.SetContent(mainContainer)
+	w.SetOnDropped(nil)
+	setContent(w, mainContainer)
 }
 
 func showDeleteUserDialog(w fyne.Window, user models.User) {
@@ -207,50 +227,172 @@ func getUserName(userID int) string {
 }
 
 func ShowTodoLists(w fyne.Window, userID int) {
+	currentUserID = userID
 	lists, err := db.GetTodoLists(userID)
 	if err != nil {
 		dialog.ShowError(fmt.Errorf("Ошибка загрузки списков: %v", err), w)
 		return
 	}
+	folders, err := db.GetFolders(userID)
+	if err != nil {
+		dialog.ShowError(fmt.Errorf("Ошибка загрузки папок: %v", err), w)
+		return
+	}
+	counts, err := db.GetListCounts(userID)
+	if err != nil {
+		dialog.ShowError(fmt.Errorf("Ошибка загрузки списков: %v", err), w)
+		return
+	}
+
+	agendaButton := widget.NewButton("← Сегодня и ближайшие", func() {
+		ShowAgenda(w, userID, viewToday)
+	})
+	assignedButton := widget.NewButton("Назначенные мне", func() {
+		ShowAssignedTasks(w, userID)
+	})
 
 	mainContainer := container.NewVBox(
 		layout.NewSpacer(),
 		widget.NewLabelWithStyle("My Tasks", fyne.TextAlignCenter, fyne.TextStyle{Bold: true}),
+		container.NewHBox(agendaButton, assignedButton, layout.NewSpacer(), newNotificationsButton(w, userID)),
+		newSearchBar(w, userID, ""),
 		layout.NewSpacer(),
 	)
 
-	listsContainer := container.NewVBox()
-	for _, list := range lists {
-		currentList := list
+	listsOrder := &rowOrder{move: func(id, targetID int, after bool) {
+		if err := db.MoveTodoList(userID, id, targetID, after); err != nil {
+			dialog.ShowError(err, w)
+		}
+		ShowTodoLists(w, userID)
+	}}
 
-		check := widget.NewCheck("", func(checked bool) {
-			fmt.Printf("Список %s отмечен как выполненный: %v\n", currentList.Title, checked)
-		})
+	var activeLists, archivedLists []models.TodoList
+	for _, l := range lists {
+		if l.ArchivedAt.IsZero() {
+			activeLists = append(activeLists, l)
+		} else {
+			archivedLists = append(archivedLists, l)
+		}
+	}
+	tree := newListTree(folders, activeLists, counts)
+
+	// newListRow строит строку списка; флажок убирает список в архив или возвращает из него.
+	// group - группа перестановки строки (см. pinnedGroup)
+	newListRow := func(currentList models.TodoList, group int) fyne.CanvasObject {
+		archived := !currentList.ArchivedAt.IsZero()
+		check := widget.NewCheck("", nil)
+		check.SetChecked(archived)
+		check.OnChanged = func(checked bool) {
+			if err := db.SetListArchived(userID, currentList.ID, checked); err != nil {
+				dialog.ShowError(err, w)
+			}
+			ShowTodoLists(w, userID)
+		}
+		if !currentList.Role.CanEdit() {
+			check.Disable()
+		}
 
 		listBtn := widget.NewButton(currentList.Title, func() {
 			ShowTodoItems(w, currentList)
 		})
 		listBtn.Alignment = widget.ButtonAlignLeading
 
-		deleteBtn := widget.NewButton("✕", func() {
-			showDeleteConfirmDialog(w, "Удаление списка", "Удалить этот список и все его задачи?", func() {
-				if err := db.DeleteTodoList(currentList.ID); err != nil {
+		// Архивные списки не переставляются: они показываются отдельно
+		var row []fyne.CanvasObject
+		if !archived {
+			row = append(row, listsOrder.handle(currentList.ID, group))
+		}
+		listRow := container.NewHBox(append(row, check, listBtn)...)
+		if badge := sharedBadge(currentList); badge != "" {
+			listRow.Add(widget.NewLabel(badge))
+		}
+		listRow.Add(layout.NewSpacer())
+		for _, obj := range newListProgress(counts[currentList.ID]) {
+			listRow.Add(obj)
+		}
+
+		if !archived {
+			pinBtn := widget.NewButton("📌", func() {
+				if err := db.SetListPinned(userID, currentList.ID, !currentList.Pinned); err != nil {
 					dialog.ShowError(err, w)
-					return
 				}
 				ShowTodoLists(w, userID)
 			})
-		})
+			if !currentList.Pinned {
+				pinBtn.Importance = widget.LowImportance
+			}
+			folderBtn := widget.NewButton("📁", func() {
+				showFolderPicker(w, "Папка списка", tree, currentList.FolderID, 0, func(folderID int) {
+					if err := db.SetListFolder(userID, currentList.ID, folderID); err != nil {
+						dialog.ShowError(err, w)
+					}
+					ShowTodoLists(w, userID)
+				})
+			})
+			listRow.Add(pinBtn)
+			listRow.Add(folderBtn)
+			listsOrder.add(currentList.ID, group, listRow)
+		}
 
-		listRow := container.NewHBox(
-			check,
-			listBtn,
-			layout.NewSpacer(),
-			deleteBtn,
-		)
+		if currentList.Role == models.RoleOwner {
+			shareBtn := widget.NewButton("👥", func() {
+				showShareDialog(w, currentList)
+			})
+			deleteBtn := widget.NewButton("✕", func() {
+				showDeleteConfirmDialog(w, "Удаление списка", "Удалить этот список и все его задачи?", func() {
+					if err := db.DeleteTodoList(userID, currentList.ID); err != nil {
+						dialog.ShowError(err, w)
+						return
+					}
+					ShowTodoLists(w, userID)
+				})
+			})
+			listRow.Add(shareBtn)
+			listRow.Add(deleteBtn)
+		} else {
+			listRow.Add(widget.NewButton("Покинуть", func() {
+				showLeaveListDialog(w, currentList)
+			}))
+		}
+		return listRow
+	}
+
+	listsContainer := container.NewVBox()
+	addSmartListRows(w, userID, listsContainer)
+	for _, list := range tree.pinned {
+		listsContainer.Add(newListRow(list, pinnedGroup))
+	}
+	if len(tree.pinned) > 0 {
+		listsContainer.Add(widget.NewSeparator())
+	}
+
+	// Список, отпущенный над строкой папки, переходит в эту папку
+	var folderTargets []folderTarget
+	tree.addRows(w, userID, listsContainer, 0, 0, newListRow, &folderTargets)
+	listsOrder.outside = func(id int, pos fyne.Position) {
+		driver := fyne.CurrentApp().Driver()
+		for _, t := range folderTargets {
+			topLeft := driver.AbsolutePositionForObject(t.obj)
+			size := t.obj.Size()
+			if pos.X < topLeft.X || pos.X > topLeft.X+size.Width || pos.Y < topLeft.Y || pos.Y > topLeft.Y+size.Height {
+				continue
+			}
+			if err := db.SetListFolder(userID, id, t.id); err != nil {
+				dialog.ShowError(err, w)
+			}
+			ShowTodoLists(w, userID)
+			return
+		}
+	}
 
-		listsContainer.Add(listRow)
-		listsContainer.Add(container.NewPadded(container.NewVBox(layout.NewSpacer())))
+	if len(archivedLists) > 0 {
+		archiveContainer := container.NewVBox()
+		for _, list := range archivedLists {
+			archiveContainer.Add(newListRow(list, 0))
+		}
+		listsContainer.Add(widget.NewAccordion(
+			widget.NewAccordionItem(fmt.Sprintf("Архив (%d)", len(archivedLists)), archiveContainer),
+		))
 	}
 
 	scrollContainer := container.NewVScroll(listsContainer)
@@ -265,9 +407,18 @@ func ShowTodoLists(w fyne.Window, userID int) {
 	addButton.Importance = widget.HighImportance
 	addButton.Resize(fyne.NewSize(300, 50))
 
+	smartButton := widget.NewButton("+ Умный список", func() {
+		showSmartListDialog(w, userID, nil)
+	})
+	folderButton := widget.NewButton("+ Папка", func() {
+		showNewFolderDialog(w, userID, 0)
+	})
+
 	addButtonContainer := container.NewHBox(
 		layout.NewSpacer(),
 		addButton,
+		smartButton,
+		folderButton,
 		layout.NewSpacer(),
 	)
 
@@ -278,9 +429,67 @@ func ShowTodoLists(w fyne.Window, userID int) {
 		ShowUserSelection(w)
 	})
 
-	mainContainer.Add(backButton)
+	fileButton := newMenuButton(w, "Файл…",
+		fyne.NewMenuItem("Экспорт всех задач (.ics)", func() {
+			exportUserICal(w, userID)
+		}),
+		fyne.NewMenuItem("Подписка на календарь…", func() {
+			showFeedDialog(w, userID, 0)
+		}),
+		fyne.NewMenuItemSeparator(),
+		fyne.NewMenuItem("Экспорт всех задач (.csv)", func() {
+			exportUserSpreadsheet(w, userID, false)
+		}),
+		fyne.NewMenuItem("Экспорт всех задач (.xlsx)", func() {
+			exportUserSpreadsheet(w, userID, true)
+		}),
+		fyne.NewMenuItem("Импорт из CSV…", func() {
+			importCSV(w, userID, 0)
+		}),
+		fyne.NewMenuItemSeparator(),
+		fyne.NewMenuItem("Экспорт в todo.txt", func() {
+			exportUserTodoTxt(w, userID)
+		}),
+		fyne.NewMenuItem("Импорт из todo.txt", func() {
+			importTodoTxt(w, userID, nil)
+		}),
+		fyne.NewMenuItemSeparator(),
+		fyne.NewMenuItem("Экспорт в Markdown", func() {
+			exportUserMarkdown(w, userID)
+		}),
+		fyne.NewMenuItem("Импорт из Markdown", func() {
+			importMarkdownDialog(w, userID, nil)
+		}),
+		fyne.NewMenuItemSeparator(),
+		fyne.NewMenuItem("Импорт из Todoist…", func() {
+			importTodoist(w, userID)
+		}),
+		fyne.NewMenuItem("Импорт доски Trello…", func() {
+			importTrello(w, userID)
+		}),
+		fyne.NewMenuItemSeparator(),
+		fyne.NewMenuItem("Шаблоны списков…", func() {
+			showTemplatesDialog(w, userID)
+		}),
+		fyne.NewMenuItem("Резервные копии…", func() {
+			showBackupDialog(w, userID)
+		}),
+		fyne.NewMenuItemSeparator(),
+		fyne.NewMenuItem("Пароль или PIN…", func() {
+			showPasswordDialog(w, userID)
+		}),
+		fyne.NewMenuItem("Шифрование…", func() {
+			showEncryptionDialog(w, userID)
+		}),
+		fyne.NewMenuItem("Заблокировать", func() {
+			ShowUserSelection(w)
+		}),
+	)
+
+	mainContainer.Add(container.NewHBox(backButton, layout.NewSpacer(), fileButton))
 
-	w.SetContent(mainContainer)
+	setMarkdownDropHandler(w, userID, nil)
+	setContent(w, mainContainer)
 }
 
 func showDeleteConfirmDialog(w fyne.Window, title, message string, onConfirm func()) {
@@ -316,6 +525,9 @@ func showAddListDialog(w fyne.Window, userID int) {
 		descEntry,
 	)
 
+	// Список можно создать из шаблона; сроки задач отсчитываются от даты начала
+	template, startEntry := newTemplateChooser(w, userID, formContent, titleEntry)
+
 	buttons := container.NewHBox(
 		layout.NewSpacer(),
 		widget.NewButton("Отмена", nil),
@@ -342,7 +554,16 @@ func showAddListDialog(w fyne.Window, userID int) {
 				CreatedAt:   time.Now(),
 			}
 
-			if err := db.CreateTodoList(&newList); err != nil {
+			create := func() error { return db.CreateTodoList(&newList) }
+			if tmpl := template(); tmpl != nil {
+				start, err := time.Parse(dateFormat, startEntry.Text)
+				if err != nil {
+					dialog.ShowError(fmt.Errorf("неверная дата начала. Формат: дд.мм.гггг"), w)
+					return
+				}
+				create = func() error { return db.CreateListFromTemplate(tmpl.ID, &newList, start) }
+			}
+			if err := create(); err != nil {
 				dialog.ShowError(err, w)
 				return
 			}
@@ -363,25 +584,61 @@ func showAddListDialog(w fyne.Window, userID int) {
 }
 
 func ShowTodoItems(w fyne.Window, list models.TodoList) {
+	showTodoItems(w, list, newTaskSelection())
+}
+
+// showTodoItems показывает задачи списка; sel - выбор задач для групповых действий
+func showTodoItems(w fyne.Window, list models.TodoList, sel *taskSelection) {
 	tasks, err := db.GetTasksByList(list.ID)
 	if err != nil {
 		dialog.ShowError(fmt.Errorf("Ошибка загрузки задач: %v", err), w)
 		return
 	}
 
-	tasksContainer := container.NewVBox()
-	for _, task := range tasks {
-		currentTask := task
-		taskRow := createTaskRow(w, &currentTask, list)
-		tasksContainer.Add(taskRow)
+	// Выбирать можно только то, что можно менять
+	if !list.Role.CanEdit() {
+		sel = nil
+	} else {
+		sel.order, sel.checks = nil, make(map[int]*selectCheck)
+		sel.redraw = func() {
+			showTodoItems(w, list, sel)
+		}
+	}
+
+	// Задачи перетаскиваются на другие списки в боковой панели, а при ручном порядке - и внутри списка
+	var (
+		order     *rowOrder
+		sidePanel fyne.CanvasObject
+	)
+	if sel != nil && !sel.active {
+		order = &rowOrder{}
+		sidePanel, order.outside = newMoveTargets(w, list)
+		if list.ManualOrder {
+			order.move = func(id, targetID int, after bool) {
+				if err := db.MoveTask(currentUserID, id, targetID, after); err != nil {
+					dialog.ShowError(err, w)
+				}
+				ShowTodoItems(w, list)
+			}
+		}
 	}
 
+	tasksContainer := container.NewVBox()
+	// Сводка в заголовке пересчитывается по тем же задачам, что правят строки
+	progress := &listProgress{box: container.NewHBox(), roots: models.NestTasks(tasks), listID: list.ID}
+	progress.refresh()
+	addTaskRows(w, tasksContainer, progress.roots, list, 0, order, sel, progress.refresh)
+
 	addButton := widget.NewButton("+ Добавить задачу", func() {
 		showAddTaskDialog(w, list)
 	})
 
+	if !list.Role.CanEdit() {
+		addButton.Disable()
+	}
+
 	backButton := widget.NewButton("← Назад", func() {
-		ShowTodoLists(w, list.UserID)
+		ShowTodoLists(w, currentUserID)
 	})
 
 	deleteListButton := widget.NewButton("Удалить список", func() {
@@ -389,45 +646,222 @@ func ShowTodoItems(w fyne.Window, list models.TodoList) {
 			"Удаление списка",
 			"Вы уверены, что хотите удалить этот список и все его задачи?",
 			func() {
-				if err := db.DeleteTodoList(list.ID); err != nil {
+				if err := db.DeleteTodoList(currentUserID, list.ID); err != nil {
 					dialog.ShowError(err, w)
 					return
 				}
-				ShowTodoLists(w, list.UserID)
+				ShowTodoLists(w, currentUserID)
 			})
 	})
+	if list.Role != models.RoleOwner {
+		deleteListButton = widget.NewButton("Покинуть список", func() {
+			showLeaveListDialog(w, list)
+		})
+	}
 
-	controls := container.NewHBox(
-		backButton,
-		layout.NewSpacer(),
-		deleteListButton,
+	fileButton := newMenuButton(w, "Файл…",
+		fyne.NewMenuItem("Экспорт в календарь (.ics)", func() {
+			exportListICal(w, list)
+		}),
+		editOnly(list, fyne.NewMenuItem("Импорт из календаря (.ics)", func() {
+			importListICal(w, list)
+		})),
+		fyne.NewMenuItem("Подписка на календарь…", func() {
+			showFeedDialog(w, currentUserID, list.ID)
+		}),
+		fyne.NewMenuItemSeparator(),
+		fyne.NewMenuItem("Экспорт в CSV", func() {
+			exportListSpreadsheet(w, list, false)
+		}),
+		fyne.NewMenuItem("Экспорт в Excel (.xlsx)", func() {
+			exportListSpreadsheet(w, list, true)
+		}),
+		editOnly(list, fyne.NewMenuItem("Импорт из CSV…", func() {
+			importCSV(w, currentUserID, list.ID)
+		})),
+		fyne.NewMenuItemSeparator(),
+		fyne.NewMenuItem("Экспорт в todo.txt", func() {
+			exportListTodoTxt(w, list)
+		}),
+		editOnly(list, fyne.NewMenuItem("Импорт из todo.txt", func() {
+			importTodoTxt(w, currentUserID, &list)
+		})),
+		fyne.NewMenuItemSeparator(),
+		fyne.NewMenuItem("Экспорт в Markdown", func() {
+			exportListMarkdown(w, list)
+		}),
+		editOnly(list, fyne.NewMenuItem("Импорт из Markdown", func() {
+			importMarkdownDialog(w, currentUserID, &list)
+		})),
+		fyne.NewMenuItemSeparator(),
+		fyne.NewMenuItem("Сохранить как шаблон…", func() {
+			showSaveTemplateDialog(w, list, tasks)
+		}),
+		fyne.NewMenuItem("Дублировать список…", func() {
+			showDuplicateListDialog(w, list)
+		}),
 	)
 
-	w.SetContent(container.NewVBox(
-		widget.NewLabelWithStyle(list.Title, fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+	boardButton := widget.NewButton("Доска", func() {
+		ShowKanban(w, list)
+	})
+
+	orderButton := widget.NewButton("Порядок: по сроку", nil)
+	if list.ManualOrder {
+		orderButton.SetText("Порядок: вручную")
+	}
+	orderButton.OnTapped = func() {
+		if err := db.SetListManualOrder(currentUserID, list.ID, !list.ManualOrder); err != nil {
+			dialog.ShowError(err, w)
+			return
+		}
+		list.ManualOrder = !list.ManualOrder
+		ShowTodoItems(w, list)
+	}
+	if !list.Role.CanEdit() {
+		orderButton.Disable()
+	}
+
+	autoArchiveCheck := widget.NewCheck("В архив, когда всё выполнено", func(on bool) {
+		if err := db.SetListAutoArchive(currentUserID, list.ID, on); err != nil {
+			dialog.ShowError(err, w)
+			return
+		}
+		// Если всё уже выполнено, список сразу ушёл в архив: перечитываем его
+		lists, err := db.GetTodoLists(currentUserID)
+		if err != nil {
+			dialog.ShowError(fmt.Errorf("Ошибка загрузки списков: %v", err), w)
+			return
+		}
+		for _, l := range lists {
+			if l.ID == list.ID {
+				ShowTodoItems(w, l)
+				return
+			}
+		}
+	})
+	autoArchiveCheck.Checked = list.AutoArchive
+	if !list.Role.CanEdit() {
+		autoArchiveCheck.Disable()
+	}
+
+	header := container.NewHBox(widget.NewLabelWithStyle(list.Title, fyne.TextAlignLeading, fyne.TextStyle{Bold: true}))
+	if !list.ArchivedAt.IsZero() {
+		header.Add(widget.NewLabelWithStyle("в архиве с "+list.ArchivedAt.Format(dateFormat), fyne.TextAlignLeading, fyne.TextStyle{Italic: true}))
+		restoreButton := widget.NewButton("Вернуть из архива", func() {
+			if err := db.SetListArchived(currentUserID, list.ID, false); err != nil {
+				dialog.ShowError(err, w)
+				return
+			}
+			list.ArchivedAt = time.Time{}
+			ShowTodoItems(w, list)
+		})
+		if !list.Role.CanEdit() {
+			restoreButton.Disable()
+		}
+		header.Add(restoreButton)
+	}
+	header.Add(progress.box)
+	header.Add(layout.NewSpacer())
+	header.Add(autoArchiveCheck)
+
+	selectButton := widget.NewButton("Выбрать", func() {
+		sel.active = true
+		sel.redraw()
+	})
+	if sel == nil {
+		selectButton.Disable()
+	}
+
+	controls := container.NewHBox(backButton)
+	// Последнее групповое действие пользователя можно отменить
+	if label, ok := db.UndoLabel(currentUserID); ok && list.Role.CanEdit() {
+		controls.Add(widget.NewButton("↶ Отменить: "+label, func() {
+			if _, err := db.UndoLast(currentUserID); err != nil {
+				dialog.ShowError(err, w)
+			}
+			ShowTodoItems(w, list)
+		}))
+	}
+	controls.Add(layout.NewSpacer())
+	controls.Add(selectButton)
+	controls.Add(orderButton)
+	controls.Add(boardButton)
+	controls.Add(fileButton)
+	controls.Add(deleteListButton)
+
+	var bottom fyne.CanvasObject = addButton
+	if sel != nil && sel.active {
+		bottom = sel.toolbar(w, list)
+	}
+	var content fyne.CanvasObject = container.NewVBox(
+		header,
 		widget.NewLabel(list.Description),
 		tasksContainer,
-		addButton,
+		bottom,
 		controls,
-	))
+	)
+	if sidePanel != nil {
+		content = container.NewBorder(nil, nil, sidePanel, nil, content)
+	}
+
+	setListDropHandler(w, list)
+	setContent(w, content)
+}
+
+// addTaskRows добавляет строки задач, подзадачи - с отступом под родителем.
+// Если order задан, у строк появляются ручки для перестановки среди соседей по уровню
+// и переноса в другие списки; в режиме выбора sel - флажки выбора. changed передаётся строкам.
+func addTaskRows(w fyne.Window, tasksContainer *fyne.Container, tasks []models.Task, list models.TodoList, depth int, order *rowOrder, sel *taskSelection, changed func()) {
+	for i := range tasks {
+		currentTask := &tasks[i]
+		taskRow := newTaskRow(w, currentTask, list, sel, changed)
+		if sel != nil && sel.active {
+			taskRow = container.NewBorder(nil, nil, sel.newCheck(currentTask.ID), nil, taskRow)
+		}
+		if order != nil {
+			taskRow = container.NewBorder(nil, nil, order.handle(currentTask.ID, currentTask.ParentID), nil, taskRow)
+			order.add(currentTask.ID, currentTask.ParentID, taskRow)
+		}
+		tasksContainer.Add(indented(taskRow, depth))
+		addTaskRows(w, tasksContainer, currentTask.Subtasks, list, depth+1, order, sel, changed)
+	}
+}
+
+// indented сдвигает строку вправо на depth уровней вложенности
+func indented(row fyne.CanvasObject, depth int) fyne.CanvasObject {
+	if depth == 0 {
+		return row
+	}
+	indent := canvas.NewRectangle(color.Transparent)
+	indent.SetMinSize(fyne.NewSize(float32(depth)*subtaskIndent, 0))
+	return container.NewBorder(nil, nil, indent, nil, row)
 }
 
 func createTaskRow(w fyne.Window, task *models.Task, list models.TodoList) *fyne.Container {
-	taskBtn := widget.NewButton("", nil)
+	return newTaskRow(w, task, list, nil, nil)
+}
+
+// newTaskRow - строка задачи; если задан sel, нажатие с Shift или Ctrl выбирает задачу,
+// а changed, если задан, вызывается после правок задачи в строке
+func newTaskRow(w fyne.Window, task *models.Task, list models.TodoList, sel *taskSelection, changed func()) *fyne.Container {
+	taskBtn := newSelectButton()
 	taskBtn.Alignment = widget.ButtonAlignLeading
+	commentsLabel := widget.NewLabel("")
 
 	// Функция обновления текста и цвета задачи
 	updateTask := func() {
 		text := task.Title
-		if !task.DueDate.IsZero() {
+		if task.HasDue() {
 			text += " (" + task.DueDate.Format(dateFormat) + ")"
 		}
 		taskBtn.SetText(text)
+		commentsLabel.SetText(commentCountText(task))
 
 		// Устанавливаем цвет в зависимости от статуса и даты
 		if task.IsDone {
 			taskBtn.Importance = widget.LowImportance // Серый для выполненных
-		} else if !task.DueDate.IsZero() && task.DueDate.Before(time.Now()) {
+		} else if task.HasDue() && task.DueDate.Before(time.Now()) {
 			taskBtn.Importance = widget.DangerImportance // Красный для просроченных
 		} else {
 			taskBtn.Importance = widget.MediumImportance // Обычный цвет
@@ -435,26 +869,46 @@ func createTaskRow(w fyne.Window, task *models.Task, list models.TodoList) *fyne
 	}
 	updateTask()
 
+	var row *fyne.Container
 	taskBtn.OnTapped = func() {
-		showTaskDetails(w, task, updateTask)
+		if shift, ctrl := taskBtn.take(); sel != nil && sel.tap(task.ID, shift, ctrl) {
+			return
+		}
+		showTaskDetails(w, task, list, func() {
+			if changed != nil {
+				changed()
+			}
+			// Перенесённая в другой список задача из этого списка пропадает
+			if task.ListID != list.ID {
+				row.Hide()
+				return
+			}
+			updateTask()
+		})
 	}
 
 	check := widget.NewCheck("", func(done bool) {
 		task.IsDone = done
-		if err := db.UpdateTask(task); err != nil {
+		if err := db.UpdateTask(currentUserID, task); err != nil {
 			dialog.ShowError(err, w)
 			return
 		}
 		updateTask() // Обновляем цвет после изменения статуса
+		if changed != nil {
+			changed()
+		}
 	})
 	check.SetChecked(task.IsDone)
+	if !list.Role.CanEdit() {
+		check.Disable()
+	}
 
 	deleteBtn := widget.NewButton("✕", func() {
 		showDeleteConfirmDialog(w,
 			"Удаление",
 			"Удалить задачу?",
 			func() {
-				if err := db.DeleteTask(task.ID); err != nil {
+				if err := db.DeleteTask(currentUserID, task.ID); err != nil {
 					dialog.ShowError(err, w)
 					return
 				}
@@ -462,12 +916,18 @@ func createTaskRow(w fyne.Window, task *models.Task, list models.TodoList) *fyne
 			})
 	})
 
-	return container.NewHBox(
+	if !list.Role.CanEdit() {
+		deleteBtn.Hide()
+	}
+
+	row = container.NewHBox(
 		check,
 		taskBtn,
+		commentsLabel,
 		layout.NewSpacer(),
 		deleteBtn,
 	)
+	return row
 }
 
 func showAddTaskDialog(w fyne.Window, list models.TodoList) {
@@ -524,11 +984,18 @@ func showAddTaskDialog(w fyne.Window, list models.TodoList) {
 		}
 	}
 
+	prioritySelect := newPrioritySelect(0)
+	tagsEntry := newTagsEntry(nil)
+	assigneeSelect, selectedAssignee := newAssigneeSelect(list, 0)
+
 	// Создаем контейнер с формой
 	form := widget.NewForm(
 		widget.NewFormItem("Название:", titleEntry),
 		widget.NewFormItem("Описание:", descEntry),
 		widget.NewFormItem("Срок:", dateEntry),
+		widget.NewFormItem("Приоритет:", prioritySelect),
+		widget.NewFormItem("Теги:", tagsEntry),
+		widget.NewFormItem("Исполнитель:", assigneeSelect),
 	)
 
 	// Создаем кнопки
@@ -572,9 +1039,12 @@ func showAddTaskDialog(w fyne.Window, list models.TodoList) {
 			Description: descEntry.Text,
 			DueDate:     dueDate,
 			CreatedAt:   time.Now(),
+			Priority:    selectedPriority(prioritySelect),
+			Tags:        parseTags(tagsEntry.Text),
+			AssigneeID:  selectedAssignee(),
 		}
 
-		if err := db.CreateTask(&task); err != nil {
+		if err := db.CreateTask(currentUserID, &task); err != nil {
 			dialog.ShowError(err, w)
 			return
 		}
@@ -606,7 +1076,7 @@ func showAddTaskDialog(w fyne.Window, list models.TodoList) {
 	d.Show()
 }
 
-func showTaskDetails(w fyne.Window, task *models.Task, onUpdate func()) {
+func showTaskDetails(w fyne.Window, task *models.Task, list models.TodoList, onUpdate func()) {
 	// Создаем элементы интерфейса
 	titleLabel := widget.NewLabelWithStyle(task.Title, fyne.TextAlignLeading, fyne.TextStyle{Bold: true})
 
@@ -615,7 +1085,7 @@ func showTaskDetails(w fyne.Window, task *models.Task, onUpdate func()) {
 
 	// Создаем лейбл для даты
 	dateText := "Срок не установлен"
-	if !task.DueDate.IsZero() {
+	if task.HasDue() {
 		dateText = "Срок: " + task.DueDate.Format(dateFormat)
 		if task.DueDate.Before(time.Now()) && !task.IsDone {
 			dateText += " (ПРОСРОЧЕНО)"
@@ -623,14 +1093,20 @@ func showTaskDetails(w fyne.Window, task *models.Task, onUpdate func()) {
 	}
 
 	dateLabel := widget.NewLabel(dateText)
-	if !task.DueDate.IsZero() && task.DueDate.Before(time.Now()) && !task.IsDone {
+	if task.HasDue() && task.DueDate.Before(time.Now()) && !task.IsDone {
 		dateLabel = widget.NewLabelWithStyle(dateText, fyne.TextAlignLeading, fyne.TextStyle{Bold: true})
 		dateLabel.Importance = widget.DangerImportance // Устанавливаем красный цвет через Importance
 	}
 
+	// Приоритет и теги
+	metaLabel := widget.NewLabel(taskMetaText(task))
+
+	// Вложения; пока открыт диалог, перетащенные на окно файлы прикрепляются к задаче
+	attachments, attach := newAttachmentsSection(w, task, list)
+
 	// Кнопка редактирования
 	editBtn := widget.NewButton("Редактировать", func() {
-		editTaskDialog(w, task, func() {
+		editTaskDialog(w, task, list, func() {
 			onUpdate()
 			// Обновляем текст
 			titleLabel.SetText(task.Title)
@@ -638,7 +1114,7 @@ func showTaskDetails(w fyne.Window, task *models.Task, onUpdate func()) {
 
 			// Обновляем дату
 			newDateText := "Срок не установлен"
-			if !task.DueDate.IsZero() {
+			if task.HasDue() {
 				newDateText = "Срок: " + task.DueDate.Format(dateFormat)
 				if task.DueDate.Before(time.Now()) && !task.IsDone {
 					newDateText += " (ПРОСРОЧЕНО)"
@@ -647,35 +1123,67 @@ func showTaskDetails(w fyne.Window, task *models.Task, onUpdate func()) {
 			dateLabel.SetText(newDateText)
 
 			// Обновляем стиль
-			if !task.DueDate.IsZero() && task.DueDate.Before(time.Now()) && !task.IsDone {
+			if task.HasDue() && task.DueDate.Before(time.Now()) && !task.IsDone {
 				dateLabel.Importance = widget.DangerImportance
 			} else {
 				dateLabel.Importance = widget.MediumImportance
 			}
+
+			metaLabel.SetText(taskMetaText(task))
+		})
+	})
+	var d *dialog.CustomDialog
+	moveBtn := widget.NewButton("Переместить…", func() {
+		showTransferDialog(w, []int{task.ID}, list, false, func(target models.TodoList) {
+			task.ListID = target.ID
+			d.Hide()
+			onUpdate()
+		})
+	})
+	copyBtn := widget.NewButton("Копировать…", func() {
+		showTransferDialog(w, []int{task.ID}, list, true, func(target models.TodoList) {
+			dialog.ShowInformation("Копирование задач", "Копия добавлена в список "+target.Title, w)
 		})
 	})
+	if !list.Role.CanEdit() {
+		editBtn.Hide()
+		moveBtn.Hide()
+	}
 
 	// Создаем контейнер с содержимым
-	content := container.NewVBox(
+	details := container.NewVBox(
 		titleLabel,
 		widget.NewSeparator(),
 		descLabel,
 		dateLabel,
-		layout.NewSpacer(),
-		editBtn,
+		metaLabel,
+		container.NewHBox(editBtn, moveBtn, copyBtn),
+		widget.NewSeparator(),
+		attachments,
+		widget.NewSeparator(),
 	)
+	content := container.NewBorder(details, nil, nil, nil, newCommentThread(w, task, onUpdate))
 
 	// Создаем и показываем диалог
-	d := dialog.NewCustom(
+	d = dialog.NewCustom(
 		"Детали задачи",
 		"Закрыть",
 		content,
 		w,
 	)
+	if list.Role.CanEdit() {
+		w.SetOnDropped(func(_ fyne.Position, uris []fyne.URI) {
+			attach(uris)
+		})
+		d.SetOnClosed(func() {
+			setListDropHandler(w, list)
+		})
+	}
+	d.Resize(fyne.NewSize(420, 640))
 	d.Show()
 }
 
-func editTaskDialog(w fyne.Window, task *models.Task, onSave func()) {
+func editTaskDialog(w fyne.Window, task *models.Task, list models.TodoList, onSave func()) {
 	titleEntry := widget.NewEntry()
 	titleEntry.SetText(task.Title)
 
@@ -684,7 +1192,7 @@ func editTaskDialog(w fyne.Window, task *models.Task, onSave func()) {
 	descEntry.MultiLine = true
 
 	dateEntry := widget.NewEntry()
-	if !task.DueDate.IsZero() {
+	if task.HasDue() {
 		dateEntry.SetText(task.DueDate.Format(dateFormat))
 	}
 
@@ -732,6 +1240,10 @@ func editTaskDialog(w fyne.Window, task *models.Task, onSave func()) {
 		}
 	}
 
+	prioritySelect := newPrioritySelect(task.Priority)
+	tagsEntry := newTagsEntry(task.Tags)
+	assigneeSelect, selectedAssignee := newAssigneeSelect(list, task.AssigneeID)
+
 	d := dialog.NewForm(
 		"Редактировать",
 		"Сохранить",
@@ -740,6 +1252,9 @@ func editTaskDialog(w fyne.Window, task *models.Task, onSave func()) {
 			{Text: "Название:", Widget: titleEntry},
 			{Text: "Описание:", Widget: descEntry},
 			{Text: "Срок:", Widget: dateEntry},
+			{Text: "Приоритет:", Widget: prioritySelect},
+			{Text: "Теги:", Widget: tagsEntry},
+			{Text: "Исполнитель:", Widget: assigneeSelect},
 		},
 		func(b bool) {
 			if !b {
@@ -757,6 +1272,9 @@ func editTaskDialog(w fyne.Window, task *models.Task, onSave func()) {
 			// Остальная логика сохранения...
 			task.Title = titleEntry.Text
 			task.Description = descEntry.Text
+			task.Priority = selectedPriority(prioritySelect)
+			task.Tags = db.NormalizeTags(parseTags(tagsEntry.Text))
+			task.AssigneeID = selectedAssignee()
 
 			if dateText := dateEntry.Text; dateText != "" {
 				if parsed, err := time.Parse(dateFormat, dateText); err == nil {
@@ -766,7 +1284,7 @@ func editTaskDialog(w fyne.Window, task *models.Task, onSave func()) {
 				task.DueDate = time.Time{}
 			}
 
-			if err := db.UpdateTask(task); err != nil {
+			if err := db.UpdateTask(currentUserID, task); err != nil {
 				dialog.ShowError(err, w)
 				return
 			}
@@ -0,0 +1,67 @@
+// ical.go
+package gui
+
+import (
+	"fmt"
+	"io"
+	"todolist/db"
+	"todolist/ical"
+	"todolist/models"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+)
+
+var icsExtensions = []string{".ics"}
+
+func exportListICal(w fyne.Window, list models.TodoList) {
+	tasks, err := db.GetTasksByList(list.ID)
+	if err != nil {
+		dialog.ShowError(fmt.Errorf("Ошибка загрузки задач: %v", err), w)
+		return
+	}
+
+	lists := []models.ListWithTasks{{TodoList: list, Tasks: tasks}}
+	showSaveFileDialog(w, list.Title+".ics", icsExtensions, func(out io.Writer) error {
+		return ical.Encode(out, list.Title, lists)
+	})
+}
+
+func exportUserICal(w fyne.Window, userID int) {
+	lists, err := db.GetListsWithTasks(userID)
+	if err != nil {
+		dialog.ShowError(fmt.Errorf("Ошибка загрузки задач: %v", err), w)
+		return
+	}
+
+	showSaveFileDialog(w, "tasks.ics", icsExtensions, func(out io.Writer) error {
+		return ical.Encode(out, getUserName(userID), lists)
+	})
+}
+
+func importListICal(w fyne.Window, list models.TodoList) {
+	showOpenFileDialog(w, icsExtensions, func(in fyne.URIReadCloser) error {
+		tasks, err := ical.Decode(in)
+		if err != nil {
+			return fmt.Errorf("Ошибка чтения календаря: %v", err)
+		}
+
+		// Задачи, выгруженные из этого приложения, сопоставляем по ID. Если в списке такой
+		// задачи нет (файл из другого списка), повторный импорт найдёт копию по сохранённому UID
+		for i := range tasks {
+			if id, ok := ical.ParseTaskUID(tasks[i].ExternalUID); ok {
+				tasks[i].ID = id
+			}
+		}
+
+		created, updated, err := db.ImportTasks(currentUserID, list.ID, tasks)
+		if err != nil {
+			return err
+		}
+
+		ShowTodoItems(w, list)
+		dialog.ShowInformation("Импорт",
+			fmt.Sprintf("Добавлено задач: %d\nОбновлено задач: %d", created, updated), w)
+		return nil
+	})
+}
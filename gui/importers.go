@@ -0,0 +1,133 @@
+// importers.go
+package gui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"todolist/db"
+	"todolist/importers"
+	"todolist/models"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+// importSummary - итог загрузки задач в базу
+type importSummary struct {
+	created, updated, newLists int
+}
+
+func (s importSummary) String() string {
+	return fmt.Sprintf("Добавлено задач: %d\nОбновлено задач: %d\nНовых списков: %d", s.created, s.updated, s.newLists)
+}
+
+// importLists загружает списки в базу. Задачи попадают в существующий список
+// с тем же названием, а если такого нет - в новый.
+func importLists(userID int, lists []models.ListWithTasks) (importSummary, error) {
+	var summary importSummary
+
+	existing, err := db.GetTodoLists(userID)
+	if err != nil {
+		return summary, fmt.Errorf("Ошибка загрузки списков: %v", err)
+	}
+	byTitle := make(map[string]models.TodoList)
+	for _, l := range existing {
+		// В списки, открытые только для чтения, импортировать нельзя
+		if l.Role.CanEdit() {
+			byTitle[strings.ToLower(l.Title)] = l
+		}
+	}
+
+	for _, l := range lists {
+		key := strings.ToLower(l.Title)
+		list, ok := byTitle[key]
+		if !ok {
+			list = l.TodoList
+			list.UserID = userID
+			if list.CreatedAt.IsZero() {
+				list.CreatedAt = time.Now()
+			}
+			if err := db.CreateTodoList(&list); err != nil {
+				return summary, err
+			}
+			byTitle[key] = list
+			summary.newLists++
+		}
+
+		created, updated, err := db.ImportTasks(userID, list.ID, l.Tasks)
+		if err != nil {
+			return summary, err
+		}
+		summary.created += created
+		summary.updated += updated
+	}
+	return summary, nil
+}
+
+func importTodoist(w fyne.Window, userID int) {
+	showOpenFileDialog(w, []string{".json", ".csv", ".zip"}, func(in fyne.URIReadCloser) error {
+		res, err := importers.ParseTodoist(in, in.URI().Name())
+		if err != nil {
+			return err
+		}
+		showImportResult(w, userID, "Импорт из Todoist", res)
+		return nil
+	})
+}
+
+func importTrello(w fyne.Window, userID int) {
+	showOpenFileDialog(w, []string{".json"}, func(in fyne.URIReadCloser) error {
+		res, err := importers.ParseTrello(in, in.URI().Name())
+		if err != nil {
+			return err
+		}
+		showImportResult(w, userID, "Импорт из Trello", res)
+		return nil
+	})
+}
+
+// showImportResult спрашивает подтверждение, загружает данные и показывает отчёт
+// со всем, что не удалось перенести
+func showImportResult(w fyne.Window, userID int, title string, res *importers.Result) {
+	message := fmt.Sprintf("Найдено списков: %d, задач: %d", len(res.Lists), res.TaskCount())
+	if len(res.Unmapped) > 0 {
+		message += fmt.Sprintf("\nНе удастся перенести: %d", len(res.Unmapped))
+	}
+
+	dialog.ShowConfirm(title, message+"\n\nИмпортировать?", func(ok bool) {
+		if !ok {
+			return
+		}
+
+		summary, err := importLists(userID, res.Lists)
+		if err != nil {
+			dialog.ShowError(err, w)
+			return
+		}
+
+		ShowTodoLists(w, userID)
+		showReport(w, title, summary.String(), res.Unmapped)
+	}, w)
+}
+
+// showReport показывает итог импорта и прокручиваемый перечень пропущенного
+func showReport(w fyne.Window, title, summary string, skipped []string) {
+	if len(skipped) == 0 {
+		dialog.ShowInformation(title, summary, w)
+		return
+	}
+
+	details := widget.NewLabel(strings.Join(skipped, "\n"))
+	details.Wrapping = fyne.TextWrapWord
+	content := container.NewBorder(
+		widget.NewLabel(summary+"\n\nНе перенесено:"), nil, nil, nil,
+		container.NewVScroll(details),
+	)
+
+	d := dialog.NewCustom(title, "Закрыть", content, w)
+	d.Resize(fyne.NewSize(520, 420))
+	d.Show()
+}
@@ -0,0 +1,284 @@
+// kanban.go
+package gui
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"todolist/db"
+	"todolist/models"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+// kanbanColumn - колонка доски, нужна для поиска колонки под отпущенной карточкой
+type kanbanColumn struct {
+	status models.Status
+	obj    fyne.CanvasObject
+}
+
+// ShowKanban показывает задачи списка колонками по статусам; карточки перетаскиваются между колонками
+func ShowKanban(w fyne.Window, list models.TodoList) {
+	statuses, err := db.GetListStatuses(list.ID)
+	if err != nil {
+		dialog.ShowError(fmt.Errorf("Ошибка загрузки колонок: %v", err), w)
+		return
+	}
+	tasks, err := db.GetTasksByList(list.ID)
+	if err != nil {
+		dialog.ShowError(fmt.Errorf("Ошибка загрузки задач: %v", err), w)
+		return
+	}
+
+	byStatus := make(map[int][]*models.Task)
+	for i := range tasks {
+		byStatus[tasks[i].StatusID] = append(byStatus[tasks[i].StatusID], &tasks[i])
+	}
+
+	refresh := func() {
+		ShowKanban(w, list)
+	}
+
+	var columns []kanbanColumn
+	// move переносит задачу в колонку под точкой pos
+	move := func(task *models.Task, pos fyne.Position) {
+		driver := fyne.CurrentApp().Driver()
+		for _, col := range columns {
+			topLeft := driver.AbsolutePositionForObject(col.obj)
+			size := col.obj.Size()
+			if pos.X < topLeft.X || pos.X > topLeft.X+size.Width || pos.Y < topLeft.Y || pos.Y > topLeft.Y+size.Height {
+				continue
+			}
+			if col.status.ID == task.StatusID {
+				return
+			}
+			if err := db.SetTaskStatus(currentUserID, task.ID, col.status.ID); err != nil {
+				if errors.Is(err, db.ErrWIPLimit) {
+					dialog.ShowInformation("Колонка заполнена", err.Error(), w)
+				} else {
+					dialog.ShowError(err, w)
+				}
+			}
+			refresh()
+			return
+		}
+	}
+
+	board := container.NewGridWithColumns(len(statuses))
+	for _, s := range statuses {
+		s := s
+		columnTasks := byStatus[s.ID]
+
+		heading := s.Title + " " + strconv.Itoa(len(columnTasks))
+		if s.WIPLimit > 0 {
+			heading += "/" + strconv.Itoa(s.WIPLimit)
+		}
+		header := canvas.NewText(heading, theme.Color(theme.ColorNameForeground))
+		header.TextStyle = fyne.TextStyle{Bold: true}
+		header.Alignment = fyne.TextAlignCenter
+		if s.WIPLimit > 0 && len(columnTasks) >= s.WIPLimit {
+			header.Color = theme.Color(theme.ColorNameError)
+		}
+
+		cards := container.NewVBox()
+		for _, task := range columnTasks {
+			task := task
+			text := task.Title
+			if task.HasDue() {
+				text += " (" + task.DueDate.Format(dateFormat) + ")"
+			}
+			onDrop := func(pos fyne.Position) { move(task, pos) }
+			if !list.Role.CanEdit() {
+				onDrop = func(fyne.Position) {}
+			}
+			cards.Add(newTaskChip(text, func() {
+				showTaskDetails(w, task, list, refresh)
+			}, onDrop))
+		}
+
+		border := canvas.NewRectangle(theme.Color(theme.ColorNameBackground))
+		border.StrokeColor = theme.Color(theme.ColorNameSeparator)
+		border.StrokeWidth = 1
+		column := container.NewStack(border, container.NewBorder(header, nil, nil, nil, container.NewVScroll(cards)))
+		columns = append(columns, kanbanColumn{status: s, obj: column})
+		board.Add(column)
+	}
+
+	backButton := widget.NewButton("← Назад", func() {
+		ShowTodoItems(w, list)
+	})
+	addButton := widget.NewButton("+ Добавить задачу", func() {
+		showAddTaskDialog(w, list)
+	})
+	if !list.Role.CanEdit() {
+		addButton.Disable()
+	}
+	controls := container.NewHBox(backButton, layout.NewSpacer(), addButton)
+	if list.Role == models.RoleOwner {
+		controls.Add(widget.NewButton("Колонки…", func() {
+			showStatusesDialog(w, list, refresh)
+		}))
+	}
+
+	w.SetOnDropped(nil)
+	setContent(w, container.NewBorder(
+		widget.NewLabelWithStyle(list.Title, fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+		container.NewVBox(widget.NewLabel("Перетащите карточку в другую колонку, чтобы сменить статус"), controls),
+		nil, nil,
+		board,
+	))
+}
+
+// showStatusesDialog настраивает колонки доски: название, порядок, предел и признак выполненных задач
+func showStatusesDialog(w fyne.Window, list models.TodoList, onChange func()) {
+	statusesContainer := container.NewVBox()
+	changed := false
+
+	var refresh func()
+	refresh = func() {
+		statuses, err := db.GetListStatuses(list.ID)
+		if err != nil {
+			dialog.ShowError(fmt.Errorf("Ошибка загрузки колонок: %v", err), w)
+			return
+		}
+
+		// reorder ставит колонку i на место j
+		reorder := func(i, j int) {
+			ids := make([]int, len(statuses))
+			for k, s := range statuses {
+				ids[k] = s.ID
+			}
+			ids[i], ids[j] = ids[j], ids[i]
+			if err := db.ReorderStatuses(currentUserID, list.ID, ids); err != nil {
+				dialog.ShowError(err, w)
+				return
+			}
+			changed = true
+			refresh()
+		}
+
+		statusesContainer.RemoveAll()
+		for i, s := range statuses {
+			i, current := i, s
+
+			var marks []string
+			if current.WIPLimit > 0 {
+				marks = append(marks, "не больше "+strconv.Itoa(current.WIPLimit))
+			}
+			if current.IsFinal {
+				marks = append(marks, "выполнено")
+			}
+			text := current.Title
+			if len(marks) > 0 {
+				text += " (" + strings.Join(marks, ", ") + ")"
+			}
+
+			upBtn := widget.NewButton("↑", func() { reorder(i, i-1) })
+			if i == 0 {
+				upBtn.Disable()
+			}
+			downBtn := widget.NewButton("↓", func() { reorder(i, i+1) })
+			if i == len(statuses)-1 {
+				downBtn.Disable()
+			}
+			editBtn := widget.NewButton("✎", func() {
+				editStatusDialog(w, list, &current, func() {
+					changed = true
+					refresh()
+				})
+			})
+			deleteBtn := widget.NewButton("✕", func() {
+				showDeleteConfirmDialog(w, "Удаление колонки",
+					"Удалить колонку "+current.Title+"? Её задачи перейдут в первую колонку того же вида.",
+					func() {
+						if err := db.DeleteStatus(currentUserID, current.ID); err != nil {
+							dialog.ShowError(err, w)
+							return
+						}
+						changed = true
+						refresh()
+					})
+			})
+
+			statusesContainer.Add(container.NewHBox(widget.NewLabel(text), layout.NewSpacer(), upBtn, downBtn, editBtn, deleteBtn))
+		}
+	}
+	refresh()
+
+	addBtn := widget.NewButton("+ Колонка", func() {
+		editStatusDialog(w, list, nil, func() {
+			changed = true
+			refresh()
+		})
+	})
+
+	d := dialog.NewCustom("Колонки доски", "Закрыть", container.NewBorder(
+		nil,
+		container.NewHBox(layout.NewSpacer(), addBtn),
+		nil, nil,
+		container.NewVScroll(statusesContainer),
+	), w)
+	d.SetOnClosed(func() {
+		if changed {
+			onChange()
+		}
+	})
+	d.Resize(fyne.NewSize(520, 360))
+	d.Show()
+}
+
+// editStatusDialog создаёт колонку (status == nil) или меняет существующую
+func editStatusDialog(w fyne.Window, list models.TodoList, status *models.Status, onSave func()) {
+	titleEntry := widget.NewEntry()
+	titleEntry.SetPlaceHolder("Название")
+	limitEntry := widget.NewEntry()
+	limitEntry.SetPlaceHolder("0 - без ограничения")
+	finalCheck := widget.NewCheck("Задачи в колонке выполнены", nil)
+	title := "Новая колонка"
+	if status != nil {
+		titleEntry.SetText(status.Title)
+		if status.WIPLimit > 0 {
+			limitEntry.SetText(strconv.Itoa(status.WIPLimit))
+		}
+		finalCheck.SetChecked(status.IsFinal)
+		title = "Колонка"
+	}
+
+	dialog.ShowForm(title, "Сохранить", "Отмена", []*widget.FormItem{
+		widget.NewFormItem("Название:", titleEntry),
+		widget.NewFormItem("Предел задач:", limitEntry),
+		widget.NewFormItem("", finalCheck),
+	}, func(ok bool) {
+		if !ok {
+			return
+		}
+		limit := 0
+		if text := strings.TrimSpace(limitEntry.Text); text != "" {
+			var err error
+			if limit, err = strconv.Atoi(text); err != nil {
+				dialog.ShowError(fmt.Errorf("предел задач должен быть числом"), w)
+				return
+			}
+		}
+		s := models.Status{ListID: list.ID, Title: titleEntry.Text, WIPLimit: limit, IsFinal: finalCheck.Checked}
+		var err error
+		if status != nil {
+			s.ID = status.ID
+			err = db.UpdateStatus(currentUserID, &s)
+		} else {
+			err = db.CreateStatus(currentUserID, &s)
+		}
+		if err != nil {
+			dialog.ShowError(err, w)
+			return
+		}
+		onSave()
+	}, w)
+}
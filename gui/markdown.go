@@ -0,0 +1,142 @@
+// markdown.go
+package gui
+
+import (
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"time"
+	"todolist/db"
+	"todolist/markdown"
+	"todolist/models"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/storage"
+)
+
+var markdownExtensions = []string{".md", ".markdown"}
+
+func exportListMarkdown(w fyne.Window, list models.TodoList) {
+	tasks, err := db.GetTasksByList(list.ID)
+	if err != nil {
+		dialog.ShowError(fmt.Errorf("Ошибка загрузки задач: %v", err), w)
+		return
+	}
+
+	lists := []models.ListWithTasks{{TodoList: list, Tasks: tasks}}
+	showSaveFileDialog(w, list.Title+".md", markdownExtensions, func(out io.Writer) error {
+		return markdown.Write(out, lists)
+	})
+}
+
+func exportUserMarkdown(w fyne.Window, userID int) {
+	lists, err := db.GetListsWithTasks(userID)
+	if err != nil {
+		dialog.ShowError(fmt.Errorf("Ошибка загрузки задач: %v", err), w)
+		return
+	}
+
+	showSaveFileDialog(w, "tasks.md", markdownExtensions, func(out io.Writer) error {
+		return markdown.Write(out, lists)
+	})
+}
+
+func importMarkdownDialog(w fyne.Window, userID int, defaultList *models.TodoList) {
+	showOpenFileDialog(w, markdownExtensions, func(in fyne.URIReadCloser) error {
+		return importMarkdown(w, userID, defaultList, in, in.URI().Name())
+	})
+}
+
+// setListDropHandler - перетаскивание на экран списка: .md файлы импортируются в него
+func setListDropHandler(w fyne.Window, list models.TodoList) {
+	if list.Role.CanEdit() {
+		setMarkdownDropHandler(w, currentUserID, &list)
+	} else {
+		w.SetOnDropped(nil)
+	}
+}
+
+// setMarkdownDropHandler разрешает перетащить .md файлы на окно
+func setMarkdownDropHandler(w fyne.Window, userID int, defaultList *models.TodoList) {
+	w.SetOnDropped(func(_ fyne.Position, uris []fyne.URI) {
+		for _, uri := range uris {
+			if !isMarkdownFile(uri) {
+				continue
+			}
+			in, err := storage.Reader(uri)
+			if err != nil {
+				dialog.ShowError(err, w)
+				continue
+			}
+			err = importMarkdown(w, userID, defaultList, in, uri.Name())
+			in.Close()
+			if err != nil {
+				dialog.ShowError(err, w)
+			}
+		}
+	})
+}
+
+func isMarkdownFile(uri fyne.URI) bool {
+	ext := strings.ToLower(uri.Extension())
+	for _, e := range markdownExtensions {
+		if ext == e {
+			return true
+		}
+	}
+	return false
+}
+
+// importMarkdown создаёт по списку на каждый заголовок файла. Пункты до первого заголовка
+// попадают в defaultList, а если он не задан - в новый список с именем файла.
+func importMarkdown(w fyne.Window, userID int, defaultList *models.TodoList, in io.Reader, fileName string) error {
+	doc, err := markdown.Parse(in)
+	if err != nil {
+		return fmt.Errorf("Ошибка чтения Markdown: %v", err)
+	}
+
+	lists := doc.Lists
+	var target *models.TodoList
+	if len(doc.Untitled) > 0 {
+		if defaultList != nil {
+			target = defaultList
+		} else {
+			title := strings.TrimSuffix(fileName, path.Ext(fileName))
+			lists = append([]models.ListWithTasks{{TodoList: models.TodoList{Title: title, CreatedAt: time.Now()}, Tasks: doc.Untitled}}, lists...)
+		}
+	}
+
+	var created, updated int
+	if target != nil {
+		c, u, err := db.ImportTasks(userID, target.ID, doc.Untitled)
+		if err != nil {
+			return err
+		}
+		created, updated = c, u
+	}
+
+	for _, l := range lists {
+		list := l.TodoList
+		list.UserID = userID
+		if err := db.CreateTodoList(&list); err != nil {
+			return err
+		}
+		c, u, err := db.ImportTasks(userID, list.ID, l.Tasks)
+		if err != nil {
+			return err
+		}
+		created += c
+		updated += u
+	}
+
+	if defaultList != nil {
+		ShowTodoItems(w, *defaultList)
+	} else {
+		ShowTodoLists(w, userID)
+	}
+	dialog.ShowInformation("Импорт Markdown",
+		fmt.Sprintf("%s\nНовых списков: %d\nДобавлено задач: %d\nОбновлено задач: %d", fileName, len(lists), created, updated), w)
+	return nil
+}
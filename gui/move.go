@@ -0,0 +1,126 @@
+// move.go
+package gui
+
+import (
+	"fmt"
+	"todolist/db"
+	"todolist/models"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+// newListSelect возвращает выбор среди списков вне архива, в которые можно добавлять задачи, кроме excludeID,
+// и функцию, возвращающую выбранный список; nil, если таких списков нет
+func newListSelect(lists []models.TodoList, excludeID int) (*widget.Select, func() models.TodoList) {
+	var (
+		options  []string
+		editable = make(map[string]models.TodoList)
+	)
+	for _, l := range lists {
+		if l.ID == excludeID || !l.Role.CanEdit() || !l.ArchivedAt.IsZero() {
+			continue
+		}
+		option := l.Title
+		if _, exists := editable[option]; exists {
+			option = fmt.Sprintf("%s (ID %d)", l.Title, l.ID)
+		}
+		options = append(options, option)
+		editable[option] = l
+	}
+	if len(options) == 0 {
+		return nil, nil
+	}
+	listSelect := widget.NewSelect(options, nil)
+	listSelect.SetSelected(options[0])
+	return listSelect, func() models.TodoList { return editable[listSelect.Selected] }
+}
+
+// showTransferDialog переносит (или копирует при copying) задачи с подзадачами в другой список
+func showTransferDialog(w fyne.Window, taskIDs []int, list models.TodoList, copying bool, onDone func(target models.TodoList)) {
+	lists, err := db.GetTodoLists(currentUserID)
+	if err != nil {
+		dialog.ShowError(fmt.Errorf("Ошибка загрузки списков: %v", err), w)
+		return
+	}
+	title, confirm := "Перенос задач", "Перенести"
+	if copying {
+		title, confirm = "Копирование задач", "Копировать"
+	}
+	listSelect, selected := newListSelect(lists, list.ID)
+	if listSelect == nil {
+		dialog.ShowInformation(title, "Нет других списков, в которые можно добавить задачи", w)
+		return
+	}
+
+	dialog.ShowForm(title, confirm, "Отмена", []*widget.FormItem{
+		widget.NewFormItem("В список:", listSelect),
+	}, func(ok bool) {
+		if !ok {
+			return
+		}
+		target := selected()
+		transfer := db.MoveTasks
+		if copying {
+			transfer = db.CopyTasks
+		}
+		if err := transfer(currentUserID, taskIDs, target.ID); err != nil {
+			dialog.ShowError(err, w)
+			return
+		}
+		onDone(target)
+	}, w)
+}
+
+// newMoveTargets строит боковую панель с другими списками пользователя: нажатие открывает список,
+// а задача, отпущенная над ним, переносится туда вместе с подзадачами
+func newMoveTargets(w fyne.Window, list models.TodoList) (fyne.CanvasObject, func(taskID int, pos fyne.Position)) {
+	lists, err := db.GetTodoLists(currentUserID)
+	if err != nil {
+		dialog.ShowError(fmt.Errorf("Ошибка загрузки списков: %v", err), w)
+		return nil, nil
+	}
+
+	type moveTarget struct {
+		list models.TodoList
+		obj  fyne.CanvasObject
+	}
+	var targets []moveTarget
+	panel := container.NewVBox(widget.NewLabelWithStyle("Перенести в:", fyne.TextAlignLeading, fyne.TextStyle{Italic: true}))
+	for _, l := range lists {
+		if l.ID == list.ID || !l.Role.CanEdit() || !l.ArchivedAt.IsZero() {
+			continue
+		}
+		target := l
+		btn := widget.NewButton(target.Title, func() {
+			ShowTodoItems(w, target)
+		})
+		btn.Alignment = widget.ButtonAlignLeading
+		btn.Importance = widget.LowImportance
+		targets = append(targets, moveTarget{list: target, obj: btn})
+		panel.Add(btn)
+	}
+	if len(targets) == 0 {
+		return nil, nil
+	}
+
+	drop := func(taskID int, pos fyne.Position) {
+		driver := fyne.CurrentApp().Driver()
+		for _, t := range targets {
+			topLeft := driver.AbsolutePositionForObject(t.obj)
+			size := t.obj.Size()
+			if pos.X < topLeft.X || pos.X > topLeft.X+size.Width || pos.Y < topLeft.Y || pos.Y > topLeft.Y+size.Height {
+				continue
+			}
+			if err := db.MoveTasks(currentUserID, []int{taskID}, t.list.ID); err != nil {
+				dialog.ShowError(err, w)
+				return
+			}
+			ShowTodoItems(w, list)
+			return
+		}
+	}
+	return container.NewVScroll(panel), drop
+}
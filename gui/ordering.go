@@ -0,0 +1,93 @@
+// ordering.go
+package gui
+
+import (
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+// dragHandle - ручка в начале строки, за которую строку перетаскивают на новое место
+type dragHandle struct {
+	widget.BaseWidget
+	background *canvas.Rectangle
+	onDrop     func(pos fyne.Position)
+	dropPos    fyne.Position
+	dragging   bool
+}
+
+func newDragHandle(onDrop func(fyne.Position)) *dragHandle {
+	h := &dragHandle{
+		background: canvas.NewRectangle(theme.Color(theme.ColorNameBackground)),
+		onDrop:     onDrop,
+	}
+	h.background.CornerRadius = theme.InputRadiusSize()
+	h.ExtendBaseWidget(h)
+	return h
+}
+
+func (h *dragHandle) CreateRenderer() fyne.WidgetRenderer {
+	return widget.NewSimpleRenderer(container.NewStack(h.background, widget.NewLabel("☰")))
+}
+
+func (h *dragHandle) Dragged(e *fyne.DragEvent) {
+	if !h.dragging {
+		h.dragging = true
+		h.background.FillColor = theme.Color(theme.ColorNameFocus)
+		h.background.Refresh()
+	}
+	h.dropPos = e.AbsolutePosition
+}
+
+func (h *dragHandle) DragEnd() {
+	if !h.dragging {
+		return
+	}
+	h.dragging = false
+	h.background.FillColor = theme.Color(theme.ColorNameBackground)
+	h.background.Refresh()
+	h.onDrop(h.dropPos)
+}
+
+// orderedRow - строка, которую можно переставить; group - строки меняются местами
+// только внутри своей группы (например, подзадачи одного родителя)
+type orderedRow struct {
+	id, group int
+	obj       fyne.CanvasObject
+}
+
+// rowOrder собирает строки экрана и переносит строку на место той, на которую её отпустили:
+// в верхнюю половину строки - перед ней, в нижнюю - после. Без move строки не переставляются;
+// outside получает строки, отпущенные мимо остальных строк
+type rowOrder struct {
+	rows    []orderedRow
+	move    func(id, targetID int, after bool)
+	outside func(id int, pos fyne.Position)
+}
+
+// handle возвращает ручку для строки id; саму строку нужно добавить через add
+func (o *rowOrder) handle(id, group int) *dragHandle {
+	return newDragHandle(func(pos fyne.Position) {
+		driver := fyne.CurrentApp().Driver()
+		for _, row := range o.rows {
+			topLeft := driver.AbsolutePositionForObject(row.obj)
+			size := row.obj.Size()
+			if pos.X < topLeft.X || pos.X > topLeft.X+size.Width || pos.Y < topLeft.Y || pos.Y > topLeft.Y+size.Height {
+				continue
+			}
+			if o.move != nil && row.group == group && row.id != id {
+				o.move(id, row.id, pos.Y > topLeft.Y+size.Height/2)
+			}
+			return
+		}
+		if o.outside != nil {
+			o.outside(id, pos)
+		}
+	})
+}
+
+func (o *rowOrder) add(id, group int, obj fyne.CanvasObject) {
+	o.rows = append(o.rows, orderedRow{id: id, group: group, obj: obj})
+}
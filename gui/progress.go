@@ -0,0 +1,70 @@
+// progress.go
+package gui
+
+import (
+	"fmt"
+	"time"
+	"todolist/models"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+)
+
+const listProgressWidth = 100
+
+func overdueLabel(n int) *widget.Label {
+	label := widget.NewLabel(fmt.Sprintf("просрочено: %d", n))
+	label.Importance = widget.DangerImportance
+	return label
+}
+
+// newListProgress показывает, сколько задач списка осталось, и долю выполненных
+func newListProgress(c models.ListCounts) []fyne.CanvasObject {
+	if c.Total == 0 {
+		return nil
+	}
+	bar := widget.NewProgressBar()
+	bar.SetValue(c.Progress())
+	objects := []fyne.CanvasObject{
+		widget.NewLabel(fmt.Sprintf("осталось %d из %d", c.Open, c.Total)),
+		container.NewGridWrap(fyne.NewSize(listProgressWidth, bar.MinSize().Height), bar),
+	}
+	if c.Overdue > 0 {
+		objects = append(objects, overdueLabel(c.Overdue))
+	}
+	return objects
+}
+
+// listProgress - сводка по задачам на экране списка. Строки задач меняют задачи дерева roots
+// на месте, поэтому после правки достаточно пересчитать его (refresh)
+type listProgress struct {
+	box    *fyne.Container
+	roots  []models.Task
+	listID int
+}
+
+func (p *listProgress) refresh() {
+	var count func(tasks []models.Task) models.ListCounts
+	count = func(tasks []models.Task) models.ListCounts {
+		var c models.ListCounts
+		now := time.Now()
+		for _, task := range tasks {
+			// Задача, перенесённая в другой список, уходит вместе с подзадачами
+			if task.ListID != p.listID {
+				continue
+			}
+			c.Total++
+			if !task.IsDone {
+				c.Open++
+				if task.HasDue() && task.DueDate.Before(now) {
+					c.Overdue++
+				}
+			}
+			c = c.Add(count(task.Subtasks))
+		}
+		return c
+	}
+	p.box.Objects = newListProgress(count(p.roots))
+	p.box.Refresh()
+}
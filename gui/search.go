@@ -0,0 +1,97 @@
+// search.go
+package gui
+
+import (
+	"fmt"
+	"todolist/db"
+	"todolist/models"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/widget"
+)
+
+// newSearchBar - строка поиска задач по всем спискам пользователя
+func newSearchBar(w fyne.Window, userID int, query string) fyne.CanvasObject {
+	entry := widget.NewEntry()
+	entry.SetPlaceHolder("Поиск задач: слова, \"фраза\", -исключить")
+	entry.SetText(query)
+
+	search := func() {
+		if entry.Text != "" {
+			ShowSearchResults(w, userID, entry.Text)
+		}
+	}
+	entry.OnSubmitted = func(string) { search() }
+
+	return container.NewBorder(nil, nil, nil, widget.NewButton("🔍", search), entry)
+}
+
+// ShowSearchResults показывает найденные задачи; выбор задачи открывает её в своём списке
+func ShowSearchResults(w fyne.Window, userID int, query string) {
+	results, err := db.SearchTasks(userID, query)
+	if err != nil {
+		dialog.ShowError(fmt.Errorf("Ошибка поиска: %v", err), w)
+		return
+	}
+	lists, err := db.GetTodoLists(userID)
+	if err != nil {
+		dialog.ShowError(fmt.Errorf("Ошибка загрузки списков: %v", err), w)
+		return
+	}
+	byID := make(map[int]models.TodoList, len(lists))
+	for _, l := range lists {
+		byID[l.ID] = l
+	}
+
+	resultsContainer := container.NewVBox()
+	for _, r := range results {
+		task := r.Task
+		list := byID[task.ListID]
+
+		taskBtn := widget.NewButton(task.Title, func() {
+			openTaskInList(w, &task, list)
+		})
+		taskBtn.Alignment = widget.ButtonAlignLeading
+		if task.IsDone {
+			taskBtn.Importance = widget.LowImportance
+		}
+
+		resultsContainer.Add(taskBtn)
+		resultsContainer.Add(widget.NewLabelWithStyle(r.ListTitle, fyne.TextAlignLeading, fyne.TextStyle{Italic: true}))
+		if r.Snippet != "" {
+			snippet := widget.NewLabel(r.Snippet)
+			snippet.Wrapping = fyne.TextWrapWord
+			resultsContainer.Add(snippet)
+		}
+		resultsContainer.Add(widget.NewSeparator())
+	}
+	if len(results) == 0 {
+		resultsContainer.Add(widget.NewLabel("Ничего не найдено"))
+	}
+
+	backButton := widget.NewButton("← Назад", func() {
+		ShowTodoLists(w, userID)
+	})
+
+	w.SetOnDropped(nil)
+	setContent(w, container.NewBorder(
+		container.NewVBox(
+			widget.NewLabelWithStyle("Поиск", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+			newSearchBar(w, userID, query),
+		),
+		container.NewHBox(backButton, layout.NewSpacer()),
+		nil, nil,
+		container.NewVScroll(resultsContainer),
+	))
+}
+
+// openTaskInList открывает список и сразу карточку задачи в нём
+func openTaskInList(w fyne.Window, task *models.Task, list models.TodoList) {
+	ShowTodoItems(w, list)
+	showTaskDetails(w, task, list, func() {
+		ShowTodoItems(w, list)
+	})
+}
@@ -0,0 +1,286 @@
+// selection.go
+package gui
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"todolist/db"
+	"todolist/models"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/driver/desktop"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/widget"
+)
+
+// taskSelection - выбранные задачи на экране списка. В режим выбора входят кнопкой
+// "Выбрать" или нажатием на задачу с Ctrl (по одной) или Shift (диапазоном).
+type taskSelection struct {
+	active   bool
+	ids      map[int]bool
+	order    []int // задачи в порядке строк, для выбора диапазона
+	anchor   int   // задача, от которой Shift выбирает диапазон
+	checks   map[int]*selectCheck
+	updating bool
+	count    *widget.Label
+	redraw   func() // перерисовывает экран списка, сохраняя выбор
+}
+
+func newTaskSelection() *taskSelection {
+	return &taskSelection{ids: make(map[int]bool), checks: make(map[int]*selectCheck)}
+}
+
+// tap обрабатывает нажатие на задачу; false - нажатие не относится к выбору
+func (s *taskSelection) tap(id int, shift, ctrl bool) bool {
+	if !s.active && !shift && !ctrl {
+		return false
+	}
+	if shift && s.anchor != 0 {
+		s.selectRange(s.anchor, id)
+	} else {
+		s.ids[id] = !s.ids[id]
+		s.anchor = id
+	}
+	if !s.active {
+		s.active = true
+		s.redraw()
+		return true
+	}
+	s.sync()
+	return true
+}
+
+// newCheck возвращает флажок выбора для строки задачи id
+func (s *taskSelection) newCheck(id int) *selectCheck {
+	s.order = append(s.order, id)
+	check := &selectCheck{}
+	check.ExtendBaseWidget(check)
+	check.OnChanged = func(on bool) {
+		if s.updating {
+			return
+		}
+		if shift, _ := check.take(); shift && s.anchor != 0 {
+			s.selectRange(s.anchor, id)
+		} else {
+			s.ids[id] = on
+			s.anchor = id
+		}
+		s.sync()
+	}
+	check.SetChecked(s.ids[id])
+	s.checks[id] = check
+	return check
+}
+
+// tapModifiers запоминает Shift и Ctrl (Cmd на macOS) при нажатии мыши: fyne передаёт
+// модификаторы в MouseDown, но не в Tapped, который приходит следом
+type tapModifiers struct {
+	modifier fyne.KeyModifier
+}
+
+func (t *tapModifiers) MouseDown(e *desktop.MouseEvent) {
+	t.modifier = e.Modifier
+	touchActivity()
+}
+
+func (t *tapModifiers) MouseUp(*desktop.MouseEvent) {}
+
+// take возвращает модификаторы последнего нажатия и сбрасывает их, чтобы нажатие
+// с клавиатуры не унаследовало их
+func (t *tapModifiers) take() (shift, ctrl bool) {
+	m := t.modifier
+	t.modifier = 0
+	return m&fyne.KeyModifierShift != 0, m&(fyne.KeyModifierControl|fyne.KeyModifierSuper) != 0
+}
+
+// selectButton - кнопка задачи, знающая модификаторы нажатия
+type selectButton struct {
+	widget.Button
+	tapModifiers
+}
+
+func newSelectButton() *selectButton {
+	b := &selectButton{}
+	b.ExtendBaseWidget(b)
+	return b
+}
+
+// selectCheck - флажок выбора задачи, знающий модификаторы нажатия
+type selectCheck struct {
+	widget.Check
+	tapModifiers
+}
+
+// selectRange выбирает задачи от from до to включительно в порядке строк
+func (s *taskSelection) selectRange(from, to int) {
+	start, end := -1, -1
+	for i, id := range s.order {
+		if id == from {
+			start = i
+		}
+		if id == to {
+			end = i
+		}
+	}
+	if start < 0 || end < 0 {
+		s.ids[to] = true
+		return
+	}
+	if start > end {
+		start, end = end, start
+	}
+	for _, id := range s.order[start : end+1] {
+		s.ids[id] = true
+	}
+}
+
+// sync приводит флажки и счётчик в соответствие с выбором
+func (s *taskSelection) sync() {
+	s.updating = true
+	for id, check := range s.checks {
+		check.SetChecked(s.ids[id])
+	}
+	s.updating = false
+	if s.count != nil {
+		s.count.SetText(fmt.Sprintf("Выбрано: %d", len(s.selected())))
+	}
+}
+
+func (s *taskSelection) selected() []int {
+	var ids []int
+	for _, id := range s.order {
+		if s.ids[id] {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// toolbar - панель групповых действий над выбранными задачами
+func (s *taskSelection) toolbar(w fyne.Window, list models.TodoList) fyne.CanvasObject {
+	s.count = widget.NewLabel("")
+	s.sync()
+
+	// chosen возвращает выбранные задачи или подсказывает, что выбора нет
+	chosen := func() ([]int, bool) {
+		ids := s.selected()
+		if len(ids) == 0 {
+			dialog.ShowInformation("Групповые действия", "Сначала выберите задачи", w)
+		}
+		return ids, len(ids) > 0
+	}
+	// run выполняет действие над выбранными задачами и снимает выбор
+	run := func(action func(ids []int) error) {
+		ids, ok := chosen()
+		if !ok {
+			return
+		}
+		if err := action(ids); err != nil {
+			dialog.ShowError(err, w)
+			return
+		}
+		s.ids = make(map[int]bool)
+		s.redraw()
+	}
+
+	allBtn := widget.NewButton("Все", func() {
+		all := len(s.selected()) < len(s.order)
+		for _, id := range s.order {
+			s.ids[id] = all
+		}
+		s.sync()
+	})
+	completeBtn := widget.NewButton("Выполнить", func() {
+		run(func(ids []int) error { return db.CompleteTasks(currentUserID, ids, true) })
+	})
+	deleteBtn := widget.NewButton("Удалить", func() {
+		ids, ok := chosen()
+		if !ok {
+			return
+		}
+		showDeleteConfirmDialog(w, "Удаление задач",
+			fmt.Sprintf("Удалить выбранные задачи (%d) вместе с подзадачами? Удаление можно отменить.", len(ids)),
+			func() {
+				run(func(ids []int) error { return db.DeleteTasks(currentUserID, ids) })
+			})
+	})
+	moreBtn := newMenuButton(w, "Ещё…",
+		fyne.NewMenuItem("Вернуть в работу", func() {
+			run(func(ids []int) error { return db.CompleteTasks(currentUserID, ids, false) })
+		}),
+		fyne.NewMenuItem("Срок…", func() {
+			askValue(w, "Срок для выбранных задач", "дд.мм.гггг, пусто - без срока", "", func(text string) {
+				due := time.Time{}
+				if text != "" {
+					parsed, err := time.Parse(dateFormat, text)
+					if err != nil {
+						dialog.ShowError(fmt.Errorf("неверная дата. Формат: дд.мм.гггг"), w)
+						return
+					}
+					due = parsed
+				}
+				run(func(ids []int) error { return db.SetTasksDue(currentUserID, ids, due) })
+			})
+		}),
+		fyne.NewMenuItem("Отложить…", func() {
+			askValue(w, "Отложить на дней", "число дней", "1", func(text string) {
+				days, err := strconv.Atoi(text)
+				if err != nil {
+					dialog.ShowError(fmt.Errorf("введите число дней"), w)
+					return
+				}
+				run(func(ids []int) error { return db.PostponeTasks(currentUserID, ids, days) })
+			})
+		}),
+		fyne.NewMenuItem("Добавить тег…", func() {
+			askValue(w, "Тег для выбранных задач", "например, срочно", "", func(text string) {
+				run(func(ids []int) error { return db.TagTasks(currentUserID, ids, strings.TrimPrefix(text, "#")) })
+			})
+		}),
+		fyne.NewMenuItem("Переместить…", func() {
+			ids, ok := chosen()
+			if !ok {
+				return
+			}
+			showTransferDialog(w, ids, list, false, func(models.TodoList) {
+				s.ids = make(map[int]bool)
+				s.redraw()
+			})
+		}),
+		fyne.NewMenuItem("Копировать…", func() {
+			ids, ok := chosen()
+			if !ok {
+				return
+			}
+			showTransferDialog(w, ids, list, true, func(target models.TodoList) {
+				dialog.ShowInformation("Копирование задач", "Копии добавлены в список "+target.Title, w)
+			})
+		}),
+	)
+	doneBtn := widget.NewButton("Готово", func() {
+		ShowTodoItems(w, list)
+	})
+
+	return container.NewHBox(s.count, allBtn, layout.NewSpacer(), completeBtn, deleteBtn, moreBtn, doneBtn)
+}
+
+// askValue спрашивает одно значение и передаёт его onOK без пробелов по краям
+func askValue(w fyne.Window, title, placeholder, value string, onOK func(string)) {
+	entry := widget.NewEntry()
+	entry.SetPlaceHolder(placeholder)
+	entry.SetText(value)
+	d := dialog.NewForm(title, "OK", "Отмена", []*widget.FormItem{
+		widget.NewFormItem("", entry),
+	}, func(ok bool) {
+		if ok {
+			onOK(strings.TrimSpace(entry.Text))
+		}
+	}, w)
+	d.Resize(fyne.NewSize(320, 160))
+	d.Show()
+	w.Canvas().Focus(entry)
+}
@@ -0,0 +1,161 @@
+// sharing.go
+package gui
+
+import (
+	"fmt"
+	"todolist/db"
+	"todolist/models"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+var roleNames = map[models.Role]string{
+	models.RoleOwner:  "Владелец",
+	models.RoleEditor: "Редактор",
+	models.RoleViewer: "Читатель",
+}
+
+// memberRoles - роли, которые владелец может выдать участнику
+var memberRoles = []models.Role{models.RoleEditor, models.RoleViewer}
+
+func memberRoleOptions() []string {
+	options := make([]string, len(memberRoles))
+	for i, r := range memberRoles {
+		options[i] = roleNames[r]
+	}
+	return options
+}
+
+func roleByName(name string) models.Role {
+	for _, r := range memberRoles {
+		if roleNames[r] == name {
+			return r
+		}
+	}
+	return models.RoleViewer
+}
+
+// sharedBadge - пометка у общего списка: чей он и с какой ролью доступен
+func sharedBadge(list models.TodoList) string {
+	if list.Role != models.RoleOwner {
+		return fmt.Sprintf("👥 %s · %s", getUserName(list.UserID), roleNames[list.Role])
+	}
+	if list.Shared {
+		return "👥"
+	}
+	return ""
+}
+
+// editOnly отключает пункт меню, если пользователь не может менять список
+func editOnly(list models.TodoList, item *fyne.MenuItem) *fyne.MenuItem {
+	item.Disabled = !list.Role.CanEdit()
+	return item
+}
+
+// showShareDialog управляет участниками списка; доступен только владельцу
+func showShareDialog(w fyne.Window, list models.TodoList) {
+	membersContainer := container.NewVBox()
+	userSelect := widget.NewSelect(nil, nil)
+	userSelect.PlaceHolder = "Пользователь"
+	userIDs := make(map[string]int)
+
+	var refresh func()
+	refresh = func() {
+		members, err := db.GetListMembers(list.ID)
+		if err != nil {
+			dialog.ShowError(fmt.Errorf("Ошибка загрузки участников: %v", err), w)
+			return
+		}
+		users, err := db.GetAllUsers()
+		if err != nil {
+			dialog.ShowError(fmt.Errorf("Ошибка загрузки пользователей: %v", err), w)
+			return
+		}
+
+		membersContainer.RemoveAll()
+		membersContainer.Add(widget.NewLabel(fmt.Sprintf("%s — %s", getUserName(list.UserID), roleNames[models.RoleOwner])))
+
+		isMember := make(map[int]bool)
+		for _, m := range members {
+			member := m
+			isMember[member.UserID] = true
+
+			roleSelect := widget.NewSelect(memberRoleOptions(), nil)
+			roleSelect.SetSelected(roleNames[member.Role])
+			roleSelect.OnChanged = func(name string) {
+				if err := db.ShareList(currentUserID, list.ID, member.UserID, roleByName(name)); err != nil {
+					dialog.ShowError(err, w)
+				}
+			}
+			removeBtn := widget.NewButton("✕", func() {
+				if err := db.UnshareList(currentUserID, list.ID, member.UserID); err != nil {
+					dialog.ShowError(err, w)
+					return
+				}
+				refresh()
+			})
+			membersContainer.Add(container.NewBorder(nil, nil, nil,
+				container.NewHBox(roleSelect, removeBtn), widget.NewLabel(getUserName(member.UserID))))
+		}
+
+		// Открыть доступ можно тем, у кого его ещё нет
+		var options []string
+		clear(userIDs)
+		for _, u := range users {
+			if u.ID == list.UserID || isMember[u.ID] {
+				continue
+			}
+			option := getUserName(u.ID)
+			if _, taken := userIDs[option]; taken && userIDs[option] != u.ID {
+				option = fmt.Sprintf("%s (ID %d)", option, u.ID)
+			}
+			userIDs[option] = u.ID
+			options = append(options, option)
+		}
+		userSelect.Options = options
+		userSelect.ClearSelected()
+	}
+	refresh()
+
+	newRoleSelect := widget.NewSelect(memberRoleOptions(), nil)
+	newRoleSelect.SetSelected(roleNames[models.RoleEditor])
+	shareBtn := widget.NewButton("Открыть доступ", func() {
+		if userSelect.Selected == "" {
+			return
+		}
+		if err := db.ShareList(currentUserID, list.ID, userIDs[userSelect.Selected], roleByName(newRoleSelect.Selected)); err != nil {
+			dialog.ShowError(err, w)
+			return
+		}
+		refresh()
+	})
+
+	content := container.NewBorder(nil,
+		container.NewVBox(widget.NewSeparator(), container.NewGridWithColumns(2, userSelect, newRoleSelect), shareBtn),
+		nil, nil,
+		container.NewVScroll(membersContainer),
+	)
+
+	d := dialog.NewCustom("Доступ к списку «"+list.Title+"»", "Готово", content, w)
+	d.SetOnClosed(func() {
+		ShowTodoLists(w, currentUserID)
+	})
+	d.Resize(fyne.NewSize(420, 400))
+	d.Show()
+}
+
+// showLeaveListDialog убирает чужой список из своих, закрывая себе доступ
+func showLeaveListDialog(w fyne.Window, list models.TodoList) {
+	showDeleteConfirmDialog(w, "Выход из списка",
+		fmt.Sprintf("Список «%s» пропадёт из ваших списков. Вернуть доступ сможет только владелец. Продолжить?", list.Title),
+		func() {
+			if err := db.UnshareList(currentUserID, list.ID, currentUserID); err != nil {
+				dialog.ShowError(err, w)
+				return
+			}
+			ShowTodoLists(w, currentUserID)
+		})
+}
@@ -0,0 +1,150 @@
+// smartlists.go
+package gui
+
+import (
+	"fmt"
+	"time"
+	"todolist/db"
+	"todolist/filter"
+	"todolist/models"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/widget"
+)
+
+const filterHelp = `is:done, is:open, is:overdue, is:archived - из архивных списков
+due:today, due:tomorrow, due:week, due:month, due:none
+due<=+7d, due>=2024-05-01
+list:Работа, list:"Дом и сад"
+tag:urgent или #urgent, pri:A, pri<=B
+слово или "фраза" - поиск в тексте
+or - или, not или -условие - отрицание, скобки группируют`
+
+// addSmartListRows добавляет умные списки пользователя в начало экрана со списками
+func addSmartListRows(w fyne.Window, userID int, listsContainer *fyne.Container) {
+	smartLists, err := db.GetSmartLists(userID)
+	if err != nil {
+		dialog.ShowError(fmt.Errorf("Ошибка загрузки умных списков: %v", err), w)
+		return
+	}
+	for _, sl := range smartLists {
+		current := sl
+
+		listBtn := widget.NewButton("🔎 "+current.Title, func() {
+			ShowSmartList(w, userID, current)
+		})
+		listBtn.Alignment = widget.ButtonAlignLeading
+
+		editBtn := widget.NewButton("✎", func() {
+			showSmartListDialog(w, userID, &current)
+		})
+		deleteBtn := widget.NewButton("✕", func() {
+			showDeleteConfirmDialog(w, "Удаление умного списка", "Удалить умный список "+current.Title+"? Задачи не пострадают.", func() {
+				if err := db.DeleteSmartList(userID, current.ID); err != nil {
+					dialog.ShowError(err, w)
+					return
+				}
+				ShowTodoLists(w, userID)
+			})
+		})
+
+		listsContainer.Add(container.NewHBox(listBtn, layout.NewSpacer(), editBtn, deleteBtn))
+	}
+}
+
+// ShowSmartList показывает задачи из всех списков, подходящие под фильтр умного списка
+func ShowSmartList(w fyne.Window, userID int, sl models.SmartList) {
+	expr, err := filter.Parse(sl.Query, time.Now())
+	if err != nil {
+		dialog.ShowError(fmt.Errorf("Ошибка в фильтре: %v", err), w)
+		return
+	}
+	tasks, err := db.FilterTasks(userID, expr)
+	if err != nil {
+		dialog.ShowError(fmt.Errorf("Ошибка загрузки задач: %v", err), w)
+		return
+	}
+	lists, err := db.GetTodoLists(userID)
+	if err != nil {
+		dialog.ShowError(fmt.Errorf("Ошибка загрузки списков: %v", err), w)
+		return
+	}
+	byID := make(map[int]models.TodoList, len(lists))
+	for _, l := range lists {
+		byID[l.ID] = l
+	}
+
+	tasksContainer := container.NewVBox()
+	for i := range tasks {
+		task := &tasks[i]
+		list := byID[task.ListID]
+		tasksContainer.Add(widget.NewLabelWithStyle(list.Title, fyne.TextAlignLeading, fyne.TextStyle{Italic: true}))
+		tasksContainer.Add(createTaskRow(w, task, list))
+	}
+	if len(tasks) == 0 {
+		tasksContainer.Add(widget.NewLabel("Подходящих задач нет"))
+	}
+
+	backButton := widget.NewButton("← Назад", func() {
+		ShowTodoLists(w, userID)
+	})
+	editButton := widget.NewButton("Изменить фильтр…", func() {
+		showSmartListDialog(w, userID, &sl)
+	})
+
+	w.SetOnDropped(nil)
+	setContent(w, container.NewBorder(
+		container.NewVBox(
+			widget.NewLabelWithStyle(sl.Title, fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+			widget.NewLabel(sl.Query),
+		),
+		container.NewHBox(backButton, layout.NewSpacer(), editButton),
+		nil, nil,
+		container.NewVScroll(tasksContainer),
+	))
+}
+
+// showSmartListDialog создаёт умный список (sl == nil) или меняет существующий
+func showSmartListDialog(w fyne.Window, userID int, sl *models.SmartList) {
+	titleEntry := widget.NewEntry()
+	titleEntry.SetPlaceHolder("Название")
+	queryEntry := widget.NewEntry()
+	queryEntry.SetPlaceHolder("is:overdue (list:Работа or list:Дом)")
+	title := "Новый умный список"
+	if sl != nil {
+		titleEntry.SetText(sl.Title)
+		queryEntry.SetText(sl.Query)
+		title = "Умный список"
+	}
+
+	help := widget.NewLabel(filterHelp)
+	help.TextStyle = fyne.TextStyle{Monospace: true}
+
+	d := dialog.NewForm(title, "Сохранить", "Отмена", []*widget.FormItem{
+		widget.NewFormItem("Название:", titleEntry),
+		widget.NewFormItem("Фильтр:", queryEntry),
+		widget.NewFormItem("", help),
+	}, func(ok bool) {
+		if !ok {
+			return
+		}
+		list := models.SmartList{UserID: userID, Title: titleEntry.Text, Query: queryEntry.Text}
+		var err error
+		if sl != nil {
+			list.ID = sl.ID
+			err = db.UpdateSmartList(&list)
+		} else {
+			err = db.CreateSmartList(&list)
+		}
+		if err != nil {
+			dialog.ShowError(err, w)
+			return
+		}
+		ShowSmartList(w, userID, list)
+	}, w)
+	d.Resize(fyne.NewSize(480, 360))
+	d.Show()
+}
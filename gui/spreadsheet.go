@@ -0,0 +1,221 @@
+// spreadsheet.go
+package gui
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"todolist/db"
+	"todolist/models"
+	"todolist/spreadsheet"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+const (
+	previewRows   = 10
+	noColumn      = "—"
+	newListOption = "+ Новый список"
+)
+
+func exportSpreadsheet(w fyne.Window, name string, lists []models.ListWithTasks, xlsx bool) {
+	if xlsx {
+		showSaveFileDialog(w, name+".xlsx", []string{".xlsx"}, func(out io.Writer) error {
+			return spreadsheet.WriteXLSX(out, lists)
+		})
+		return
+	}
+	showSaveFileDialog(w, name+".csv", []string{".csv"}, func(out io.Writer) error {
+		return spreadsheet.WriteCSV(out, lists)
+	})
+}
+
+func exportListSpreadsheet(w fyne.Window, list models.TodoList, xlsx bool) {
+	tasks, err := db.GetTasksByList(list.ID)
+	if err != nil {
+		dialog.ShowError(fmt.Errorf("Ошибка загрузки задач: %v", err), w)
+		return
+	}
+	exportSpreadsheet(w, list.Title, []models.ListWithTasks{{TodoList: list, Tasks: tasks}}, xlsx)
+}
+
+func exportUserSpreadsheet(w fyne.Window, userID int, xlsx bool) {
+	lists, err := db.GetListsWithTasks(userID)
+	if err != nil {
+		dialog.ShowError(fmt.Errorf("Ошибка загрузки задач: %v", err), w)
+		return
+	}
+	exportSpreadsheet(w, "tasks", lists, xlsx)
+}
+
+// importCSV выбирает файл и показывает предпросмотр импорта; listID = 0 - список выбирается в диалоге
+func importCSV(w fyne.Window, userID, listID int) {
+	showOpenFileDialog(w, []string{".csv", ".txt"}, func(in fyne.URIReadCloser) error {
+		records, err := spreadsheet.ReadCSV(in)
+		if err != nil {
+			return err
+		}
+		if len(records) < 2 {
+			return fmt.Errorf("в файле нет строк с задачами")
+		}
+
+		all, err := db.GetTodoLists(userID)
+		if err != nil {
+			return fmt.Errorf("Ошибка загрузки списков: %v", err)
+		}
+		var lists []models.TodoList
+		for _, l := range all {
+			if l.Role.CanEdit() {
+				lists = append(lists, l)
+			}
+		}
+
+		showCSVImportDialog(w, userID, listID, lists, records)
+		return nil
+	})
+}
+
+func showCSVImportDialog(w fyne.Window, userID, listID int, lists []models.TodoList, records [][]string) {
+	header := records[0]
+	mapping := spreadsheet.DetectMapping(header)
+
+	// Выбор списка. SelectedIndex находит вариант по тексту, поэтому одинаковые
+	// названия различаем по ID
+	listOptions := []string{newListOption}
+	seen := map[string]bool{newListOption: true}
+	for _, l := range lists {
+		option := l.Title
+		if seen[option] {
+			option = fmt.Sprintf("%s (ID %d)", option, l.ID)
+		}
+		seen[option] = true
+		listOptions = append(listOptions, option)
+	}
+	newListEntry := widget.NewEntry()
+	newListEntry.SetPlaceHolder("Название нового списка")
+	listSelect := widget.NewSelect(listOptions, func(s string) {
+		if s == newListOption {
+			newListEntry.Show()
+		} else {
+			newListEntry.Hide()
+		}
+	})
+	listSelect.SetSelectedIndex(0)
+	for i, l := range lists {
+		if l.ID == listID {
+			listSelect.SetSelectedIndex(i + 1)
+		}
+	}
+
+	// Сопоставление колонок; одинаковые заголовки различаем по номеру колонки
+	columnOptions := []string{noColumn}
+	seen = map[string]bool{noColumn: true}
+	for i, name := range header {
+		if seen[name] {
+			name = fmt.Sprintf("%s (колонка %d)", name, i+1)
+		}
+		seen[name] = true
+		columnOptions = append(columnOptions, name)
+	}
+	preview := container.NewVBox()
+	var (
+		tasks []models.Task
+		rows  []int
+		errs  []spreadsheet.RowError
+	)
+
+	updatePreview := func() {
+		tasks, rows, errs = spreadsheet.ParseRows(records, mapping)
+
+		preview.RemoveAll()
+		preview.Add(widget.NewLabelWithStyle(
+			fmt.Sprintf("Будет импортировано задач: %d, строк с ошибками: %d", len(tasks), len(errs)),
+			fyne.TextAlignLeading, fyne.TextStyle{Bold: true}))
+
+		for i, task := range tasks {
+			if i == previewRows {
+				preview.Add(widget.NewLabel(fmt.Sprintf("… и ещё %d", len(tasks)-previewRows)))
+				break
+			}
+			text := fmt.Sprintf("%d. %s", rows[i], task.Title)
+			if task.HasDue() {
+				text += " (" + task.DueDate.Format(dateFormat) + ")"
+			}
+			if task.IsDone {
+				text += " ✓"
+			}
+			preview.Add(widget.NewLabel(text))
+		}
+
+		for _, e := range errs {
+			label := widget.NewLabel(e.Error())
+			label.Importance = widget.DangerImportance
+			preview.Add(label)
+		}
+	}
+
+	columnSelect := func(current int, set func(int)) *widget.Select {
+		sel := widget.NewSelect(columnOptions, nil)
+		sel.SetSelectedIndex(current + 1)
+		sel.OnChanged = func(string) {
+			set(sel.SelectedIndex() - 1)
+			updatePreview()
+		}
+		return sel
+	}
+
+	form := widget.NewForm(
+		widget.NewFormItem("Список:", container.NewVBox(listSelect, newListEntry)),
+		widget.NewFormItem("Название:", columnSelect(mapping.Title, func(i int) { mapping.Title = i })),
+		widget.NewFormItem("Описание:", columnSelect(mapping.Description, func(i int) { mapping.Description = i })),
+		widget.NewFormItem("Срок:", columnSelect(mapping.DueDate, func(i int) { mapping.DueDate = i })),
+		widget.NewFormItem("Выполнено:", columnSelect(mapping.Done, func(i int) { mapping.Done = i })),
+	)
+	updatePreview()
+
+	content := container.NewBorder(form, nil, nil, nil, container.NewVScroll(preview))
+
+	d := dialog.NewCustomConfirm("Импорт из CSV", "Импортировать", "Отмена", content, func(ok bool) {
+		if !ok {
+			return
+		}
+		if len(tasks) == 0 {
+			dialog.ShowError(fmt.Errorf("нет задач для импорта"), w)
+			return
+		}
+
+		var target models.TodoList
+		if listSelect.SelectedIndex() <= 0 {
+			title := strings.TrimSpace(newListEntry.Text)
+			if title == "" {
+				title = "Импорт " + time.Now().Format(dateFormat)
+			}
+			target = models.TodoList{UserID: userID, Title: title, CreatedAt: time.Now()}
+			if err := db.CreateTodoList(&target); err != nil {
+				dialog.ShowError(err, w)
+				return
+			}
+		} else {
+			target = lists[listSelect.SelectedIndex()-1]
+		}
+
+		created, updated, err := db.ImportTasks(userID, target.ID, tasks)
+		if err != nil {
+			dialog.ShowError(err, w)
+			return
+		}
+
+		ShowTodoItems(w, target)
+		report := fmt.Sprintf("Добавлено задач: %d\nОбновлено задач: %d\nПропущено строк: %d", created, updated, len(errs))
+		for _, e := range errs {
+			report += "\n" + e.Error()
+		}
+		dialog.ShowInformation("Импорт из CSV", report, w)
+	}, w)
+	d.Resize(fyne.NewSize(520, 560))
+	d.Show()
+}
@@ -0,0 +1,69 @@
+// tags.go
+package gui
+
+import (
+	"strings"
+	"todolist/models"
+
+	"fyne.io/fyne/v2/widget"
+)
+
+const noPriority = "—"
+
+// priorityOptions - "—", "A", "B", ..., "Z"; индекс в списке совпадает с models.Task.Priority
+func priorityOptions() []string {
+	options := []string{noPriority}
+	for c := 'A'; c <= 'Z'; c++ {
+		options = append(options, string(c))
+	}
+	return options
+}
+
+func newPrioritySelect(priority int) *widget.Select {
+	sel := widget.NewSelect(priorityOptions(), nil)
+	sel.SetSelectedIndex(priority)
+	return sel
+}
+
+func selectedPriority(sel *widget.Select) int {
+	if sel.SelectedIndex() < 0 {
+		return 0
+	}
+	return sel.SelectedIndex()
+}
+
+func newTagsEntry(tags []string) *widget.Entry {
+	entry := widget.NewEntry()
+	entry.SetPlaceHolder("через запятую")
+	entry.SetText(strings.Join(tags, ", "))
+	return entry
+}
+
+func parseTags(s string) []string {
+	return strings.FieldsFunc(s, func(r rune) bool {
+		return r == ',' || r == ' '
+	})
+}
+
+func formatTags(tags []string) string {
+	parts := make([]string, len(tags))
+	for i, tag := range tags {
+		parts[i] = "#" + tag
+	}
+	return strings.Join(parts, " ")
+}
+
+// taskMetaText - строка с приоритетом, тегами и исполнителем для деталей задачи
+func taskMetaText(task *models.Task) string {
+	var parts []string
+	if task.Priority > 0 {
+		parts = append(parts, "Приоритет: "+priorityOptions()[task.Priority])
+	}
+	if len(task.Tags) > 0 {
+		parts = append(parts, formatTags(task.Tags))
+	}
+	if task.AssigneeID != 0 {
+		parts = append(parts, "Исполнитель: "+getUserName(task.AssigneeID))
+	}
+	return strings.Join(parts, "   ")
+}
@@ -0,0 +1,173 @@
+// templates.go
+package gui
+
+import (
+	"fmt"
+	"time"
+	"todolist/db"
+	"todolist/models"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/widget"
+)
+
+// newTemplateChooser добавляет в форму нового списка выбор шаблона и даты начала.
+// Возвращает выбранный шаблон (nil - пустой список) и поле даты начала
+func newTemplateChooser(w fyne.Window, userID int, form *fyne.Container, titleEntry *widget.Entry) (func() *models.ListTemplate, *widget.Entry) {
+	startEntry := widget.NewEntry()
+	startEntry.SetText(time.Now().Format(dateFormat))
+	startEntry.Disable()
+
+	templates, err := db.GetTemplates(userID)
+	if err != nil {
+		dialog.ShowError(fmt.Errorf("Ошибка загрузки шаблонов: %v", err), w)
+	}
+	if len(templates) == 0 {
+		return func() *models.ListTemplate { return nil }, startEntry
+	}
+
+	const empty = "Пустой список"
+	options := []string{empty}
+	byOption := make(map[string]*models.ListTemplate, len(templates))
+	for i := range templates {
+		option := fmt.Sprintf("%s (задач: %d)", templates[i].Title, templates[i].TaskCount)
+		if _, exists := byOption[option]; exists {
+			option = fmt.Sprintf("%s (ID %d)", option, templates[i].ID)
+		}
+		options = append(options, option)
+		byOption[option] = &templates[i]
+	}
+	templateSelect := widget.NewSelect(options, func(option string) {
+		tmpl := byOption[option]
+		if tmpl == nil {
+			startEntry.Disable()
+			return
+		}
+		startEntry.Enable()
+		if titleEntry.Text == "" {
+			titleEntry.SetText(tmpl.Title)
+		}
+	})
+	templateSelect.SetSelected(empty)
+
+	form.Add(widget.NewLabel("Шаблон:"))
+	form.Add(templateSelect)
+	form.Add(widget.NewLabel("Дата начала (дд.мм.гггг):"))
+	form.Add(startEntry)
+	return func() *models.ListTemplate { return byOption[templateSelect.Selected] }, startEntry
+}
+
+// showSaveTemplateDialog сохраняет список с задачами как шаблон. Дата начала по умолчанию -
+// самый ранний срок среди задач, чтобы первая задача шаблона получила срок "+0 дней"
+func showSaveTemplateDialog(w fyne.Window, list models.TodoList, tasks []models.Task) {
+	start := time.Time{}
+	for _, task := range tasks {
+		if task.HasDue() && (start.IsZero() || task.DueDate.Before(start)) {
+			start = task.DueDate
+		}
+	}
+	if start.IsZero() {
+		start = time.Now()
+	}
+
+	titleEntry := widget.NewEntry()
+	titleEntry.SetText(list.Title)
+	startEntry := widget.NewEntry()
+	startEntry.SetText(start.Format(dateFormat))
+
+	d := dialog.NewForm("Сохранить как шаблон", "Сохранить", "Отмена", []*widget.FormItem{
+		widget.NewFormItem("Название:", titleEntry),
+		widget.NewFormItem("Дата начала:", startEntry),
+	}, func(ok bool) {
+		if !ok {
+			return
+		}
+		start, err := time.Parse(dateFormat, startEntry.Text)
+		if err != nil {
+			dialog.ShowError(fmt.Errorf("неверная дата начала. Формат: дд.мм.гггг"), w)
+			return
+		}
+		tmpl := models.ListTemplate{Title: titleEntry.Text, Description: list.Description}
+		if err := db.CreateTemplate(currentUserID, list.ID, &tmpl, start); err != nil {
+			dialog.ShowError(err, w)
+			return
+		}
+		dialog.ShowInformation("Шаблон сохранён",
+			fmt.Sprintf("Шаблон %q (задач: %d) можно выбрать при создании списка.\nСроки задач отсчитываются от даты начала.", tmpl.Title, tmpl.TaskCount), w)
+	}, w)
+	d.Resize(fyne.NewSize(400, 200))
+	d.Show()
+}
+
+// showDuplicateListDialog создаёт копию списка со всеми задачами
+func showDuplicateListDialog(w fyne.Window, list models.TodoList) {
+	titleEntry := widget.NewEntry()
+	titleEntry.SetText(list.Title + " (копия)")
+	reopenCheck := widget.NewCheck("Сбросить отметки о выполнении", nil)
+	reopenCheck.SetChecked(true)
+
+	d := dialog.NewForm("Дублировать список", "Создать", "Отмена", []*widget.FormItem{
+		widget.NewFormItem("Название:", titleEntry),
+		widget.NewFormItem("", reopenCheck),
+	}, func(ok bool) {
+		if !ok || titleEntry.Text == "" {
+			return
+		}
+		copyList := models.TodoList{
+			UserID:      currentUserID,
+			Title:       titleEntry.Text,
+			Description: list.Description,
+			CreatedAt:   time.Now(),
+			ManualOrder: list.ManualOrder,
+			AutoArchive: list.AutoArchive,
+			FolderID:    list.FolderID,
+		}
+		if err := db.DuplicateList(list.ID, &copyList, reopenCheck.Checked); err != nil {
+			dialog.ShowError(err, w)
+			return
+		}
+		ShowTodoItems(w, copyList)
+	}, w)
+	d.Resize(fyne.NewSize(400, 200))
+	d.Show()
+}
+
+// showTemplatesDialog показывает шаблоны пользователя с возможностью удаления
+func showTemplatesDialog(w fyne.Window, userID int) {
+	templates, err := db.GetTemplates(userID)
+	if err != nil {
+		dialog.ShowError(fmt.Errorf("Ошибка загрузки шаблонов: %v", err), w)
+		return
+	}
+	if len(templates) == 0 {
+		dialog.ShowInformation("Шаблоны списков",
+			"Шаблонов пока нет. Сохранить список как шаблон можно в меню \"Файл…\" на экране списка.", w)
+		return
+	}
+
+	var d dialog.Dialog
+	rows := container.NewVBox()
+	for _, t := range templates {
+		tmpl := t
+		rows.Add(container.NewHBox(
+			widget.NewLabel(fmt.Sprintf("%s (задач: %d)", tmpl.Title, tmpl.TaskCount)),
+			layout.NewSpacer(),
+			widget.NewButton("✕", func() {
+				showDeleteConfirmDialog(w, "Удаление шаблона", fmt.Sprintf("Удалить шаблон %q?", tmpl.Title), func() {
+					if err := db.DeleteTemplate(userID, tmpl.ID); err != nil {
+						dialog.ShowError(err, w)
+						return
+					}
+					d.Hide()
+					showTemplatesDialog(w, userID)
+				})
+			}),
+		))
+	}
+	d = dialog.NewCustom("Шаблоны списков", "Закрыть", container.NewVScroll(rows), w)
+	d.Resize(fyne.NewSize(400, 300))
+	d.Show()
+}
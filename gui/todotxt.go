@@ -0,0 +1,96 @@
+// todotxt.go
+package gui
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"todolist/db"
+	"todolist/models"
+	"todolist/todotxt"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+)
+
+const inboxListTitle = "Входящие"
+
+var todoTxtExtensions = []string{".txt"}
+
+func exportListTodoTxt(w fyne.Window, list models.TodoList) {
+	tasks, err := db.GetTasksByList(list.ID)
+	if err != nil {
+		dialog.ShowError(fmt.Errorf("Ошибка загрузки задач: %v", err), w)
+		return
+	}
+
+	lists := []models.ListWithTasks{{TodoList: list, Tasks: tasks}}
+	showSaveFileDialog(w, "todo.txt", todoTxtExtensions, func(out io.Writer) error {
+		return todotxt.Write(out, lists)
+	})
+}
+
+func exportUserTodoTxt(w fyne.Window, userID int) {
+	lists, err := db.GetListsWithTasks(userID)
+	if err != nil {
+		dialog.ShowError(fmt.Errorf("Ошибка загрузки задач: %v", err), w)
+		return
+	}
+
+	showSaveFileDialog(w, "todo.txt", todoTxtExtensions, func(out io.Writer) error {
+		return todotxt.Write(out, lists)
+	})
+}
+
+// importTodoTxt раскладывает задачи по спискам согласно +проекту. Задачи без проекта
+// попадают в defaultList, а если он не задан - в список "Входящие".
+func importTodoTxt(w fyne.Window, userID int, defaultList *models.TodoList) {
+	showOpenFileDialog(w, todoTxtExtensions, func(in fyne.URIReadCloser) error {
+		items, err := todotxt.ParseFile(in)
+		if err != nil {
+			return fmt.Errorf("Ошибка чтения todo.txt: %v", err)
+		}
+
+		var (
+			lists   []models.ListWithTasks
+			index   = make(map[string]int)
+			skipped []string
+		)
+		for _, item := range items {
+			task, project, err := todotxt.ToTask(item)
+			if err != nil {
+				skipped = append(skipped, fmt.Sprintf("%s: %v", item, err))
+				continue
+			}
+
+			title := todotxt.ListTitle(project)
+			if title == "" {
+				title = inboxListTitle
+				if defaultList != nil {
+					title = defaultList.Title
+				}
+			}
+			key := strings.ToLower(title)
+			i, ok := index[key]
+			if !ok {
+				i = len(lists)
+				index[key] = i
+				lists = append(lists, models.ListWithTasks{TodoList: models.TodoList{Title: title}})
+			}
+			lists[i].Tasks = append(lists[i].Tasks, task)
+		}
+
+		summary, err := importLists(userID, lists)
+		if err != nil {
+			return err
+		}
+
+		if defaultList != nil {
+			ShowTodoItems(w, *defaultList)
+		} else {
+			ShowTodoLists(w, userID)
+		}
+		showReport(w, "Импорт todo.txt", summary.String(), skipped)
+		return nil
+	})
+}
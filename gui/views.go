@@ -0,0 +1,134 @@
+// views.go
+package gui
+
+import (
+	"fmt"
+	"time"
+	"todolist/db"
+	"todolist/filter"
+	"todolist/models"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/widget"
+)
+
+// taskView - встроенное представление задач из всех списков, заданное фильтром (см. пакет filter)
+type taskView struct {
+	title string
+	query string
+	empty string
+}
+
+const (
+	viewToday = iota
+	viewWeek
+	viewOverdue
+	viewNoDue
+)
+
+var taskViews = []taskView{
+	viewToday:   {"Сегодня", "due:today -is:done", "На сегодня задач нет"},
+	viewWeek:    {"7 дней", "due>=today due<+7d -is:done", "На ближайшую неделю задач нет"},
+	viewOverdue: {"Просрочено", "is:overdue", "Просроченных задач нет"},
+	viewNoDue:   {"Без срока", "due:none -is:done", "Все задачи со сроком"},
+}
+
+var weekdayNames = [...]string{"вс", "пн", "вт", "ср", "чт", "пт", "сб"}
+
+// ShowAgenda - экран после выбора пользователя: задачи из всех его списков
+// на сегодня, ближайшую неделю, просроченные или без срока, по дням
+func ShowAgenda(w fyne.Window, userID, view int) {
+	currentUserID = userID
+	now := time.Now()
+	expr, err := filter.Parse(taskViews[view].query, now)
+	if err != nil {
+		dialog.ShowError(err, w)
+		return
+	}
+	tasks, err := db.FilterTasks(userID, expr)
+	if err != nil {
+		dialog.ShowError(fmt.Errorf("Ошибка загрузки задач: %v", err), w)
+		return
+	}
+	lists, err := db.GetTodoLists(userID)
+	if err != nil {
+		dialog.ShowError(fmt.Errorf("Ошибка загрузки списков: %v", err), w)
+		return
+	}
+	byID := make(map[int]models.TodoList, len(lists))
+	for _, l := range lists {
+		byID[l.ID] = l
+	}
+
+	tabs := container.NewHBox()
+	for i, v := range taskViews {
+		i := i
+		btn := widget.NewButton(v.title, func() {
+			ShowAgenda(w, userID, i)
+		})
+		if i == view {
+			btn.Importance = widget.HighImportance
+		}
+		tabs.Add(btn)
+	}
+	tabs.Add(layout.NewSpacer())
+	tabs.Add(newNotificationsButton(w, userID))
+
+	// Задачи уже упорядочены по сроку, поэтому группы по дням идут подряд
+	tasksContainer := container.NewVBox()
+	lastDay := ""
+	for i := range tasks {
+		task := &tasks[i]
+		if day := dayHeading(task, now); day != lastDay {
+			lastDay = day
+			tasksContainer.Add(widget.NewLabelWithStyle(day, fyne.TextAlignLeading, fyne.TextStyle{Bold: true}))
+		}
+		list := byID[task.ListID]
+		listLabel := widget.NewLabelWithStyle(list.Title, fyne.TextAlignTrailing, fyne.TextStyle{Italic: true})
+		tasksContainer.Add(container.NewBorder(nil, nil, nil, listLabel, createTaskRow(w, task, list)))
+	}
+	if len(tasks) == 0 {
+		tasksContainer.Add(widget.NewLabel(taskViews[view].empty))
+	}
+
+	backButton := widget.NewButton("← Назад к пользователям", func() {
+		ShowUserSelection(w)
+	})
+	listsButton := widget.NewButton("Все списки →", func() {
+		ShowTodoLists(w, userID)
+	})
+	calendarButton := widget.NewButton("Календарь", func() {
+		ShowCalendar(w, userID, calendarMonth, time.Now())
+	})
+
+	setMarkdownDropHandler(w, userID, nil)
+	setContent(w, container.NewBorder(
+		container.NewVBox(tabs, newSearchBar(w, userID, "")),
+		container.NewHBox(backButton, layout.NewSpacer(), calendarButton, listsButton),
+		nil, nil,
+		container.NewVScroll(tasksContainer),
+	))
+}
+
+// dayHeading - заголовок группы задач: "Сегодня", "Завтра" или день недели с датой
+func dayHeading(task *models.Task, now time.Time) string {
+	if !task.HasDue() {
+		return "Без срока"
+	}
+	due := task.DueDate
+	y, m, d := now.Date()
+	today := time.Date(y, m, d, 0, 0, 0, 0, now.Location())
+	day := time.Date(due.Year(), due.Month(), due.Day(), 0, 0, 0, 0, now.Location())
+	switch {
+	case day.Equal(today):
+		return "Сегодня"
+	case day.Equal(today.AddDate(0, 0, 1)):
+		return "Завтра"
+	case day.Equal(today.AddDate(0, 0, -1)):
+		return "Вчера"
+	}
+	return weekdayNames[day.Weekday()] + ", " + day.Format(dateFormat)
+}
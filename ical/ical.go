@@ -0,0 +1,302 @@
+// ical.go
+package ical
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"todolist/models"
+	"unicode/utf8"
+)
+
+const (
+	prodID      = "-//todolist//My Tasks//RU"
+	uidSuffix   = "@todolist"
+	dateLayout  = "20060102"
+	stampLayout = "20060102T150405Z"
+	localLayout = "20060102T150405"
+	maxLineLen  = 75
+)
+
+// TaskUID возвращает UID компонента VTODO для задачи.
+// UID зависит только от ID задачи, поэтому при повторной выгрузке не меняется.
+// Для задач, пришедших из другого календаря, сохраняется их исходный UID.
+// Копия задачи этого приложения из другого списка получает собственный UID,
+// иначе в выгрузке оказались бы два компонента с одним UID.
+func TaskUID(task models.Task) string {
+	if _, own := ParseTaskUID(task.ExternalUID); task.ExternalUID != "" && !own {
+		return task.ExternalUID
+	}
+	return fmt.Sprintf("task-%d%s", task.ID, uidSuffix)
+}
+
+// ParseTaskUID извлекает ID задачи из UID, созданного TaskUID
+func ParseTaskUID(uid string) (int, bool) {
+	if !strings.HasPrefix(uid, "task-") || !strings.HasSuffix(uid, uidSuffix) {
+		return 0, false
+	}
+	id, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(uid, "task-"), uidSuffix))
+	if err != nil || id <= 0 {
+		return 0, false
+	}
+	return id, true
+}
+
+// Encode записывает списки задач как календарь с компонентами VTODO
+func Encode(w io.Writer, name string, lists []models.ListWithTasks) error {
+	var b strings.Builder
+
+	writeLine(&b, "BEGIN:VCALENDAR")
+	writeLine(&b, "VERSION:2.0")
+	writeLine(&b, "PRODID:"+prodID)
+	writeLine(&b, "CALSCALE:GREGORIAN")
+	if name != "" {
+		writeLine(&b, "X-WR-CALNAME:"+escapeText(name))
+	}
+
+	for _, list := range lists {
+		for _, task := range list.Tasks {
+			writeTodo(&b, list.TodoList, task)
+		}
+	}
+
+	writeLine(&b, "END:VCALENDAR")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func writeTodo(b *strings.Builder, list models.TodoList, task models.Task) {
+	created := task.CreatedAt.UTC()
+
+	writeLine(b, "BEGIN:VTODO")
+	writeLine(b, "UID:"+escapeText(TaskUID(task)))
+	// DTSTAMP берём из даты создания, чтобы выгрузка одних и тех же данных была побайтно одинаковой
+	writeLine(b, "DTSTAMP:"+created.Format(stampLayout))
+	writeLine(b, "CREATED:"+created.Format(stampLayout))
+	writeLine(b, "SUMMARY:"+escapeText(task.Title))
+	if task.Description != "" {
+		writeLine(b, "DESCRIPTION:"+escapeText(task.Description))
+	}
+	if task.HasDue() {
+		writeLine(b, "DUE;VALUE=DATE:"+task.DueDate.Format(dateLayout))
+	}
+	if list.Title != "" {
+		writeLine(b, "CATEGORIES:"+escapeText(list.Title))
+	}
+	if task.IsDone {
+		writeLine(b, "STATUS:COMPLETED")
+	} else {
+		writeLine(b, "STATUS:NEEDS-ACTION")
+	}
+	writeLine(b, "END:VTODO")
+}
+
+// writeLine пишет строку контента с переносом по 75 октетов (RFC 5545, 3.1),
+// не разрывая многобайтовые символы
+func writeLine(b *strings.Builder, line string) {
+	limit := maxLineLen
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		b.WriteString(line[:cut])
+		b.WriteString("\r\n ")
+		line = line[cut:]
+		// строка продолжения начинается с пробела, который тоже занимает октет
+		limit = maxLineLen - 1
+	}
+	b.WriteString(line)
+	b.WriteString("\r\n")
+}
+
+func escapeText(s string) string {
+	r := strings.NewReplacer(
+		`\`, `\\`,
+		";", `\;`,
+		",", `\,`,
+		"\r\n", `\n`,
+		"\n", `\n`,
+	)
+	return r.Replace(s)
+}
+
+func unescapeText(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i == len(s)-1 {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch s[i] {
+		case 'n', 'N':
+			b.WriteByte('\n')
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String()
+}
+
+// Decode читает компоненты VTODO из календаря.
+// В ExternalUID каждой задачи записывается UID компонента; ListID не заполняется.
+func Decode(r io.Reader) ([]models.Task, error) {
+	lines, err := unfold(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		tasks   []models.Task
+		current *models.Task
+		depth   int // вложенные компоненты внутри VTODO (например, VALARM)
+	)
+
+	for n, line := range lines {
+		name, params, value, ok := splitLine(line)
+		if !ok {
+			continue
+		}
+
+		switch {
+		case name == "BEGIN" && strings.EqualFold(value, "VTODO"):
+			if current != nil {
+				return nil, fmt.Errorf("строка %d: вложенный VTODO", n+1)
+			}
+			current = &models.Task{}
+			continue
+		case name == "END" && strings.EqualFold(value, "VTODO"):
+			if current == nil {
+				return nil, fmt.Errorf("строка %d: END:VTODO без BEGIN:VTODO", n+1)
+			}
+			if current.CreatedAt.IsZero() {
+				current.CreatedAt = time.Now()
+			}
+			tasks = append(tasks, *current)
+			current = nil
+			continue
+		}
+
+		if current == nil {
+			continue
+		}
+		if name == "BEGIN" {
+			depth++
+			continue
+		}
+		if name == "END" {
+			depth--
+			continue
+		}
+		if depth > 0 {
+			continue
+		}
+
+		switch name {
+		case "UID":
+			current.ExternalUID = unescapeText(value)
+		case "SUMMARY":
+			current.Title = unescapeText(value)
+		case "DESCRIPTION":
+			current.Description = unescapeText(value)
+		case "STATUS":
+			current.IsDone = strings.EqualFold(value, "COMPLETED")
+		case "COMPLETED":
+			current.IsDone = true
+		case "DUE":
+			due, err := parseTime(value, params)
+			if err != nil {
+				return nil, fmt.Errorf("строка %d: неверный DUE: %v", n+1, err)
+			}
+			// В приложении срок хранится как дата без времени
+			due = due.In(time.Local)
+			current.DueDate = time.Date(due.Year(), due.Month(), due.Day(), 0, 0, 0, 0, time.UTC)
+		case "CREATED":
+			created, err := parseTime(value, params)
+			if err != nil {
+				return nil, fmt.Errorf("строка %d: неверный CREATED: %v", n+1, err)
+			}
+			current.CreatedAt = created
+		case "DTSTAMP":
+			if current.CreatedAt.IsZero() {
+				if stamp, err := parseTime(value, params); err == nil {
+					current.CreatedAt = stamp
+				}
+			}
+		}
+	}
+
+	if current != nil {
+		return nil, fmt.Errorf("VTODO не закрыт")
+	}
+	return tasks, nil
+}
+
+// unfold склеивает перенесённые строки контента
+func unfold(r io.Reader) ([]string, error) {
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if line == "" {
+			continue
+		}
+		if (line[0] == ' ' || line[0] == '\t') && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return lines, scanner.Err()
+}
+
+// splitLine разбирает строку вида NAME;PARAM=VALUE:значение
+func splitLine(line string) (name string, params map[string]string, value string, ok bool) {
+	inQuotes := false
+	colon := -1
+	for i, r := range line {
+		if r == '"' {
+			inQuotes = !inQuotes
+		}
+		if r == ':' && !inQuotes {
+			colon = i
+			break
+		}
+	}
+	if colon < 0 {
+		return "", nil, "", false
+	}
+
+	parts := strings.Split(line[:colon], ";")
+	name = strings.ToUpper(parts[0])
+	params = make(map[string]string)
+	for _, p := range parts[1:] {
+		if k, v, found := strings.Cut(p, "="); found {
+			params[strings.ToUpper(k)] = strings.Trim(v, `"`)
+		}
+	}
+	return name, params, line[colon+1:], true
+}
+
+func parseTime(value string, params map[string]string) (time.Time, error) {
+	if params["VALUE"] == "DATE" || len(value) == len(dateLayout) {
+		return time.ParseInLocation(dateLayout, value, time.Local)
+	}
+	if strings.HasSuffix(value, "Z") {
+		return time.Parse(stampLayout, value)
+	}
+
+	loc := time.Local
+	if tzid := params["TZID"]; tzid != "" {
+		if l, err := time.LoadLocation(tzid); err == nil {
+			loc = l
+		}
+	}
+	return time.ParseInLocation(localLayout, value, loc)
+}
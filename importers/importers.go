@@ -0,0 +1,62 @@
+// importers.go
+package importers
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"todolist/models"
+)
+
+// Result - данные из файла экспорта другого сервиса, готовые к загрузке в базу.
+// Задачи списков хранятся деревом (models.Task.Subtasks), ListID и UserID не заполнены.
+type Result struct {
+	Lists []models.ListWithTasks
+	// Unmapped - человекочитаемый перечень того, что не удалось перенести
+	Unmapped []string
+}
+
+func (r *Result) skip(format string, args ...any) {
+	r.Unmapped = append(r.Unmapped, fmt.Sprintf(format, args...))
+}
+
+// TaskCount - количество задач вместе с подзадачами
+func (r *Result) TaskCount() int {
+	var count func([]models.Task) int
+	count = func(tasks []models.Task) int {
+		n := len(tasks)
+		for _, t := range tasks {
+			n += count(t.Subtasks)
+		}
+		return n
+	}
+
+	n := 0
+	for _, l := range r.Lists {
+		n += count(l.Tasks)
+	}
+	return n
+}
+
+// parseDate понимает ISO-даты и даты со временем из экспортов; время отбрасывается
+func parseDate(s string) (time.Time, bool) {
+	s = strings.TrimSpace(s)
+	for _, layout := range []string{time.RFC3339Nano, time.RFC3339, "2006-01-02T15:04:05", "2006-01-02", "02.01.2006"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			if layout == time.RFC3339Nano || layout == time.RFC3339 {
+				t = t.In(time.Local)
+			}
+			return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC), true
+		}
+	}
+	return time.Time{}, false
+}
+
+func parseTime(s string) time.Time {
+	for _, layout := range []string{time.RFC3339Nano, time.RFC3339} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t
+		}
+	}
+	return time.Now()
+}
@@ -0,0 +1,362 @@
+// todoist.go
+package importers
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"todolist/models"
+)
+
+// Todoist помечает приоритет от p1 (срочно) до p4 (обычный); p4 переносим как "без приоритета"
+const todoistPriorities = 4
+
+// ParseTodoist читает резервную копию Todoist: JSON в формате Sync API,
+// CSV-файл одного проекта или ZIP-архив с CSV-файлами проектов
+func ParseTodoist(r io.Reader, fileName string) (*Result, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	switch strings.ToLower(path.Ext(fileName)) {
+	case ".json":
+		return parseTodoistJSON(data)
+	case ".zip":
+		return parseTodoistZip(data)
+	case ".csv":
+		res := &Result{}
+		if err := parseTodoistCSV(res, data, fileName); err != nil {
+			return nil, err
+		}
+		return res, nil
+	}
+	return nil, fmt.Errorf("неизвестный формат резервной копии Todoist: %s", fileName)
+}
+
+type todoistBackup struct {
+	Projects []struct {
+		ID         json.RawMessage `json:"id"`
+		Name       string          `json:"name"`
+		IsArchived bool            `json:"is_archived"`
+		IsDeleted  bool            `json:"is_deleted"`
+	} `json:"projects"`
+	Items []struct {
+		ID          json.RawMessage `json:"id"`
+		ProjectID   json.RawMessage `json:"project_id"`
+		ParentID    json.RawMessage `json:"parent_id"`
+		Content     string          `json:"content"`
+		Description string          `json:"description"`
+		Priority    int             `json:"priority"`
+		Checked     json.RawMessage `json:"checked"`
+		IsDeleted   bool            `json:"is_deleted"`
+		Labels      []string        `json:"labels"`
+		AddedAt     string          `json:"added_at"`
+		CompletedAt string          `json:"completed_at"`
+		Due         *struct {
+			Date        string `json:"date"`
+			String      string `json:"string"`
+			IsRecurring bool   `json:"is_recurring"`
+		} `json:"due"`
+		ChildOrder int `json:"child_order"`
+	} `json:"items"`
+	Notes    []json.RawMessage `json:"notes"`
+	Sections []struct {
+		Name string `json:"name"`
+	} `json:"sections"`
+}
+
+// todoistID приводит идентификатор к строке: в старых версиях API это число, в новых - строка
+func todoistID(raw json.RawMessage) string {
+	s := strings.Trim(string(raw), `"`)
+	if s == "null" {
+		return ""
+	}
+	return s
+}
+
+func parseTodoistJSON(data []byte) (*Result, error) {
+	var backup todoistBackup
+	if err := json.Unmarshal(data, &backup); err != nil {
+		return nil, fmt.Errorf("ошибка чтения JSON Todoist: %v", err)
+	}
+	if len(backup.Projects) == 0 && len(backup.Items) == 0 {
+		return nil, fmt.Errorf("в файле нет проектов и задач Todoist")
+	}
+
+	res := &Result{}
+	listIndex := make(map[string]int)
+	for _, p := range backup.Projects {
+		if p.IsDeleted {
+			continue
+		}
+		if p.IsArchived {
+			res.skip("Проект %q в архиве Todoist - импортирован как обычный список", p.Name)
+		}
+		listIndex[todoistID(p.ID)] = len(res.Lists)
+		res.Lists = append(res.Lists, models.ListWithTasks{
+			TodoList: models.TodoList{Title: p.Name, CreatedAt: time.Now()},
+		})
+	}
+
+	// Сначала собираем плоский список, затем строим дерево по parent_id
+	type flatTask struct {
+		task     models.Task
+		id       string
+		parentID string
+		list     int
+	}
+	items := backup.Items
+	sort.SliceStable(items, func(i, j int) bool { return items[i].ChildOrder < items[j].ChildOrder })
+
+	var flat []flatTask
+	for _, item := range items {
+		if item.IsDeleted {
+			continue
+		}
+		id := todoistID(item.ID)
+		list, ok := listIndex[todoistID(item.ProjectID)]
+		if !ok {
+			res.skip("Задача %q: проект не найден в резервной копии", item.Content)
+			continue
+		}
+
+		title, tags := splitTodoistLabels(item.Content)
+		task := models.Task{
+			Title:       title,
+			Description: item.Description,
+			IsDone:      isChecked(item.Checked),
+			Priority:    todoistPriority(item.Priority, true),
+			Tags:        append(tags, item.Labels...),
+			CreatedAt:   parseTime(item.AddedAt),
+			ExternalUID: "todoist:" + id,
+		}
+		if item.CompletedAt != "" {
+			task.CompletedAt = parseTime(item.CompletedAt)
+		}
+		if item.Due != nil {
+			if due, ok := parseDate(item.Due.Date); ok {
+				task.DueDate = due
+			} else {
+				res.skip("Задача %q: не удалось разобрать срок %q", title, item.Due.Date)
+			}
+			if item.Due.IsRecurring {
+				res.skip("Задача %q: повторение %q не поддерживается, перенесён только ближайший срок", title, item.Due.String)
+			}
+		}
+		flat = append(flat, flatTask{task: task, id: id, parentID: todoistID(item.ParentID), list: list})
+	}
+
+	// Переводим идентификаторы Todoist во временные числовые, чтобы воспользоваться NestTasks
+	numeric := make(map[string]int, len(flat))
+	for i, f := range flat {
+		numeric[f.id] = i + 1
+	}
+	byList := make(map[int][]models.Task)
+	for i, f := range flat {
+		f.task.ID = i + 1
+		f.task.ParentID = numeric[f.parentID]
+		byList[f.list] = append(byList[f.list], f.task)
+	}
+	for list, tasks := range byList {
+		res.Lists[list].Tasks = clearIDs(models.NestTasks(tasks))
+	}
+
+	if len(backup.Notes) > 0 {
+		res.skip("Комментарии к задачам (%d) не перенесены", len(backup.Notes))
+	}
+	if len(backup.Sections) > 0 {
+		res.skip("Разделы проектов (%d) не перенесены: задачи импортированы без разбивки", len(backup.Sections))
+	}
+	return res, nil
+}
+
+// clearIDs убирает временные идентификаторы, иначе импорт примет их за ID задач в базе
+func clearIDs(tasks []models.Task) []models.Task {
+	for i := range tasks {
+		tasks[i].ID = 0
+		tasks[i].ParentID = 0
+		tasks[i].Subtasks = clearIDs(tasks[i].Subtasks)
+	}
+	return tasks
+}
+
+func isChecked(raw json.RawMessage) bool {
+	s := strings.Trim(string(raw), `"`)
+	return s == "true" || s == "1"
+}
+
+// todoistPriority переводит приоритет Todoist в приоритет задачи.
+// В API 4 - наивысший (p1), в CSV наоборот: 1 - это p1.
+func todoistPriority(p int, api bool) int {
+	if p < 1 || p > todoistPriorities {
+		return 0
+	}
+	level := p // номер pN
+	if api {
+		level = todoistPriorities + 1 - p
+	}
+	if level == todoistPriorities {
+		return 0
+	}
+	return level
+}
+
+// splitTodoistLabels выделяет метки "@метка" из текста задачи (так они хранятся в CSV)
+func splitTodoistLabels(content string) (string, []string) {
+	var (
+		words []string
+		tags  []string
+	)
+	for _, w := range strings.Fields(content) {
+		if len(w) > 1 && w[0] == '@' {
+			tags = append(tags, w[1:])
+			continue
+		}
+		words = append(words, w)
+	}
+	return strings.Join(words, " "), tags
+}
+
+func parseTodoistZip(data []byte) (*Result, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения архива: %v", err)
+	}
+
+	res := &Result{}
+	for _, f := range zr.File {
+		if f.FileInfo().IsDir() || strings.ToLower(path.Ext(f.Name)) != ".csv" {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		content, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return nil, err
+		}
+		if err := parseTodoistCSV(res, content, f.Name); err != nil {
+			return nil, err
+		}
+	}
+	if len(res.Lists) == 0 {
+		return nil, fmt.Errorf("в архиве нет CSV-файлов проектов Todoist")
+	}
+	return res, nil
+}
+
+// parseTodoistCSV разбирает CSV одного проекта: колонки TYPE, CONTENT, DESCRIPTION,
+// PRIORITY, INDENT, DATE. Название проекта берётся из имени файла.
+func parseTodoistCSV(res *Result, data []byte, fileName string) error {
+	reader := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(data, []byte("\uFEFF"))))
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil {
+		return fmt.Errorf("%s: ошибка чтения CSV: %v", fileName, err)
+	}
+	if len(records) == 0 {
+		return nil
+	}
+
+	col := make(map[string]int)
+	for i, h := range records[0] {
+		col[strings.ToUpper(strings.TrimSpace(h))] = i
+	}
+	if _, ok := col["CONTENT"]; !ok {
+		return fmt.Errorf("%s: это не CSV Todoist (нет колонки CONTENT)", fileName)
+	}
+	get := func(record []string, name string) string {
+		if i, ok := col[name]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	base := path.Base(fileName)
+	list := models.ListWithTasks{
+		TodoList: models.TodoList{Title: strings.TrimSuffix(base, path.Ext(base)), CreatedAt: time.Now()},
+	}
+
+	// stack[i] - путь к последней задаче уровня i+1
+	var stack []*models.Task
+	notes := 0
+	for n, record := range records[1:] {
+		row := n + 2
+		switch strings.ToLower(get(record, "TYPE")) {
+		case "task":
+		case "note":
+			// Комментарий к предыдущей задаче дописываем в её описание
+			if len(stack) > 0 {
+				t := stack[len(stack)-1]
+				t.Description = strings.TrimSpace(t.Description + "\n" + get(record, "CONTENT"))
+			} else {
+				notes++
+			}
+			continue
+		case "section":
+			res.skip("%s: раздел %q не перенесён", base, get(record, "CONTENT"))
+			continue
+		case "":
+			continue
+		default:
+			res.skip("%s, строка %d: неизвестный тип %q", base, row, get(record, "TYPE"))
+			continue
+		}
+
+		title, tags := splitTodoistLabels(get(record, "CONTENT"))
+		if title == "" {
+			res.skip("%s, строка %d: задача без названия", base, row)
+			continue
+		}
+		priority, _ := strconv.Atoi(get(record, "PRIORITY"))
+		task := models.Task{
+			Title:       title,
+			Description: get(record, "DESCRIPTION"),
+			Priority:    todoistPriority(priority, false),
+			Tags:        tags,
+			CreatedAt:   time.Now(),
+		}
+		if date := get(record, "DATE"); date != "" {
+			if due, ok := parseDate(date); ok {
+				task.DueDate = due
+			} else {
+				res.skip("%s, строка %d: срок %q записан словами и не перенесён", base, row, date)
+			}
+		}
+
+		indent, _ := strconv.Atoi(get(record, "INDENT"))
+		if indent < 1 {
+			indent = 1
+		}
+		if indent > len(stack)+1 {
+			indent = len(stack) + 1
+		}
+		stack = stack[:indent-1]
+
+		var target *[]models.Task
+		if len(stack) == 0 {
+			target = &list.Tasks
+		} else {
+			target = &stack[len(stack)-1].Subtasks
+		}
+		*target = append(*target, task)
+		stack = append(stack, &(*target)[len(*target)-1])
+	}
+
+	if notes > 0 {
+		res.skip("%s: комментарии без задачи (%d) не перенесены", base, notes)
+	}
+	res.Lists = append(res.Lists, list)
+	return nil
+}
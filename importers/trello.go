@@ -0,0 +1,192 @@
+// trello.go
+package importers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+	"time"
+	"todolist/models"
+)
+
+type trelloBoard struct {
+	Name   string `json:"name"`
+	Desc   string `json:"desc"`
+	Closed bool   `json:"closed"`
+	Lists  []struct {
+		ID     string `json:"id"`
+		Name   string `json:"name"`
+		Closed bool   `json:"closed"`
+	} `json:"lists"`
+	Cards []struct {
+		ID               string  `json:"id"`
+		Name             string  `json:"name"`
+		Desc             string  `json:"desc"`
+		Due              string  `json:"due"`
+		DueComplete      bool    `json:"dueComplete"`
+		Closed           bool    `json:"closed"`
+		IDList           string  `json:"idList"`
+		Pos              float64 `json:"pos"`
+		DateLastActivity string  `json:"dateLastActivity"`
+		Labels           []struct {
+			Name  string `json:"name"`
+			Color string `json:"color"`
+		} `json:"labels"`
+		IDMembers   []string `json:"idMembers"`
+		Attachments []struct {
+			Name string `json:"name"`
+		} `json:"attachments"`
+	} `json:"cards"`
+	Checklists []struct {
+		ID         string `json:"id"`
+		IDCard     string `json:"idCard"`
+		Name       string `json:"name"`
+		CheckItems []struct {
+			ID    string  `json:"id"`
+			Name  string  `json:"name"`
+			State string  `json:"state"`
+			Pos   float64 `json:"pos"`
+			Due   string  `json:"due"`
+		} `json:"checkItems"`
+	} `json:"checklists"`
+	Actions []struct {
+		Type string `json:"type"`
+	} `json:"actions"`
+}
+
+// ParseTrello читает JSON-экспорт доски Trello. Доска становится списком задач,
+// карточки - задачами, колонка доски и метки - тегами, пункты чек-листов - подзадачами.
+// Доска без названия называется по имени файла fileName.
+func ParseTrello(r io.Reader, fileName string) (*Result, error) {
+	var board trelloBoard
+	if err := json.NewDecoder(r).Decode(&board); err != nil {
+		return nil, fmt.Errorf("ошибка чтения JSON Trello: %v", err)
+	}
+	if board.Name == "" && len(board.Cards) == 0 {
+		return nil, fmt.Errorf("это не экспорт доски Trello")
+	}
+
+	title := strings.TrimSpace(board.Name)
+	if title == "" {
+		base := path.Base(fileName)
+		title = strings.TrimSpace(strings.TrimSuffix(base, path.Ext(base)))
+	}
+	if title == "" {
+		title = "Trello"
+	}
+
+	res := &Result{}
+	list := models.ListWithTasks{
+		TodoList: models.TodoList{Title: title, Description: board.Desc, CreatedAt: time.Now()},
+	}
+
+	columns := make(map[string]string)
+	closedColumns := make(map[string]bool)
+	for _, l := range board.Lists {
+		columns[l.ID] = l.Name
+		closedColumns[l.ID] = l.Closed
+	}
+
+	checklists := make(map[string][]int)
+	for i, c := range board.Checklists {
+		checklists[c.IDCard] = append(checklists[c.IDCard], i)
+	}
+
+	cards := board.Cards
+	sort.SliceStable(cards, func(i, j int) bool { return cards[i].Pos < cards[j].Pos })
+
+	archived := 0
+	for _, card := range cards {
+		if card.Closed || closedColumns[card.IDList] {
+			archived++
+			continue
+		}
+
+		task := models.Task{
+			Title:       card.Name,
+			Description: card.Desc,
+			IsDone:      card.DueComplete,
+			CreatedAt:   trelloCreated(card.ID),
+			ExternalUID: "trello:" + card.ID,
+		}
+		if column := columns[card.IDList]; column != "" {
+			task.Tags = append(task.Tags, column)
+		}
+		for _, label := range card.Labels {
+			if label.Name != "" {
+				task.Tags = append(task.Tags, label.Name)
+			} else if label.Color != "" {
+				task.Tags = append(task.Tags, label.Color)
+			}
+		}
+		if card.Due != "" {
+			if due, ok := parseDate(card.Due); ok {
+				task.DueDate = due
+			} else {
+				res.skip("Карточка %q: не удалось разобрать срок %q", card.Name, card.Due)
+			}
+		}
+
+		for _, ci := range checklists[card.ID] {
+			checklist := board.Checklists[ci]
+			items := checklist.CheckItems
+			sort.SliceStable(items, func(i, j int) bool { return items[i].Pos < items[j].Pos })
+			for _, item := range items {
+				sub := models.Task{
+					Title:       item.Name,
+					IsDone:      item.State == "complete",
+					CreatedAt:   task.CreatedAt,
+					ExternalUID: "trello:" + card.ID + ":" + item.ID,
+				}
+				if len(checklists[card.ID]) > 1 && checklist.Name != "" {
+					sub.Tags = []string{checklist.Name}
+				}
+				if item.Due != "" {
+					if due, ok := parseDate(item.Due); ok {
+						sub.DueDate = due
+					}
+				}
+				task.Subtasks = append(task.Subtasks, sub)
+			}
+		}
+
+		if len(card.Attachments) > 0 {
+			res.skip("Карточка %q: вложения (%d) не перенесены", card.Name, len(card.Attachments))
+		}
+		if len(card.IDMembers) > 0 {
+			res.skip("Карточка %q: участники не перенесены", card.Name)
+		}
+		list.Tasks = append(list.Tasks, task)
+	}
+
+	if archived > 0 {
+		res.skip("Архивные карточки и карточки из архивных колонок (%d) пропущены", archived)
+	}
+	comments := 0
+	for _, a := range board.Actions {
+		if a.Type == "commentCard" {
+			comments++
+		}
+	}
+	if comments > 0 {
+		res.skip("Комментарии к карточкам (%d) не перенесены", comments)
+	}
+
+	res.Lists = append(res.Lists, list)
+	return res, nil
+}
+
+// trelloCreated извлекает время создания из идентификатора: это ObjectId MongoDB,
+// первые 4 байта которого - Unix-время
+func trelloCreated(id string) time.Time {
+	var sec int64
+	if len(id) >= 8 {
+		if _, err := fmt.Sscanf(id[:8], "%x", &sec); err == nil {
+			return time.Unix(sec, 0)
+		}
+	}
+	return time.Now()
+}
@@ -2,7 +2,9 @@ package main
 
 import (
 	"log"
+	"todolist/backup"
 	"todolist/db"
+	"todolist/feed"
 	"todolist/gui"
 	"todolist/theme"
 
@@ -17,6 +19,14 @@ func main() {
 	}
 	defer db.Close()
 
+	go func() {
+		if err := feed.ListenAndServe(); err != nil {
+			log.Printf("Сервер календарных подписок остановлен: %v", err)
+		}
+	}()
+
+	backup.Start(gui.UserNames)
+
 	a := app.New()
 	a.Settings().SetTheme(&theme.CustomTheme{})
 
@@ -24,6 +34,7 @@ func main() {
 	w.Resize(fyne.NewSize(400, 600))
 
 	gui.ShowUserSelection(w)
+	gui.StartAutoLock(w)
 
 	w.ShowAndRun()
 }
@@ -0,0 +1,205 @@
+// markdown.go
+package markdown
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+	"todolist/models"
+)
+
+const (
+	dateFormat = "02.01.2006"
+	indentUnit = "  "
+)
+
+var (
+	headingRe  = regexp.MustCompile(`^(#{1,6})\s+(.+?)\s*#*\s*$`)
+	checkboxRe = regexp.MustCompile(`^([ \t]*)(?:[-*+]|\d+[.)])\s+\[([ xX])\]\s*(.*)$`)
+	// Срок в конце пункта: "(до 20.10.2026)", "📅 2026-10-20" или "due:2026-10-20"
+	dueRe = regexp.MustCompile(`\s*(?:\(до\s+(\d{2}\.\d{2}\.\d{4})\)|📅\s*(\d{4}-\d{2}-\d{2})|due:(\d{4}-\d{2}-\d{2}))\s*$`)
+)
+
+// Write выгружает списки как Markdown: заголовок, описание и чек-лист,
+// подзадачи - вложенными пунктами, описание задачи - текстом под пунктом
+func Write(w io.Writer, lists []models.ListWithTasks) error {
+	bw := bufio.NewWriter(w)
+	for i, list := range lists {
+		if i > 0 {
+			bw.WriteString("\n")
+		}
+		fmt.Fprintf(bw, "# %s\n\n", oneLine(list.Title))
+		if desc := strings.TrimSpace(list.Description); desc != "" {
+			fmt.Fprintf(bw, "%s\n\n", desc)
+		}
+		for _, task := range models.NestTasks(list.Tasks) {
+			writeTask(bw, task, 0)
+		}
+	}
+	return bw.Flush()
+}
+
+func writeTask(w *bufio.Writer, task models.Task, depth int) {
+	indent := strings.Repeat(indentUnit, depth)
+
+	mark := " "
+	if task.IsDone {
+		mark = "x"
+	}
+	line := fmt.Sprintf("%s- [%s] %s", indent, mark, oneLine(task.Title))
+	if task.HasDue() {
+		line += " (до " + task.DueDate.Format(dateFormat) + ")"
+	}
+	w.WriteString(line + "\n")
+
+	if desc := strings.TrimSpace(task.Description); desc != "" {
+		for _, l := range strings.Split(desc, "\n") {
+			w.WriteString(indent + indentUnit + strings.TrimRight(l, "\r ") + "\n")
+		}
+	}
+
+	for _, sub := range task.Subtasks {
+		writeTask(w, sub, depth+1)
+	}
+}
+
+func oneLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// Document - результат разбора Markdown-файла
+type Document struct {
+	// Пункты до первого заголовка: их список выбирает вызывающий код
+	Untitled []models.Task
+	// Списки по заголовкам; задачи хранятся деревом в Subtasks
+	Lists []models.ListWithTasks
+}
+
+type openItem struct {
+	indent int
+	task   *models.Task
+}
+
+// Parse читает Markdown: каждый заголовок начинает новый список, текст сразу после
+// заголовка становится описанием списка, пункты "- [ ]" и "- [x]" - задачами.
+// Вложенность задаётся отступом, а обычный текст под пунктом дописывается в описание задачи.
+func Parse(r io.Reader) (*Document, error) {
+	doc := &Document{}
+	var (
+		current *[]models.Task // куда добавлять задачи верхнего уровня
+		list    *models.ListWithTasks
+		stack   []openItem
+		now     = time.Now()
+	)
+	current = &doc.Untitled
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		raw := strings.TrimRight(strings.TrimPrefix(scanner.Text(), "\uFEFF"), " \t\r")
+
+		if m := headingRe.FindStringSubmatch(raw); m != nil {
+			doc.Lists = append(doc.Lists, models.ListWithTasks{
+				TodoList: models.TodoList{Title: m[2], CreatedAt: now},
+			})
+			list = &doc.Lists[len(doc.Lists)-1]
+			current = &list.Tasks
+			stack = nil
+			continue
+		}
+
+		if m := checkboxRe.FindStringSubmatch(raw); m != nil {
+			task := models.Task{
+				IsDone:    m[2] != " ",
+				CreatedAt: now,
+			}
+			title := m[3]
+			if d := dueRe.FindStringSubmatch(title); d != nil {
+				due, err := parseDue(d)
+				if err != nil {
+					return nil, err
+				}
+				task.DueDate = due
+				title = title[:len(title)-len(d[0])]
+			}
+			task.Title = strings.TrimSpace(title)
+			if task.Title == "" {
+				continue
+			}
+
+			indent := indentWidth(m[1])
+			for len(stack) > 0 && stack[len(stack)-1].indent >= indent {
+				stack = stack[:len(stack)-1]
+			}
+			var target *[]models.Task
+			if len(stack) == 0 {
+				target = current
+			} else {
+				target = &stack[len(stack)-1].task.Subtasks
+			}
+			*target = append(*target, task)
+			stack = append(stack, openItem{indent: indent, task: &(*target)[len(*target)-1]})
+			continue
+		}
+
+		text := strings.TrimSpace(raw)
+		if text == "" {
+			continue
+		}
+
+		// Текст с отступом под пунктом - описание задачи
+		if len(stack) > 0 && indentWidth(leadingSpace(raw)) > stack[len(stack)-1].indent {
+			t := stack[len(stack)-1].task
+			t.Description = joinLine(t.Description, text)
+			continue
+		}
+		stack = nil
+
+		// Текст между заголовком и первым пунктом - описание списка
+		if list != nil && len(list.Tasks) == 0 {
+			list.Description = joinLine(list.Description, text)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+func parseDue(m []string) (time.Time, error) {
+	switch {
+	case m[1] != "":
+		return time.Parse(dateFormat, m[1])
+	case m[2] != "":
+		return time.Parse("2006-01-02", m[2])
+	default:
+		return time.Parse("2006-01-02", m[3])
+	}
+}
+
+func leadingSpace(s string) string {
+	return s[:len(s)-len(strings.TrimLeft(s, " \t"))]
+}
+
+// indentWidth считает отступ в пробелах, табуляция - за четыре
+func indentWidth(s string) int {
+	n := 0
+	for _, r := range s {
+		if r == '\t' {
+			n += 4
+		} else {
+			n++
+		}
+	}
+	return n
+}
+
+func joinLine(text, line string) string {
+	if text == "" {
+		return line
+	}
+	return text + "\n" + line
+}
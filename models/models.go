@@ -1,26 +1,249 @@
 package models
 
-import "time"
+import (
+	"strings"
+	"time"
+)
 
 type User struct {
-	ID   int
-	TgID int64 `db:"tg_id"`
+	ID        int
+	TgID      int64 `db:"tg_id"`
+	Protected bool  // вход защищён паролем или PIN
 }
 
 type TodoList struct {
 	ID          int
-	UserID      int `db:"user_id"`
+	UserID      int `db:"user_id"` // владелец списка
 	Title       string
 	Description string
 	CreatedAt   time.Time `db:"created_at"`
+	Role        Role      // роль пользователя, для которого загружен список
+	Shared      bool      // у списка есть участники помимо владельца
+	ManualOrder bool      `db:"manual_order"` // задачи расставлены вручную, а не по сроку
+	ArchivedAt  time.Time `db:"archived_at"`  // когда список завершён и убран в архив; нулевое - список активен
+	AutoArchive bool      `db:"auto_archive"` // убрать в архив, когда все задачи выполнены
+	FolderID    int       `db:"folder_id"`    // папка пользователя, в которой лежит список; 0 - вне папок
+	Pinned      bool      // список закреплён пользователем наверху экрана
 }
 
-type Task struct {
+// Folder - папка, в которую пользователь собирает списки; папки вкладываются друг в друга
+type Folder struct {
+	ID        int
+	UserID    int `db:"user_id"`
+	ParentID  int `db:"parent_id"` // 0 - папка верхнего уровня
+	Title     string
+	Collapsed bool      // содержимое папки скрыто на экране списков
+	CreatedAt time.Time `db:"created_at"`
+}
+
+// ListCounts - сводка по задачам списка
+type ListCounts struct {
+	Total   int // все задачи, включая подзадачи
+	Open    int // невыполненные задачи
+	Overdue int // невыполненные задачи с прошедшим сроком
+}
+
+// Add складывает сводки, например, всех списков папки
+func (c ListCounts) Add(other ListCounts) ListCounts {
+	return ListCounts{Total: c.Total + other.Total, Open: c.Open + other.Open, Overdue: c.Overdue + other.Overdue}
+}
+
+// Progress - доля выполненных задач от 0 до 1; для пустого списка 0
+func (c ListCounts) Progress() float64 {
+	if c.Total == 0 {
+		return 0
+	}
+	return float64(c.Total-c.Open) / float64(c.Total)
+}
+
+// ListTemplate - заготовка списка с задачами, из которой создаются новые списки
+type ListTemplate struct {
 	ID          int
-	ListID      int `db:"list_id"`
+	UserID      int `db:"user_id"`
 	Title       string
 	Description string
-	DueDate     time.Time `db:"due_date"`
-	IsDone      bool      `db:"is_done"`
 	CreatedAt   time.Time `db:"created_at"`
+	TaskCount   int       // число задач шаблона, заполняется при загрузке
+}
+
+// Role - роль участника списка
+type Role string
+
+const (
+	RoleOwner  Role = "owner"
+	RoleEditor Role = "editor"
+	RoleViewer Role = "viewer"
+)
+
+// rank упорядочивает роли по объёму прав
+func (r Role) rank() int {
+	switch r {
+	case RoleOwner:
+		return 3
+	case RoleEditor:
+		return 2
+	case RoleViewer:
+		return 1
+	}
+	return 0
+}
+
+// Allows сообщает, даёт ли роль права не меньше need
+func (r Role) Allows(need Role) bool {
+	return r.rank() >= need.rank() && r.rank() > 0
+}
+
+// CanEdit - можно ли менять задачи списка
+func (r Role) CanEdit() bool {
+	return r.Allows(RoleEditor)
+}
+
+// ListMember - пользователь, которому открыт доступ к чужому списку
+type ListMember struct {
+	ListID    int       `db:"list_id"`
+	UserID    int       `db:"user_id"`
+	Role      Role      // RoleEditor или RoleViewer; владелец хранится в TodoList.UserID
+	CreatedAt time.Time `db:"created_at"`
+}
+
+type Task struct {
+	ID           int
+	ListID       int `db:"list_id"`
+	Title        string
+	Description  string
+	DueDate      time.Time `db:"due_date"`
+	IsDone       bool      `db:"is_done"`
+	CreatedAt    time.Time `db:"created_at"`
+	ExternalUID  string    `db:"external_uid"` // идентификатор задачи во внешней системе (UID из .ics и т.п.)
+	Priority     int       // 0 - без приоритета, 1 - наивысший (A), 26 - низший (Z)
+	CompletedAt  time.Time `db:"completed_at"`
+	Tags         []string
+	ParentID     int    `db:"parent_id"`   // 0 - задача верхнего уровня
+	AssigneeID   int    `db:"assignee_id"` // ответственный пользователь, 0 - не назначен
+	StatusID     int    `db:"status_id"`   // колонка доски (см. Status); IsDone совпадает с её IsFinal
+	CommentCount int    // число комментариев, заполняется при загрузке
+	Subtasks     []Task // заполняется только в дереве задач (см. NestTasks)
+}
+
+// HasDue - задан ли срок. Задача без срока хранится с нулевой датой 0001-01-01, а не NULL.
+func (t Task) HasDue() bool {
+	return t.DueDate.Year() > 1
+}
+
+// Status - колонка доски задач списка
+type Status struct {
+	ID       int
+	ListID   int `db:"list_id"`
+	Title    string
+	Position int
+	WIPLimit int  `db:"wip_limit"` // наибольшее число задач в колонке, 0 - без ограничения
+	IsFinal  bool `db:"is_final"`  // задачи в этой колонке считаются выполненными
+}
+
+// ListWithTasks - список вместе с задачами, используется при экспорте
+type ListWithTasks struct {
+	TodoList
+	Tasks []Task
+}
+
+// FeedToken - секретная ссылка на календарную подписку пользователя или одного списка
+type FeedToken struct {
+	Token     string
+	UserID    int       `db:"user_id"`
+	ListID    int       `db:"list_id"` // 0 - все списки пользователя
+	CreatedAt time.Time `db:"created_at"`
+}
+
+// Comment - сообщение в обсуждении задачи
+type Comment struct {
+	ID        int
+	TaskID    int `db:"task_id"`
+	UserID    int `db:"user_id"` // автор, 0 - пользователь удалён
+	Body      string
+	CreatedAt time.Time `db:"created_at"`
+	UpdatedAt time.Time `db:"updated_at"` // нулевое - не редактировался
+}
+
+// Attachment - файл, прикреплённый к задаче. Содержимое хранится отдельно, по хешу.
+type Attachment struct {
+	ID        int
+	TaskID    int `db:"task_id"`
+	UserID    int `db:"user_id"` // кто прикрепил
+	Name      string
+	MimeType  string `db:"mime_type"`
+	Size      int64
+	Hash      string    // SHA-256 содержимого в hex
+	CreatedAt time.Time `db:"created_at"`
+}
+
+// IsImage - можно ли показать миниатюру
+func (a Attachment) IsImage() bool {
+	return strings.HasPrefix(a.MimeType, "image/")
+}
+
+// Notification - уведомление пользователю о событии с задачей
+type Notification struct {
+	ID        int
+	UserID    int    `db:"user_id"`  // получатель
+	ActorID   int    `db:"actor_id"` // кто совершил действие
+	TaskID    int    `db:"task_id"`
+	Kind      string // вид события, см. NotifyAssigned
+	TaskTitle string // название задачи на момент чтения
+	ListID    int
+	CreatedAt time.Time `db:"created_at"`
+	ReadAt    time.Time `db:"read_at"`
+}
+
+// Виды уведомлений
+const (
+	NotifyAssigned  = "assigned"
+	NotifyCommented = "commented"
+)
+
+// SmartList - сохранённый фильтр задач (см. пакет filter), показывается рядом с обычными списками
+type SmartList struct {
+	ID        int
+	UserID    int `db:"user_id"`
+	Title     string
+	Query     string
+	CreatedAt time.Time `db:"created_at"`
+}
+
+// SearchResult - найденная задача со списком, в котором она лежит
+type SearchResult struct {
+	Task      Task
+	ListTitle string
+	Rank      float64 // релевантность, больше - выше в выдаче
+	Snippet   string  // фрагмент описания с найденными словами в «кавычках»
+}
+
+// NestTasks строит дерево задач по ParentID, сохраняя исходный порядок.
+// Задачи, чей родитель отсутствует в срезе, становятся корневыми.
+func NestTasks(tasks []Task) []Task {
+	present := make(map[int]bool, len(tasks))
+	children := make(map[int][]Task)
+	for _, t := range tasks {
+		present[t.ID] = true
+	}
+
+	var roots []Task
+	for _, t := range tasks {
+		if t.ParentID != 0 && t.ParentID != t.ID && present[t.ParentID] {
+			children[t.ParentID] = append(children[t.ParentID], t)
+		} else {
+			roots = append(roots, t)
+		}
+	}
+
+	var attach func(list []Task) []Task
+	attach = func(list []Task) []Task {
+		for i := range list {
+			if c, ok := children[list[i].ID]; ok {
+				delete(children, list[i].ID) // защита от циклов
+				list[i].Subtasks = attach(c)
+			}
+		}
+		return list
+	}
+	return attach(roots)
 }
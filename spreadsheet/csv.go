@@ -0,0 +1,237 @@
+// csv.go
+package spreadsheet
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"todolist/models"
+)
+
+const (
+	dateFormat = "02.01.2006"
+	utf8BOM    = "\uFEFF"
+)
+
+// Колонки при экспорте. Импорт распознаёт их же, поэтому выгруженный файл можно загрузить обратно.
+var exportHeader = []string{"Список", "Название", "Описание", "Срок", "Выполнено", "Создано"}
+
+// Mapping - номера колонок файла для полей задачи, -1 - колонки нет
+type Mapping struct {
+	Title       int
+	Description int
+	DueDate     int
+	Done        int
+}
+
+// Синонимы заголовков колонок в нижнем регистре
+var headerNames = map[string][]string{
+	"title":       {"title", "name", "task", "summary", "название", "задача"},
+	"description": {"description", "notes", "note", "описание", "заметки"},
+	"due":         {"due", "due date", "due_date", "deadline", "срок", "дата"},
+	"done":        {"done", "completed", "is_done", "status", "выполнено", "готово", "статус"},
+}
+
+// DetectMapping подбирает колонки по заголовку. Если название не найдено, берётся первая колонка.
+func DetectMapping(header []string) Mapping {
+	find := func(field string) int {
+		for i, h := range header {
+			h = strings.ToLower(strings.TrimSpace(h))
+			for _, name := range headerNames[field] {
+				if h == name {
+					return i
+				}
+			}
+		}
+		return -1
+	}
+
+	m := Mapping{
+		Title:       find("title"),
+		Description: find("description"),
+		DueDate:     find("due"),
+		Done:        find("done"),
+	}
+	if m.Title < 0 && len(header) > 0 {
+		m.Title = 0
+	}
+	return m
+}
+
+// RowError - ошибка разбора строки файла; Row считается с 1, как в редакторе таблиц
+type RowError struct {
+	Row int
+	Err error
+}
+
+func (e RowError) Error() string {
+	return fmt.Sprintf("строка %d: %v", e.Row, e.Err)
+}
+
+// ReadCSV читает все строки файла. Разделитель (запятая, точка с запятой или табуляция)
+// определяется по первой строке.
+func ReadCSV(r io.Reader) ([][]string, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	data = bytes.TrimPrefix(data, []byte(utf8BOM))
+
+	firstLine, _, _ := bufio.NewReader(bytes.NewReader(data)).ReadLine()
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.Comma = detectDelimiter(string(firstLine))
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения CSV: %v", err)
+	}
+	return records, nil
+}
+
+func detectDelimiter(line string) rune {
+	best, bestCount := ',', 0
+	for _, d := range []rune{',', ';', '\t'} {
+		if n := strings.Count(line, string(d)); n > bestCount {
+			best, bestCount = d, n
+		}
+	}
+	return best
+}
+
+// ParseRows превращает строки файла в задачи. Первой строкой должен быть заголовок.
+// Строки с ошибками пропускаются и попадают в отчёт; tasks[i] взята из строки rows[i].
+func ParseRows(records [][]string, m Mapping) (tasks []models.Task, rows []int, errs []RowError) {
+	if m.Title < 0 {
+		return nil, nil, []RowError{{Row: 1, Err: fmt.Errorf("не выбрана колонка с названием")}}
+	}
+
+	now := time.Now()
+	for i, record := range records {
+		if i == 0 {
+			continue
+		}
+		row := i + 1
+		if isBlank(record) {
+			continue
+		}
+
+		task := models.Task{
+			Title:       cell(record, m.Title),
+			Description: cell(record, m.Description),
+			CreatedAt:   now,
+		}
+		if task.Title == "" {
+			errs = append(errs, RowError{Row: row, Err: fmt.Errorf("пустое название")})
+			continue
+		}
+
+		if s := cell(record, m.DueDate); s != "" {
+			due, err := ParseDate(s)
+			if err != nil {
+				errs = append(errs, RowError{Row: row, Err: err})
+				continue
+			}
+			task.DueDate = due
+		}
+
+		if s := cell(record, m.Done); s != "" {
+			done, err := ParseDone(s)
+			if err != nil {
+				errs = append(errs, RowError{Row: row, Err: err})
+				continue
+			}
+			task.IsDone = done
+		}
+
+		tasks = append(tasks, task)
+		rows = append(rows, row)
+	}
+	return tasks, rows, errs
+}
+
+func cell(record []string, i int) string {
+	if i < 0 || i >= len(record) {
+		return ""
+	}
+	return strings.TrimSpace(record[i])
+}
+
+func isBlank(record []string) bool {
+	for _, v := range record {
+		if strings.TrimSpace(v) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// ParseDate понимает дд.мм.гггг и ISO 8601 (гггг-мм-дд, в том числе со временем)
+func ParseDate(s string) (time.Time, error) {
+	for _, layout := range []string{dateFormat, "2006-01-02", time.RFC3339, "2006-01-02T15:04:05", "2006-01-02 15:04:05"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("неверная дата %q, ожидается дд.мм.гггг или гггг-мм-дд", s)
+}
+
+// ParseDone распознаёт отметку о выполнении
+func ParseDone(s string) (bool, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "1", "true", "yes", "y", "x", "+", "done", "completed", "да", "выполнено", "готово":
+		return true, nil
+	case "0", "false", "no", "n", "-", "todo", "нет", "не выполнено":
+		return false, nil
+	}
+	return false, fmt.Errorf("не удалось понять отметку о выполнении %q", s)
+}
+
+// WriteCSV выгружает задачи в CSV (UTF-8 с BOM, чтобы Excel правильно показал кириллицу)
+func WriteCSV(w io.Writer, lists []models.ListWithTasks) error {
+	if _, err := io.WriteString(w, utf8BOM); err != nil {
+		return err
+	}
+
+	writer := csv.NewWriter(w)
+	if err := writer.Write(exportHeader); err != nil {
+		return err
+	}
+	for _, record := range exportRecords(lists) {
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func exportRecords(lists []models.ListWithTasks) [][]string {
+	var records [][]string
+	for _, list := range lists {
+		for _, task := range list.Tasks {
+			due := ""
+			if task.HasDue() {
+				due = task.DueDate.Format(dateFormat)
+			}
+			done := "нет"
+			if task.IsDone {
+				done = "да"
+			}
+			records = append(records, []string{
+				list.Title,
+				task.Title,
+				task.Description,
+				due,
+				done,
+				task.CreatedAt.Format(dateFormat),
+			})
+		}
+	}
+	return records
+}
@@ -0,0 +1,273 @@
+// todotxt.go
+package todotxt
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"todolist/models"
+)
+
+// Формат описан в https://github.com/todotxt/todo.txt
+const (
+	dateLayout  = "2006-01-02"
+	dueKey      = "due"
+	priorityKey = "pri" // приоритет выполненной задачи: спецификация требует убирать "(A)" при выполнении
+)
+
+// Item - одна строка файла todo.txt
+type Item struct {
+	Done           bool
+	Priority       byte // 'A'..'Z', 0 - без приоритета
+	CompletionDate time.Time
+	CreationDate   time.Time
+	Text           string // текст задачи вместе с +проектами, @контекстами и ключ:значение
+}
+
+// Parse разбирает строку todo.txt
+func Parse(line string) (Item, error) {
+	var item Item
+	rest := strings.TrimSpace(line)
+	if rest == "" {
+		return item, fmt.Errorf("пустая строка")
+	}
+
+	if strings.HasPrefix(rest, "x ") {
+		item.Done = true
+		rest = strings.TrimLeft(rest[2:], " ")
+		if d, r, ok := cutDate(rest); ok {
+			item.CompletionDate = d
+			rest = r
+			if d, r, ok := cutDate(rest); ok {
+				item.CreationDate = d
+				rest = r
+			}
+		}
+	} else {
+		if len(rest) >= 4 && rest[0] == '(' && rest[2] == ')' && rest[3] == ' ' && rest[1] >= 'A' && rest[1] <= 'Z' {
+			item.Priority = rest[1]
+			rest = strings.TrimLeft(rest[4:], " ")
+		}
+		if d, r, ok := cutDate(rest); ok {
+			item.CreationDate = d
+			rest = r
+		}
+	}
+
+	item.Text = rest
+	if item.Done && item.Priority == 0 {
+		if p := item.Value(priorityKey); len(p) == 1 && p[0] >= 'A' && p[0] <= 'Z' {
+			item.Priority = p[0]
+		}
+	}
+	return item, nil
+}
+
+func cutDate(s string) (time.Time, string, bool) {
+	word, rest, _ := strings.Cut(s, " ")
+	d, err := time.Parse(dateLayout, word)
+	if err != nil {
+		return time.Time{}, s, false
+	}
+	return d, strings.TrimLeft(rest, " "), true
+}
+
+// String форматирует задачу обратно в строку todo.txt
+func (item Item) String() string {
+	var parts []string
+	if item.Done {
+		parts = append(parts, "x")
+		if !item.CompletionDate.IsZero() {
+			parts = append(parts, item.CompletionDate.Format(dateLayout))
+		}
+	} else if item.Priority != 0 {
+		parts = append(parts, "("+string(item.Priority)+")")
+	}
+	// дату создания у выполненной задачи можно указать только вместе с датой выполнения
+	if !item.CreationDate.IsZero() && (!item.Done || !item.CompletionDate.IsZero()) {
+		parts = append(parts, item.CreationDate.Format(dateLayout))
+	}
+
+	text := item.Text
+	if item.Done && item.Priority != 0 && item.Value(priorityKey) == "" {
+		text += " " + priorityKey + ":" + string(item.Priority)
+	}
+	parts = append(parts, text)
+	return strings.Join(parts, " ")
+}
+
+func words(text string) []string {
+	return strings.Fields(text)
+}
+
+func isProject(w string) bool { return len(w) > 1 && w[0] == '+' }
+func isContext(w string) bool { return len(w) > 1 && w[0] == '@' }
+
+// keyValue распознаёт ключ:значение; ссылки вида http://… ключами не считаются
+func keyValue(w string) (string, string, bool) {
+	k, v, ok := strings.Cut(w, ":")
+	if !ok || k == "" || v == "" || strings.HasPrefix(v, "//") || strings.ContainsAny(k, "+@") {
+		return "", "", false
+	}
+	return k, v, true
+}
+
+// Projects возвращает +проекты без знака "+"
+func (item Item) Projects() []string {
+	var result []string
+	for _, w := range words(item.Text) {
+		if isProject(w) {
+			result = append(result, w[1:])
+		}
+	}
+	return result
+}
+
+// Contexts возвращает @контексты без знака "@"
+func (item Item) Contexts() []string {
+	var result []string
+	for _, w := range words(item.Text) {
+		if isContext(w) {
+			result = append(result, w[1:])
+		}
+	}
+	return result
+}
+
+// Value возвращает значение ключа (например, due) или пустую строку
+func (item Item) Value(key string) string {
+	for _, w := range words(item.Text) {
+		if k, v, ok := keyValue(w); ok && k == key {
+			return v
+		}
+	}
+	return ""
+}
+
+// ParseFile читает все непустые строки файла
+func ParseFile(r io.Reader) ([]Item, error) {
+	var items []Item
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(strings.TrimPrefix(scanner.Text(), "\uFEFF"))
+		if line == "" {
+			continue
+		}
+		item, err := Parse(line)
+		if err != nil {
+			return nil, fmt.Errorf("строка %d: %v", n, err)
+		}
+		items = append(items, item)
+	}
+	return items, scanner.Err()
+}
+
+// ProjectName переводит название списка в +проект: пробелы в todo.txt недопустимы
+func ProjectName(listTitle string) string {
+	return strings.Join(strings.Fields(listTitle), "_")
+}
+
+// ListTitle переводит +проект обратно в название списка
+func ListTitle(project string) string {
+	return strings.ReplaceAll(project, "_", " ")
+}
+
+// ToTask переводит строку в задачу. Первый +проект определяет список и возвращается
+// отдельно, @контексты становятся тегами, due: - сроком. Служебные метки из названия убираются.
+func ToTask(item Item) (models.Task, string, error) {
+	task := models.Task{
+		IsDone:      item.Done,
+		CreatedAt:   item.CreationDate,
+		CompletedAt: item.CompletionDate,
+	}
+	if item.Priority != 0 {
+		task.Priority = int(item.Priority-'A') + 1
+	}
+
+	var (
+		project string
+		title   []string
+	)
+	for _, w := range words(item.Text) {
+		switch {
+		case isProject(w) && project == "":
+			project = w[1:]
+		case isContext(w):
+			task.Tags = append(task.Tags, w[1:])
+		default:
+			k, v, ok := keyValue(w)
+			switch {
+			case ok && k == dueKey:
+				due, err := time.Parse(dateLayout, v)
+				if err != nil {
+					return task, "", fmt.Errorf("неверный срок %q", v)
+				}
+				task.DueDate = due
+			case ok && k == priorityKey && item.Done:
+				// уже учтён в item.Priority
+			default:
+				title = append(title, w)
+			}
+		}
+	}
+
+	task.Title = strings.Join(title, " ")
+	if task.Title == "" {
+		return task, "", fmt.Errorf("пустое название задачи")
+	}
+	if task.CreatedAt.IsZero() {
+		task.CreatedAt = time.Now()
+	}
+	return task, project, nil
+}
+
+// FromTask переводит задачу в строку todo.txt; listTitle становится +проектом
+func FromTask(task models.Task, listTitle string) Item {
+	item := Item{
+		Done:         task.IsDone,
+		CreationDate: dateOnly(task.CreatedAt),
+	}
+	if task.IsDone {
+		item.CompletionDate = dateOnly(task.CompletedAt)
+	}
+	if task.Priority >= 1 && task.Priority <= 26 {
+		item.Priority = byte('A' + task.Priority - 1)
+	}
+
+	parts := []string{strings.Join(strings.Fields(task.Title), " ")}
+	if project := ProjectName(listTitle); project != "" {
+		parts = append(parts, "+"+project)
+	}
+	for _, tag := range task.Tags {
+		if tag = strings.Join(strings.Fields(tag), "_"); tag != "" {
+			parts = append(parts, "@"+tag)
+		}
+	}
+	if task.HasDue() {
+		parts = append(parts, dueKey+":"+task.DueDate.Format(dateLayout))
+	}
+	item.Text = strings.Join(parts, " ")
+	return item
+}
+
+func dateOnly(t time.Time) time.Time {
+	if t.IsZero() {
+		return t
+	}
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// Write выгружает задачи в формате todo.txt, по строке на задачу
+func Write(w io.Writer, lists []models.ListWithTasks) error {
+	bw := bufio.NewWriter(w)
+	for _, list := range lists {
+		for _, task := range list.Tasks {
+			if _, err := fmt.Fprintln(bw, FromTask(task, list.Title)); err != nil {
+				return err
+			}
+		}
+	}
+	return bw.Flush()
+}
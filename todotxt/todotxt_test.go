@@ -0,0 +1,238 @@
+package todotxt
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+	"todolist/models"
+)
+
+func date(s string) time.Time {
+	d, err := time.Parse(dateLayout, s)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		line string
+		want Item
+	}{
+		{
+			line: "Купить молоко",
+			want: Item{Text: "Купить молоко"},
+		},
+		{
+			line: "(A) 2026-10-01 Позвонить маме +Семья @телефон due:2026-10-20",
+			want: Item{Priority: 'A', CreationDate: date("2026-10-01"), Text: "Позвонить маме +Семья @телефон due:2026-10-20"},
+		},
+		{
+			line: "x 2026-10-18 2026-10-01 Сдать отчёт +Работа pri:B",
+			want: Item{Done: true, Priority: 'B', CompletionDate: date("2026-10-18"), CreationDate: date("2026-10-01"), Text: "Сдать отчёт +Работа pri:B"},
+		},
+		{
+			// "(a)" и "(A)" не в начале строки приоритетом не считаются
+			line: "(a) задача (A) внутри",
+			want: Item{Text: "(a) задача (A) внутри"},
+		},
+		{
+			line: "x 2026-10-18 Готово",
+			want: Item{Done: true, CompletionDate: date("2026-10-18"), Text: "Готово"},
+		},
+	}
+
+	for _, tt := range tests {
+		got, err := Parse(tt.line)
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", tt.line, err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Parse(%q) = %+v, want %+v", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestParseRejectsEmptyLine(t *testing.T) {
+	if _, err := Parse("   "); err == nil {
+		t.Error("Parse(\"   \") returned no error")
+	}
+}
+
+func TestLineRoundTrip(t *testing.T) {
+	lines := []string{
+		"Купить молоко",
+		"(A) Позвонить маме",
+		"(C) 2026-10-01 Позвонить маме +Семья @телефон due:2026-10-20",
+		"2026-10-01 Прочитать http://example.com/doc +Учёба",
+		"x 2026-10-18 2026-10-01 Сдать отчёт +Работа @офис pri:B",
+		"x 2026-10-18 Выполнено без даты создания",
+		"x Выполнено без дат",
+	}
+
+	for _, line := range lines {
+		item, err := Parse(line)
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", line, err)
+		}
+		if got := item.String(); got != line {
+			t.Errorf("round trip of %q = %q", line, got)
+		}
+	}
+}
+
+func TestTokens(t *testing.T) {
+	item, err := Parse("(B) Встреча +Работа +Проект_X @офис @звонок due:2026-11-01 http://example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := item.Projects(), []string{"Работа", "Проект_X"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Projects() = %v, want %v", got, want)
+	}
+	if got, want := item.Contexts(), []string{"офис", "звонок"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Contexts() = %v, want %v", got, want)
+	}
+	if got := item.Value("due"); got != "2026-11-01" {
+		t.Errorf("Value(due) = %q", got)
+	}
+	if got := item.Value("http"); got != "" {
+		t.Errorf("URL parsed as key:value: %q", got)
+	}
+}
+
+func TestToTask(t *testing.T) {
+	item, err := Parse("x 2026-10-18 2026-10-01 Сдать отчёт +Отдел_продаж @офис @срочно due:2026-10-17 pri:B")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	task, project, err := ToTask(item)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := models.Task{
+		Title:       "Сдать отчёт",
+		DueDate:     date("2026-10-17"),
+		IsDone:      true,
+		CreatedAt:   date("2026-10-01"),
+		CompletedAt: date("2026-10-18"),
+		Priority:    2,
+		Tags:        []string{"офис", "срочно"},
+	}
+	if !reflect.DeepEqual(task, want) {
+		t.Errorf("ToTask() = %+v, want %+v", task, want)
+	}
+	if project != "Отдел_продаж" || ListTitle(project) != "Отдел продаж" {
+		t.Errorf("project = %q, list title = %q", project, ListTitle(project))
+	}
+}
+
+func TestToTaskErrors(t *testing.T) {
+	for _, line := range []string{"+Работа @офис", "Задача due:завтра"} {
+		item, err := Parse(line)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, _, err := ToTask(item); err == nil {
+			t.Errorf("ToTask(%q) returned no error", line)
+		}
+	}
+}
+
+func TestTaskRoundTrip(t *testing.T) {
+	tasks := []models.Task{
+		{
+			Title:     "Позвонить маме",
+			CreatedAt: date("2026-10-01"),
+		},
+		{
+			Title:     "Купить билеты",
+			DueDate:   date("2026-12-20"),
+			Priority:  1,
+			Tags:      []string{"дом", "покупки"},
+			CreatedAt: date("2026-10-02"),
+		},
+		{
+			Title:       "Сдать отчёт",
+			IsDone:      true,
+			Priority:    3,
+			CreatedAt:   date("2026-09-01"),
+			CompletedAt: date("2026-09-15"),
+		},
+	}
+
+	for _, listTitle := range []string{"Дом", "Отдел продаж"} {
+		for _, want := range tasks {
+			line := FromTask(want, listTitle).String()
+
+			item, err := Parse(line)
+			if err != nil {
+				t.Fatalf("Parse(%q): %v", line, err)
+			}
+			got, project, err := ToTask(item)
+			if err != nil {
+				t.Fatalf("ToTask(%q): %v", line, err)
+			}
+
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("round trip via %q = %+v, want %+v", line, got, want)
+			}
+			if ListTitle(project) != listTitle {
+				t.Errorf("round trip via %q: list %q, want %q", line, ListTitle(project), listTitle)
+			}
+		}
+	}
+}
+
+func TestWriteParseFile(t *testing.T) {
+	lists := []models.ListWithTasks{
+		{
+			TodoList: models.TodoList{Title: "Работа"},
+			Tasks: []models.Task{
+				{Title: "Отчёт", CreatedAt: date("2026-10-01"), DueDate: date("2026-10-05")},
+				{Title: "Совещание", CreatedAt: date("2026-10-02"), IsDone: true, CompletedAt: date("2026-10-03")},
+			},
+		},
+		{
+			TodoList: models.TodoList{Title: "Дом"},
+			Tasks:    []models.Task{{Title: "Полить цветы", CreatedAt: date("2026-10-04"), Tags: []string{"утро"}}},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := Write(&buf, lists); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "2026-10-01 Отчёт +Работа due:2026-10-05\n" +
+		"x 2026-10-03 2026-10-02 Совещание +Работа\n" +
+		"2026-10-04 Полить цветы +Дом @утро\n"
+	if buf.String() != want {
+		t.Fatalf("Write() =\n%s\nwant\n%s", buf.String(), want)
+	}
+
+	items, err := ParseFile(bytes.NewReader(append([]byte("\n"), buf.Bytes()...)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 3 {
+		t.Fatalf("ParseFile() returned %d items, want 3", len(items))
+	}
+	for i, item := range items {
+		task, project, err := ToTask(item)
+		if err != nil {
+			t.Fatal(err)
+		}
+		listIdx, taskIdx := 0, i
+		if i == 2 {
+			listIdx, taskIdx = 1, 0
+		}
+		if project != lists[listIdx].Title || !reflect.DeepEqual(task, lists[listIdx].Tasks[taskIdx]) {
+			t.Errorf("item %d = %+v in %q", i, task, project)
+		}
+	}
+}